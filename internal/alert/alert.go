@@ -0,0 +1,159 @@
+// File: internal/alert/alert.go (complete file)
+
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Alert is the sink-neutral form of a monitor event or a test verdict.
+type Alert struct {
+	AtUTC    time.Time `json:"at_utc"`
+	Source   string    `json:"source"` // monitor|test
+	Host     string    `json:"host,omitempty"`
+	Kind     string    `json:"kind"`
+	Severity string    `json:"severity"` // info|warning|critical
+	Message  string    `json:"message"`
+	Details  any       `json:"details,omitempty"`
+}
+
+type Sink interface {
+	Name() string
+	Send(ctx context.Context, a Alert) error
+}
+
+var severityRank = map[string]int{"info": 0, "warning": 1, "critical": 2}
+
+// Dispatcher delivers alerts to every sink in the background, retrying with
+// exponential backoff. Alerts that still fail are appended to DeadLetter.
+type Dispatcher struct {
+	Sinks       []Sink
+	MinSeverity string
+	Retries     int
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+	DeadLetter  string
+
+	once  sync.Once
+	queue chan Alert
+	done  chan struct{}
+	mu    sync.Mutex
+}
+
+func (d *Dispatcher) start() {
+	d.once.Do(func() {
+		d.queue = make(chan Alert, 256)
+		d.done = make(chan struct{})
+		go d.loop()
+	})
+}
+
+// Notify queues an alert; it never blocks the caller for delivery.
+func (d *Dispatcher) Notify(a Alert) {
+	if d == nil || len(d.Sinks) == 0 {
+		return
+	}
+	if severityRank[a.Severity] < severityRank[d.MinSeverity] {
+		return
+	}
+	if a.Host == "" {
+		a.Host, _ = os.Hostname()
+	}
+	d.start()
+	select {
+	case d.queue <- a:
+	default:
+		d.deadLetter("queue", a, fmt.Errorf("alert queue full"))
+	}
+}
+
+// Close waits up to timeout for queued alerts to be delivered.
+func (d *Dispatcher) Close(timeout time.Duration) {
+	if d == nil || d.queue == nil {
+		return
+	}
+	close(d.queue)
+	select {
+	case <-d.done:
+	case <-time.After(timeout):
+		slog.Warn("alert delivery still pending at shutdown")
+	}
+}
+
+func (d *Dispatcher) loop() {
+	defer close(d.done)
+	for a := range d.queue {
+		for _, s := range d.Sinks {
+			if err := d.deliver(s, a); err != nil {
+				slog.Warn("alert delivery failed", "sink", s.Name(), "err", err)
+				d.deadLetter(s.Name(), a, err)
+			}
+		}
+	}
+}
+
+func (d *Dispatcher) deliver(s Sink, a Alert) error {
+	backoff := d.Backoff
+	if backoff <= 0 {
+		backoff = time.Second
+	}
+	maxBackoff := d.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = 30 * time.Second
+	}
+
+	var err error
+	for attempt := 0; attempt <= d.Retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+			if backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		err = s.Send(ctx, a)
+		cancel()
+		if err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+type deadLetterRecord struct {
+	AtUTC time.Time `json:"at_utc"`
+	Sink  string    `json:"sink"`
+	Error string    `json:"error"`
+	Alert Alert     `json:"alert"`
+}
+
+func (d *Dispatcher) deadLetter(sink string, a Alert, err error) {
+	if d.DeadLetter == "" {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(d.DeadLetter), 0o755); err != nil {
+		return
+	}
+	f, ferr := os.OpenFile(d.DeadLetter, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if ferr != nil {
+		return
+	}
+	defer f.Close()
+	_ = json.NewEncoder(f).Encode(deadLetterRecord{
+		AtUTC: time.Now().UTC(),
+		Sink:  sink,
+		Error: err.Error(),
+		Alert: a,
+	})
+}
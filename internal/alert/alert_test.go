@@ -0,0 +1,76 @@
+package alert
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWebhook_TemplateAndSignature(t *testing.T) {
+	var gotBody []byte
+	var gotSig string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotBody, _ = io.ReadAll(r.Body)
+		gotSig = r.Header.Get("X-Signature-256")
+	}))
+	defer srv.Close()
+
+	tpl, err := ParseBodyTemplate(`{"text": {{json .Message}}, "sev": {{json (upper .Severity)}}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := &Dispatcher{Sinks: []Sink{&WebhookSink{URL: srv.URL, Template: tpl, Secret: []byte("s3cret")}}}
+	d.Notify(Alert{Kind: "vpn_exit_changed", Severity: "critical", Message: `exit "changed"`})
+	d.Close(5 * time.Second)
+
+	var body map[string]string
+	if err := json.Unmarshal(gotBody, &body); err != nil {
+		t.Fatalf("body %q: %v", gotBody, err)
+	}
+	if body["text"] != `exit "changed"` || body["sev"] != "CRITICAL" {
+		t.Fatalf("unexpected body: %v", body)
+	}
+	if gotSig != "sha256="+Sign([]byte("s3cret"), gotBody) {
+		t.Fatalf("bad signature header %q", gotSig)
+	}
+}
+
+func TestDispatcher_RetryThenDeadLetter(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	dead := filepath.Join(t.TempDir(), "dead.jsonl")
+	d := &Dispatcher{
+		Sinks:       []Sink{&ChatSink{URL: srv.URL}},
+		MinSeverity: "warning",
+		Retries:     2,
+		Backoff:     time.Millisecond,
+		DeadLetter:  dead,
+	}
+	d.Notify(Alert{Kind: "changed", Severity: "info", Message: "filtered"})
+	d.Notify(Alert{Kind: "connectivity_lost", Severity: "warning", Message: "offline"})
+	d.Close(5 * time.Second)
+
+	if n := calls.Load(); n != 3 {
+		t.Fatalf("expected 3 attempts, got %d", n)
+	}
+	b, err := os.ReadFile(dead)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if len(lines) != 1 || !strings.Contains(lines[0], "connectivity_lost") || !strings.Contains(lines[0], "502") {
+		t.Fatalf("unexpected dead letter: %s", b)
+	}
+}
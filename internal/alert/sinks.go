@@ -0,0 +1,168 @@
+// File: internal/alert/sinks.go (complete file)
+
+package alert
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const userAgent = "vpnleakidentifier/0.1"
+
+// WebhookSink POSTs a JSON body rendered from Template (the alert itself when
+// nil). With a Secret, the body is signed as X-Signature-256: sha256=<hex>.
+type WebhookSink struct {
+	URL      string
+	Template *template.Template
+	Secret   []byte
+	Client   *http.Client
+}
+
+// ParseBodyTemplate parses a webhook body template. Besides the alert fields
+// it provides a "json" function that renders any value as JSON, e.g.
+// {"text": {{json .Message}}, "sev": {{json .Severity}}}.
+func ParseBodyTemplate(text string) (*template.Template, error) {
+	return template.New("webhook").Funcs(template.FuncMap{
+		"json": func(v any) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+		"upper": strings.ToUpper,
+	}).Parse(text)
+}
+
+func (w *WebhookSink) Name() string { return "webhook" }
+
+func (w *WebhookSink) Send(ctx context.Context, a Alert) error {
+	body, err := w.render(a)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	if len(w.Secret) > 0 {
+		req.Header.Set("X-Signature-256", "sha256="+Sign(w.Secret, body))
+	}
+	return doPost(w.Client, req)
+}
+
+func (w *WebhookSink) render(a Alert) ([]byte, error) {
+	if w.Template == nil {
+		return json.Marshal(a)
+	}
+	var b bytes.Buffer
+	if err := w.Template.Execute(&b, a); err != nil {
+		return nil, err
+	}
+	if !json.Valid(b.Bytes()) {
+		return nil, errors.New("webhook template did not produce valid JSON")
+	}
+	return b.Bytes(), nil
+}
+
+// Sign returns the hex HMAC-SHA256 of body, as sent in X-Signature-256.
+func Sign(secret, body []byte) string {
+	m := hmac.New(sha256.New, secret)
+	m.Write(body)
+	return hex.EncodeToString(m.Sum(nil))
+}
+
+// ChatSink posts {"text": ...}, the payload accepted by Slack incoming
+// webhooks and Matrix webhook bridges (e.g. hookshot generic webhooks).
+type ChatSink struct {
+	URL    string
+	Client *http.Client
+}
+
+func (c *ChatSink) Name() string { return "chat" }
+
+func (c *ChatSink) Send(ctx context.Context, a Alert) error {
+	text := fmt.Sprintf("[%s] %s on %s: %s", strings.ToUpper(a.Severity), a.Kind, a.Host, a.Message)
+	body, err := json.Marshal(map[string]string{
+		"text":     text,
+		"username": "vpnleakidentifier",
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	return doPost(c.Client, req)
+}
+
+// CommandSink runs a local command with the alert JSON on stdin. The kind and
+// severity are also exported as VLI_ALERT_KIND / VLI_ALERT_SEVERITY.
+type CommandSink struct {
+	Argv []string
+}
+
+func (c *CommandSink) Name() string { return "command" }
+
+func (c *CommandSink) Send(ctx context.Context, a Alert) error {
+	if len(c.Argv) == 0 {
+		return errors.New("empty alert command")
+	}
+	body, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.CommandContext(ctx, c.Argv[0], c.Argv[1:]...)
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.Env = append(os.Environ(),
+		"VLI_ALERT_KIND="+a.Kind,
+		"VLI_ALERT_SEVERITY="+a.Severity,
+	)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		msg := strings.TrimSpace(string(out))
+		if len(msg) > 200 {
+			msg = msg[:200]
+		}
+		if msg != "" {
+			return fmt.Errorf("%w: %s", err, msg)
+		}
+		return err
+	}
+	return nil
+}
+
+func doPost(client *http.Client, req *http.Request) error {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("http status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+// File: internal/app/audit.go (complete file)
+
+package app
+
+import (
+	"context"
+
+	"github.com/baptistax/vpn-leak-identifier/internal/hostconf"
+	"github.com/baptistax/vpn-leak-identifier/internal/report"
+	"github.com/baptistax/vpn-leak-identifier/internal/resolved"
+)
+
+// AuditHost audits the sysctls and resolver configuration under root ("/"
+// when empty). On the live host systemd-resolved's links are read over
+// D-Bus, falling back to its state files; below another root everything,
+// interfaces included, comes from that tree. Each of names gets its
+// predicted systemd-resolved route.
+func AuditHost(ctx context.Context, root string, names ...string) report.HostConfig {
+	if root == "" || root == "/" {
+		return auditHost("/", resolvedLinks(ctx), names...)
+	}
+	return auditHost(root, nil, names...)
+}
+
+// auditHost audits root with systemd-resolved links already read over D-Bus
+// (nil reads the state files).
+func auditHost(root string, links []resolved.Link, names ...string) report.HostConfig {
+	a := hostconf.Audit(hostconf.Options{Root: root, Links: links})
+	h := report.HostConfig{
+		Tunnels:          a.Tunnels,
+		Nameservers:      a.ResolvConf.Nameservers,
+		ResolvConfTarget: a.ResolvConf.Target,
+		NSSwitchHosts:    a.NSSwitchHosts,
+		Errors:           a.Errors,
+	}
+	for _, s := range a.Sysctls {
+		h.Sysctls = append(h.Sysctls, report.HostSysctl{Name: s.Name(), Value: s.Value})
+	}
+	for _, l := range a.Links {
+		h.ResolvedLinks = append(h.ResolvedLinks, report.ResolvedLink{
+			Index:        l.Index,
+			Name:         l.Name,
+			Servers:      l.Servers,
+			Domains:      l.Domains,
+			DefaultRoute: l.DefaultRoute,
+		})
+	}
+	if len(a.Links) > 0 {
+		for _, n := range names {
+			h.DNSRoutes = append(h.DNSRoutes, mapDNSRoute(resolved.Predict(a.Links, n), root, a.Tunnels))
+		}
+	}
+	for _, f := range a.Findings {
+		h.Findings = append(h.Findings, report.HostFinding{Severity: f.Severity, Kind: f.Kind, Message: f.Message})
+	}
+	return h
+}
@@ -0,0 +1,130 @@
+// File: internal/app/networkmanager.go (complete file)
+
+package app
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/baptistax/vpn-leak-identifier/internal/nm"
+	"github.com/baptistax/vpn-leak-identifier/internal/report"
+)
+
+// nmSession watches NetworkManager during a test and optionally cycles a VPN
+// connection (down, then up again) to exercise the kill-switch on demand.
+type nmSession struct {
+	client *nm.Client
+	start  time.Time
+
+	cancelWatch context.CancelFunc
+	watchWG     sync.WaitGroup
+	cycleWG     sync.WaitGroup
+	phaseDone   chan struct{}
+
+	mu     sync.Mutex
+	events []report.ConnectionEvent
+}
+
+func startNetworkManager(ctx context.Context, start time.Time) (*nmSession, error) {
+	client, err := nm.Connect()
+	if err != nil {
+		return nil, err
+	}
+
+	watchCtx, cancel := context.WithCancel(ctx)
+	events, err := client.Watch(watchCtx)
+	if err != nil {
+		cancel()
+		client.Close()
+		return nil, err
+	}
+
+	s := &nmSession{
+		client:      client,
+		start:       start,
+		cancelWatch: cancel,
+		phaseDone:   make(chan struct{}),
+	}
+
+	s.watchWG.Add(1)
+	go func() {
+		defer s.watchWG.Done()
+		for ev := range events {
+			s.record(report.ConnectionEvent{
+				AtUTC:      ev.AtUTC,
+				Kind:       ev.Kind,
+				Connection: ev.Name,
+				State:      ev.State,
+			})
+		}
+	}()
+	return s, nil
+}
+
+// cycle deactivates name, waits downFor (or until the test ends) and
+// reactivates it. Reactivation uses its own context so an interrupted run does
+// not leave the VPN down.
+func (s *nmSession) cycle(ctx context.Context, name string, downFor time.Duration) {
+	s.cycleWG.Add(1)
+	go func() {
+		defer s.cycleWG.Done()
+
+		err := s.client.Deactivate(ctx, name)
+		s.recordAction(name, "deactivate", err)
+		if err != nil {
+			return
+		}
+
+		t := time.NewTimer(downFor)
+		defer t.Stop()
+		select {
+		case <-t.C:
+		case <-s.phaseDone:
+		case <-ctx.Done():
+		}
+
+		actx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		_, err = s.client.Activate(actx, name)
+		s.recordAction(name, "activate", err)
+	}()
+}
+
+func (s *nmSession) recordAction(name, action string, err error) {
+	ev := report.ConnectionEvent{
+		AtUTC:      time.Now().UTC(),
+		Kind:       "action",
+		Connection: name,
+		State:      action + " requested",
+	}
+	if err != nil {
+		ev.Error = err.Error()
+	}
+	s.record(ev)
+}
+
+func (s *nmSession) record(ev report.ConnectionEvent) {
+	ev.Source = "networkmanager"
+	ev.AtSec = int(ev.AtUTC.Sub(s.start).Seconds())
+
+	s.mu.Lock()
+	s.events = append(s.events, ev)
+	s.mu.Unlock()
+}
+
+// finish reactivates a cycled connection if it is still down, stops watching
+// and returns the events in timeline order.
+func (s *nmSession) finish() []report.ConnectionEvent {
+	close(s.phaseDone)
+	s.cycleWG.Wait()
+	s.cancelWatch()
+	s.watchWG.Wait()
+	s.client.Close()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sort.SliceStable(s.events, func(i, j int) bool { return s.events[i].AtUTC.Before(s.events[j].AtUTC) })
+	return s.events
+}
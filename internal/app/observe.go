@@ -0,0 +1,388 @@
+// File: internal/app/observe.go (complete file)
+
+package app
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/baptistax/vpn-leak-identifier/internal/geo"
+	"github.com/baptistax/vpn-leak-identifier/internal/ipv6"
+	"github.com/baptistax/vpn-leak-identifier/internal/leaks"
+	"github.com/baptistax/vpn-leak-identifier/internal/netutil"
+	"github.com/baptistax/vpn-leak-identifier/internal/report"
+	"github.com/baptistax/vpn-leak-identifier/internal/resolved"
+)
+
+var defaultStunServers = []string{
+	"stun.l.google.com:19302",
+	"stun1.l.google.com:19302",
+	"stun2.l.google.com:19302",
+}
+
+// observer runs the probes shared by snapshot, test and monitor.
+type observer struct {
+	ipv4Client *http.Client
+	ipv6Client *http.Client
+	hasIPv6    bool
+
+	enableSTUN  bool
+	stunServers []string
+
+	geoProviders []geo.Provider
+	geoQuorum    int
+
+	// offline and cymru, when set, annotate observed IPs from local MMDB
+	// files and Team Cymru origin lookups.
+	offline *geo.Offline
+	cymru   *geo.Cymru
+
+	// links are systemd-resolved's links as read by the last observe, so a
+	// host audit in the same snapshot does not dial the bus again.
+	links []resolved.Link
+
+	// wireGuardDenied is set once the WireGuard read failed for lack of
+	// privileges, so the note is added to one probe only.
+	wireGuardDenied bool
+
+	// record, when set, receives the timing of every probe.
+	record func(prober, endpoint string, start time.Time, err error)
+}
+
+func newObserver(enableSTUN bool, stunServers []string, geoProviders []geo.Provider, geoQuorum int) *observer {
+	if len(stunServers) == 0 {
+		stunServers = defaultStunServers
+	}
+	if len(geoProviders) == 0 {
+		geoProviders = geo.DefaultProviders
+	}
+	return &observer{
+		ipv4Client:   netutil.HTTPClientForFamily("ipv4"),
+		ipv6Client:   netutil.HTTPClientForFamily("ipv6"),
+		hasIPv6:      netutil.HasGlobalIPv6(),
+		enableSTUN:   enableSTUN,
+		stunServers:  stunServers,
+		geoProviders: geoProviders,
+		geoQuorum:    geoQuorum,
+	}
+}
+
+// observe fills an Observation: exit with geo per family, DNS recursors,
+// STUN per server and the local interfaces. Failed probes become notes.
+func (ob *observer) observe(ctx context.Context) (report.Observation, []string) {
+	var o report.Observation
+	var notes []string
+
+	// Exit IP + geo, agreed on by a quorum of providers.
+	o.ExitV4 = ob.exit(ctx, "ipv4", ob.ipv4Client)
+	if ob.hasIPv6 {
+		o.ExitV6 = ob.exit(ctx, "ipv6", ob.ipv6Client)
+	} else {
+		o.ExitV6 = report.ExitInfo{Family: "ipv6", Source: "geo", Error: "disabled"}
+	}
+	for _, e := range []report.ExitInfo{o.ExitV4, o.ExitV6} {
+		if c := e.Geo.Consensus; c != nil && (c.Status == string(geo.StatusDisputed) || c.Status == string(geo.StatusInsufficient)) {
+			notes = append(notes, "geo providers "+c.Status+" on the "+e.Family+" exit "+e.IP)
+		}
+	}
+
+	// DNS recursors per query family and transport.
+	o.SetRecursors(ob.recursors(ctx))
+	if len(o.DNSRecursors) == 0 {
+		msg := "no IPs returned"
+		for _, r := range o.Recursors {
+			if r.Error != "" {
+				msg = r.Error
+				break
+			}
+		}
+		notes = append(notes, "ns.ident.me lookup failed: "+msg)
+	}
+	ob.links = resolvedLinks(ctx)
+	if o.DNSRoute = dnsRoute(ob.links); o.DNSRoute != nil {
+		if out := o.DNSRoute.Exposed(); len(out) > 0 {
+			notes = append(notes, "systemd-resolved sends ns.ident.me to "+strings.Join(out, ", ")+" outside the tunnel")
+		}
+	}
+
+	// STUN observed, one query per server so each gets its own result.
+	if ob.enableSTUN {
+		o.Stun = ob.stun(ctx)
+		var lastErr string
+		for _, r := range o.Stun {
+			o.StunObserved = appendUnique(o.StunObserved, r.Observed...)
+			if r.Error != "" {
+				lastErr = r.Error
+			}
+		}
+		if len(o.StunObserved) == 0 && lastErr != "" {
+			notes = append(notes, "stun failed: "+lastErr)
+		}
+	}
+
+	observed := append([]string{o.ExitV4.IP, o.ExitV6.IP}, o.DNSRecursors...)
+	ob.annotate(ctx, &o, append(observed, o.StunObserved...)...)
+
+	if ifaces, err := netutil.Interfaces(); err == nil {
+		o.Interfaces = mapInterfaces(ifaces)
+	} else {
+		notes = append(notes, "interface listing failed: "+err.Error())
+	}
+	return o, notes
+}
+
+func (ob *observer) exit(ctx context.Context, family string, client *http.Client) report.ExitInfo {
+	ctxp, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	res := geo.Query(ctxp, client, family, ob.geoProviders, ob.geoQuorum)
+	for _, a := range res.Answers {
+		ob.timing(a.Provider, a.Endpoint, time.Now().Add(-a.Elapsed), a.Err)
+	}
+	return exitFromConsensus(family, res)
+}
+
+func (ob *observer) recursors(ctx context.Context) []report.RecursorResult {
+	ctxp, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	lookups := leaks.LookupRecursorsViaIdentMe(ctxp)
+	out := make([]report.RecursorResult, 0, len(lookups))
+	for _, l := range lookups {
+		r := report.RecursorResult{Query: l.Name, Family: l.Family, Transport: l.Transport, IPs: l.IPs}
+		if l.Err != nil {
+			r.Error = l.Err.Error()
+		}
+		ob.timing("ns.ident.me", l.Name+"/"+l.Family, time.Now().Add(-l.Elapsed), l.Err)
+		out = append(out, r)
+	}
+	return out
+}
+
+func (ob *observer) stun(ctx context.Context) []report.StunResult {
+	ctxp, cancel := context.WithTimeout(ctx, 6*time.Second)
+	defer cancel()
+
+	out := make([]report.StunResult, len(ob.stunServers))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i, server := range ob.stunServers {
+		wg.Add(1)
+		go func(i int, server string) {
+			defer wg.Done()
+			start := time.Now()
+			ips, err := leaks.StunObservedIPs(ctxp, []string{server})
+			r := report.StunResult{Server: server, Observed: ips}
+			if err != nil {
+				r.Error = err.Error()
+			}
+			out[i] = r
+
+			mu.Lock()
+			ob.timing("stun", server, start, err)
+			mu.Unlock()
+		}(i, server)
+	}
+	wg.Wait()
+	return out
+}
+
+// annotate adds offline geo/ASN data and, with cymru set, the routing
+// origin of ips to o.IPGeo. Lookups run in parallel.
+func (ob *observer) annotate(ctx context.Context, o *report.Observation, ips ...string) {
+	if ob.offline == nil && ob.cymru == nil {
+		return
+	}
+	var todo []string
+	for _, ip := range ips {
+		if _, done := o.IPGeo[ip]; done || ip == "" {
+			continue
+		}
+		todo = appendUnique(todo, ip)
+	}
+
+	infos := make([]report.GeoInfo, len(todo))
+	var wg sync.WaitGroup
+	for i, ip := range todo {
+		wg.Add(1)
+		go func(i int, ip string) {
+			defer wg.Done()
+			infos[i] = ob.lookupIP(ctx, ip)
+		}(i, ip)
+	}
+	wg.Wait()
+
+	for i, ip := range todo {
+		if infos[i].Source == "" {
+			continue
+		}
+		if o.IPGeo == nil {
+			o.IPGeo = map[string]report.GeoInfo{}
+		}
+		o.IPGeo[ip] = infos[i]
+	}
+}
+
+// lookupIP merges the MMDB record with the routing origin; the origin ASN
+// wins since it reflects current BGP rather than a database build.
+func (ob *observer) lookupIP(ctx context.Context, ip string) report.GeoInfo {
+	var g report.GeoInfo
+	var sources []string
+	if ob.offline != nil {
+		if a, err := ob.offline.Lookup(ip); err == nil && a.Provider != "" {
+			g = report.GeoInfo{
+				Country:     a.Country,
+				CountryCode: a.CountryCode,
+				Region:      a.Region,
+				City:        a.City,
+				ISP:         a.ISP,
+				ASN:         a.ASN,
+				Timezone:    a.Timezone,
+			}
+			sources = append(sources, "mmdb:"+a.Provider)
+		}
+	}
+	if ob.cymru != nil {
+		lctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+		defer cancel()
+		if org, err := ob.cymru.Lookup(lctx, ip); err == nil {
+			g.ASN, g.Prefix, g.Registry = org.ASN, org.Prefix, org.Registry
+			if g.ISP == "" {
+				g.ISP = org.ASName
+			}
+			if g.CountryCode == "" {
+				g.CountryCode = org.CountryCode
+			}
+			sources = append(sources, "cymru")
+		}
+	}
+	g.Source = strings.Join(sources, ",")
+	return g
+}
+
+// analyzeIPv6 classifies the local and exit IPv6 addresses of o and runs
+// RFC 7050 NAT64 discovery on the system resolver.
+func (ob *observer) analyzeIPv6(ctx context.Context, o report.Observation) (*report.IPv6Analysis, []string) {
+	var notes []string
+	ctxp, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	start := time.Now()
+	nat64, err := ipv6.DiscoverNAT64(ctxp, nil)
+	ob.timing("nat64", "ipv4only.arpa", start, err)
+	if err != nil {
+		notes = append(notes, "NAT64 discovery (ipv4only.arpa) failed: "+err.Error())
+	}
+
+	local, err := ipv6.Local(nat64.Prefixes...)
+	if err != nil {
+		notes = append(notes, "IPv6 address listing failed: "+err.Error())
+	}
+	in := ipv6.Input{
+		Local:    local,
+		EgressV4: o.EgressInterface("ipv4"),
+		EgressV6: o.EgressInterface("ipv6"),
+		NAT64:    nat64,
+	}
+	if o.ExitV4.Error == "" {
+		in.ExitV4 = o.ExitV4.IP
+	}
+	if o.ExitV6.Error == "" {
+		in.ExitV6 = o.ExitV6.IP
+	}
+	res := ipv6.Analyze(in)
+
+	out := &report.IPv6Analysis{}
+	if res.Exit != nil {
+		e := mapIPv6Address(*res.Exit)
+		out.Exit = &e
+	}
+	for _, a := range res.Local {
+		out.Local = append(out.Local, mapIPv6Address(a))
+	}
+	for _, p := range nat64.Prefixes {
+		out.NAT64Prefixes = append(out.NAT64Prefixes, p.String())
+	}
+	for _, f := range res.Findings {
+		out.Findings = append(out.Findings, report.IPv6Finding{Severity: f.Severity, Kind: f.Kind, Message: f.Message})
+	}
+	return out, notes
+}
+
+func mapIPv6Address(a ipv6.Address) report.IPv6Address {
+	out := report.IPv6Address{
+		IP:           a.IP,
+		Interface:    a.Interface,
+		MAC:          a.MAC,
+		EmbeddedIPv4: a.EmbeddedIPv4,
+		TeredoServer: a.TeredoServer,
+		TeredoPort:   a.TeredoPort,
+	}
+	for _, k := range a.Kinds {
+		out.Kinds = append(out.Kinds, string(k))
+	}
+	return out
+}
+
+func (ob *observer) timing(prober, endpoint string, start time.Time, err error) {
+	if ob.record != nil {
+		ob.record(prober, endpoint, start, err)
+	}
+}
+
+// exitFromConsensus maps a geo consensus to an exit. Source names the
+// providers that agreed on the IP.
+func exitFromConsensus(family string, res geo.Result) report.ExitInfo {
+	out := report.ExitInfo{Family: family, Source: "geo"}
+	if err := res.Err(); err != nil {
+		out.Error = err.Error()
+		return out
+	}
+	g := res.Geo
+	out.IP = g.IP
+	out.Source = strings.Join(res.Agreeing, ",")
+	out.Geo = report.GeoInfo{
+		Country:     g.Country,
+		CountryCode: g.CountryCode,
+		Region:      g.Region,
+		City:        g.City,
+		ISP:         g.ISP,
+		ASN:         g.ASN,
+		Timezone:    g.Timezone,
+		Consensus: &report.GeoConsensus{
+			Status:   string(res.Status),
+			Quorum:   res.Quorum,
+			Agreeing: res.Agreeing,
+		},
+	}
+	for _, d := range res.Disagreements {
+		out.Geo.Consensus.Disagreements = append(out.Geo.Consensus.Disagreements,
+			report.GeoDisagreement{Field: d.Field, Values: d.Values})
+	}
+	for _, a := range res.Answers {
+		ga := report.GeoAnswer{Provider: a.Provider}
+		if a.Err != nil {
+			ga.Error = a.Err.Error()
+		} else {
+			ga.IP, ga.CountryCode, ga.City, ga.ASN, ga.ISP = a.IP, a.CountryCode, a.City, a.ASN, a.ISP
+		}
+		out.Geo.Consensus.Providers = append(out.Geo.Consensus.Providers, ga)
+	}
+	return out
+}
+
+func mapInterfaces(in []netutil.Interface) []report.InterfaceInfo {
+	out := make([]report.InterfaceInfo, 0, len(in))
+	for _, i := range in {
+		out = append(out, report.InterfaceInfo{
+			Name:   i.Name,
+			Index:  i.Index,
+			MTU:    i.MTU,
+			Up:     i.Up,
+			Addrs:  i.Addrs,
+			Egress: i.Egress,
+		})
+	}
+	return out
+}
@@ -0,0 +1,75 @@
+// File: internal/app/resolved.go (complete file)
+
+package app
+
+import (
+	"context"
+	"runtime"
+	"time"
+
+	"github.com/baptistax/vpn-leak-identifier/internal/hostconf"
+	"github.com/baptistax/vpn-leak-identifier/internal/netutil"
+	"github.com/baptistax/vpn-leak-identifier/internal/report"
+	"github.com/baptistax/vpn-leak-identifier/internal/resolved"
+)
+
+// resolvedLinks reads systemd-resolved's links over D-Bus. It returns nil
+// when resolved is not running or the system bus is unreachable, which is
+// the normal case off systemd desktops.
+func resolvedLinks(ctx context.Context) []resolved.Link {
+	if runtime.GOOS != "linux" {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	c, err := resolved.Connect()
+	if err != nil {
+		return nil
+	}
+	defer c.Close()
+	links, err := c.Links(ctx)
+	if err != nil {
+		return nil
+	}
+	return links
+}
+
+// dnsRoute predicts the systemd-resolved link the recursor lookups of
+// ns.ident.me leave through.
+func dnsRoute(links []resolved.Link) *report.DNSRoute {
+	if len(links) == 0 {
+		return nil
+	}
+	r := mapDNSRoute(resolved.Predict(links, "ns.ident.me"), "/", upTunnels())
+	return &r
+}
+
+// upTunnels lists the live host's up VPN interfaces.
+func upTunnels() []string {
+	ifaces, err := netutil.Interfaces()
+	if err != nil {
+		return nil
+	}
+	var out []string
+	for _, i := range ifaces {
+		if i.Up && hostconf.IsTunnel("/", i.Name) {
+			out = append(out, i.Name)
+		}
+	}
+	return out
+}
+
+// mapDNSRoute marks each predicted link as tunnel or not by its type under
+// root; tunnels are the VPN interfaces that are up.
+func mapDNSRoute(r resolved.Route, root string, tunnels []string) report.DNSRoute {
+	out := report.DNSRoute{Name: r.Name, Domain: r.Domain, Reason: r.Reason, Tunnels: tunnels}
+	for _, l := range r.Links {
+		out.Links = append(out.Links, report.DNSRouteLink{
+			Name:    l.Name,
+			Servers: l.Servers,
+			Tunnel:  hostconf.IsTunnel(root, l.Name),
+		})
+	}
+	return out
+}
@@ -4,8 +4,10 @@ package app
 
 import (
 	"context"
+	"runtime"
 	"time"
 
+	"github.com/baptistax/vpn-leak-identifier/internal/geo"
 	"github.com/baptistax/vpn-leak-identifier/internal/leaks"
 	"github.com/baptistax/vpn-leak-identifier/internal/netutil"
 	"github.com/baptistax/vpn-leak-identifier/internal/report"
@@ -16,19 +18,39 @@ type SnapshotOptions struct {
 	EnableSTUN        bool
 	DNSQueries        int
 	StunServers       []string
+
+	// GeoProviders answer the exit IP and geo; GeoQuorum of them must agree.
+	GeoProviders []geo.Provider
+	GeoQuorum    int
+	// GeoIP and Cymru, when set, annotate every observed IP with geo/ASN
+	// data from local MMDB files and with its routing origin.
+	GeoIP *geo.Offline
+	Cymru *geo.Cymru
+
+	// DNSProfile lists the VPN provider's resolvers for the DNS leak
+	// classification.
+	DNSProfile report.DNSProfile
+
+	// SkipHostAudit leaves Host unset. The monitor audits the host on its
+	// first snapshot and after kernel network events only.
+	SkipHostAudit bool
 }
 
 func TakeSnapshot(ctx context.Context, opt SnapshotOptions) report.Snapshot {
-	s := report.Snapshot{TimestampUTC: time.Now().UTC()}
+	s := report.Snapshot{SchemaVersion: report.SchemaVersion, TimestampUTC: time.Now().UTC()}
 
-	ipv4Client := netutil.HTTPClientForFamily("ipv4")
-	ipv6Client := netutil.HTTPClientForFamily("ipv6")
+	ob := newObserver(opt.EnableSTUN, opt.StunServers, opt.GeoProviders, opt.GeoQuorum)
+	ob.offline, ob.cymru = opt.GeoIP, opt.Cymru
+	ob.record = func(prober, endpoint string, start time.Time, err error) {
+		recordProbe(&s, prober, endpoint, start, err)
+	}
 	anyClient := netutil.HTTPClientForFamily("any")
-	hasIPv6 := netutil.HasGlobalIPv6()
 
-	// Public IP probes.
+	// Public IP probes (ipify), a second opinion on the geo exits.
 	{
-		ip, err := leaks.FetchIPFromJSON(ctx, ipv4Client, "https://api.ipify.org?format=json")
+		start := time.Now()
+		ip, err := leaks.FetchIPFromJSON(ctx, ob.ipv4Client, "https://api.ipify.org?format=json")
+		recordProbe(&s, "ipify", "api.ipify.org", start, err)
 		r := report.PublicIPResult{Source: "ipify", Family: "ipv4"}
 		if err != nil {
 			r.Error = err.Error()
@@ -39,10 +61,12 @@ func TakeSnapshot(ctx context.Context, opt SnapshotOptions) report.Snapshot {
 	}
 	{
 		r := report.PublicIPResult{Source: "ipify", Family: "ipv6"}
-		if !hasIPv6 {
+		if !ob.hasIPv6 {
 			r.Error = "disabled"
 		} else {
-			ip, err := leaks.FetchIPFromJSON(ctx, ipv6Client, "https://api6.ipify.org?format=json")
+			start := time.Now()
+			ip, err := leaks.FetchIPFromJSON(ctx, ob.ipv6Client, "https://api6.ipify.org?format=json")
+			recordProbe(&s, "ipify", "api6.ipify.org", start, err)
 			if err != nil {
 				r.Error = err.Error()
 			} else {
@@ -52,7 +76,9 @@ func TakeSnapshot(ctx context.Context, opt SnapshotOptions) report.Snapshot {
 		s.PublicIPs = append(s.PublicIPs, r)
 	}
 	{
+		start := time.Now()
 		ip, err := leaks.FetchIPFromJSON(ctx, anyClient, "https://api64.ipify.org?format=json")
+		recordProbe(&s, "ipify", "api64.ipify.org", start, err)
 		r := report.PublicIPResult{Source: "ipify", Family: "any"}
 		if err != nil {
 			r.Error = err.Error()
@@ -62,42 +88,72 @@ func TakeSnapshot(ctx context.Context, opt SnapshotOptions) report.Snapshot {
 		s.PublicIPs = append(s.PublicIPs, r)
 	}
 
-	// DNS recursors hint.
-	if ips, err := leaks.LookupRecursorIPsViaIdentMe(); err == nil {
-		s.DnsRecursors = ips
-	} else {
-		s.Notes = append(s.Notes, "ns.ident.me lookup failed: "+err.Error())
-	}
+	// Exits with geo, DNS recursors, STUN and interfaces.
+	obs, notes := ob.observe(ctx)
+	s.Observation = obs
+	s.Notes = append(s.Notes, notes...)
 
 	// dnsleaktest.com flow.
 	if opt.EnableDNSLeakTest {
+		start := time.Now()
 		servers, err := leaks.DNSLeakTestViaDNSLeakTestCom(ctx, opt.DNSQueries)
+		recordProbe(&s, "dnsleaktest", "dnsleaktest.com", start, err)
 		if err != nil {
 			s.Notes = append(s.Notes, "dnsleaktest.com failed: "+err.Error())
 		} else {
 			s.DnsLeak = mapDNSLeakServers(servers)
+			var ips []string
+			for _, d := range s.DnsLeak {
+				ips = append(ips, d.IPAddress)
+			}
+			ob.annotate(ctx, &s.Observation, ips...)
 		}
 	}
 
-	// STUN observed.
-	if opt.EnableSTUN {
-		servers := opt.StunServers
-		if len(servers) == 0 {
-			servers = []string{
-				"stun.l.google.com:19302",
-				"stun1.l.google.com:19302",
-				"stun2.l.google.com:19302",
+	// Classify every recursor seen, including dnsleaktest.com's, against
+	// the snapshot's own exits.
+	dns := s.Observation
+	for _, d := range s.DnsLeak {
+		dns.DNSRecursors = appendUnique(dns.DNSRecursors, d.IPAddress)
+	}
+	s.DNS = report.ClassifyDNS(dns, s.Observation, opt.DNSProfile)
+
+	// IPv6 address kinds, NAT64 and the paths around a v4-only tunnel.
+	v6, notes := ob.analyzeIPv6(ctx, s.Observation)
+	s.IPv6 = v6
+	s.Notes = append(s.Notes, notes...)
+
+	if runtime.GOOS == "linux" && !opt.SkipHostAudit {
+		h := auditHost("/", ob.links)
+		s.Host = &h
+	}
+
+	return s
+}
+
+func recordProbe(s *report.Snapshot, prober, endpoint string, start time.Time, err error) {
+	s.Probes = append(s.Probes, report.ProbeTiming{
+		Prober:     prober,
+		Endpoint:   endpoint,
+		DurationMS: time.Since(start).Milliseconds(),
+		OK:         err == nil,
+	})
+}
+
+func appendUnique(list []string, vals ...string) []string {
+	for _, v := range vals {
+		found := false
+		for _, x := range list {
+			if x == v {
+				found = true
+				break
 			}
 		}
-		ips, err := leaks.StunObservedIPs(ctx, servers)
-		if err != nil {
-			s.Notes = append(s.Notes, "stun failed: "+err.Error())
-		} else {
-			s.StunObserved = ips
+		if !found {
+			list = append(list, v)
 		}
 	}
-
-	return s
+	return list
 }
 
 func mapDNSLeakServers(in []leaks.DNSLeakServer) []report.DnsLeakServer {
@@ -4,12 +4,14 @@ package app
 
 import (
 	"context"
-	"net/http"
+	"errors"
+	"runtime"
+	"syscall"
 	"time"
 
-	"github.com/baptistax/vpn-leak-identifier/internal/leaks"
-	"github.com/baptistax/vpn-leak-identifier/internal/netutil"
+	"github.com/baptistax/vpn-leak-identifier/internal/geo"
 	"github.com/baptistax/vpn-leak-identifier/internal/report"
+	"github.com/baptistax/vpn-leak-identifier/internal/wireguard"
 )
 
 type TestOptions struct {
@@ -19,6 +21,24 @@ type TestOptions struct {
 	Interval    time.Duration
 	EnableSTUN  bool
 	StunServers []string
+
+	// GeoProviders answer the exit IP and geo; GeoQuorum of them must agree.
+	GeoProviders []geo.Provider
+	GeoQuorum    int
+	// GeoIP and Cymru, when set, annotate every observed IP with geo/ASN
+	// data from local MMDB files and with its routing origin.
+	GeoIP *geo.Offline
+	Cymru *geo.Cymru
+
+	// DNSProfile lists the VPN provider's resolvers for the DNS leak
+	// classification.
+	DNSProfile report.DNSProfile
+
+	// NetworkManager integration: watch state changes and optionally cycle
+	// (deactivate, then reactivate) a named VPN connection after the baseline.
+	WatchNetworkManager bool
+	NMCycle             string
+	NMDownFor           time.Duration
 }
 
 func RunTest(ctx context.Context, opt TestOptions) report.RunReport {
@@ -34,18 +54,27 @@ func RunTest(ctx context.Context, opt TestOptions) report.RunReport {
 
 	r := report.NewRunReport(opt.Mode, opt.Duration, opt.Interval, opt.Baseline)
 
-	ipv4Client := netutil.HTTPClientForFamily("ipv4")
-	ipv6Client := netutil.HTTPClientForFamily("ipv6")
-	hasIPv6 := netutil.HasGlobalIPv6()
+	ob := newObserver(opt.EnableSTUN, opt.StunServers, opt.GeoProviders, opt.GeoQuorum)
+	ob.offline, ob.cymru = opt.GeoIP, opt.Cymru
 
 	start := time.Now()
 	deadline := start.Add(opt.Duration)
 
+	var nms *nmSession
+	if opt.WatchNetworkManager || opt.NMCycle != "" {
+		s, err := startNetworkManager(ctx, start)
+		if err != nil {
+			r.Notes = append(r.Notes, "networkmanager unavailable: "+err.Error())
+		} else {
+			nms = s
+		}
+	}
+
 	// Baseline phase: keep the last successful probe as baseline.
 	var baseline report.ProbeSet
 	baselineDeadline := start.Add(opt.Baseline)
 	for time.Now().Before(baselineDeadline) {
-		ps := takeProbeSet(ctx, start, ipv4Client, ipv6Client, hasIPv6, opt)
+		ps := takeProbeSet(ctx, start, ob)
 		r.Probes = append(r.Probes, ps)
 		if ps.Online {
 			baseline = ps
@@ -62,17 +91,36 @@ func RunTest(ctx context.Context, opt TestOptions) report.RunReport {
 		r.Baseline = baseline
 	}
 
+	// IPv6 paths around the tunnel, as set up for the baseline.
+	v6, notes := ob.analyzeIPv6(ctx, r.Baseline.Observation)
+	r.IPv6 = v6
+	r.Notes = append(r.Notes, notes...)
+	if runtime.GOOS == "linux" {
+		h := auditHost("/", ob.links)
+		r.Host = &h
+	}
+
+	if nms != nil && opt.NMCycle != "" {
+		downFor := opt.NMDownFor
+		if downFor <= 0 {
+			downFor = 10 * time.Second
+		}
+		nms.cycle(ctx, opt.NMCycle, downFor)
+	}
+
 	// Main phase.
 	var last report.ProbeSet
 	var consecutiveOffline int
 	for time.Now().Before(deadline) {
-		ps := takeProbeSet(ctx, start, ipv4Client, ipv6Client, hasIPv6, opt)
+		ps := takeProbeSet(ctx, start, ob)
 		r.Probes = append(r.Probes, ps)
-		last = ps
 
 		// Detect first exit deltas for v4/v6.
 		r.MaybeRecordExitDelta(baseline, ps)
 		r.MaybeRecordDNSDelta(baseline, ps)
+		r.MaybeRecordTunnelBypass(baseline, last, ps)
+
+		last = ps
 
 		// Offline detection for kill-switch behavior.
 		if baseline.Online && !ps.Online {
@@ -91,84 +139,68 @@ func RunTest(ctx context.Context, opt TestOptions) report.RunReport {
 	}
 
 	r.End = last
+	if nms != nil {
+		r.ConnectionEvents = nms.finish()
+	}
+	r.ClassifyDNS(opt.DNSProfile)
 	r.Finish()
 
 	return r
 }
 
-func takeProbeSet(ctx context.Context, start time.Time, ipv4Client, ipv6Client *http.Client, hasIPv6 bool, opt TestOptions) report.ProbeSet {
+func takeProbeSet(ctx context.Context, start time.Time, ob *observer) report.ProbeSet {
 	ps := report.NewProbeSet()
 	ps.AtSec = int(time.Since(start).Seconds())
 
-	// Exit IP + geo.
-	{
-		ctxp, cancel := context.WithTimeout(ctx, 3*time.Second)
-		info, err := leaks.FetchIdentInfo(ctxp, ipv4Client, "ipv4")
-		cancel()
-		ps.ExitV4 = exitFromIdent("ipv4", info, err)
-	}
-	if hasIPv6 {
-		ctxp, cancel := context.WithTimeout(ctx, 3*time.Second)
-		info, err := leaks.FetchIdentInfo(ctxp, ipv6Client, "ipv6")
-		cancel()
-		ps.ExitV6 = exitFromIdent("ipv6", info, err)
-	} else {
-		ps.ExitV6 = report.ExitInfo{Family: "ipv6", Source: "ident.me/json", Error: "disabled"}
-	}
-
-	// DNS recursors hint.
-	{
-		ips, err := leaks.LookupRecursorIPsViaIdentMe()
-		if err == nil {
-			ps.DNSRecursors = ips
-		} else {
-			ps.Notes = append(ps.Notes, "ns.ident.me lookup failed: "+err.Error())
-		}
-	}
-
-	// STUN observed.
-	if opt.EnableSTUN {
-		servers := opt.StunServers
-		if len(servers) == 0 {
-			servers = []string{
-				"stun.l.google.com:19302",
-				"stun1.l.google.com:19302",
-				"stun2.l.google.com:19302",
-			}
-		}
-
-		ctxp, cancel := context.WithTimeout(ctx, 6*time.Second)
-		ips, err := leaks.StunObservedIPs(ctxp, servers)
-		cancel()
-		if err == nil {
-			ps.StunObserved = ips
-		} else {
-			ps.Notes = append(ps.Notes, "stun failed: "+err.Error())
+	obs, notes := ob.observe(ctx)
+	ps.Observation = obs
+	ps.Notes = append(ps.Notes, notes...)
+
+	// WireGuard state is read last so the counters include this probe's traffic.
+	wctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	devs, err := wireguard.Devices(wctx)
+	cancel()
+	switch {
+	case err == nil:
+		ps.WireGuard = mapWireGuardDevices(devs)
+	case errors.Is(err, wireguard.ErrNotSupported):
+	case errors.Is(err, syscall.EPERM) || errors.Is(err, syscall.EACCES):
+		// Unprivileged runs fail the same way on every probe; say so once.
+		if !ob.wireGuardDenied {
+			ob.wireGuardDenied = true
+			ps.Notes = append(ps.Notes, "wireguard peer state not recorded: reading it needs CAP_NET_ADMIN (run as root)")
 		}
+	default:
+		ps.Notes = append(ps.Notes, "wireguard netlink read failed: "+err.Error())
 	}
 
 	ps.DeriveOnline()
 	return ps
 }
 
-func exitFromIdent(family string, info leaks.IdentInfo, err error) report.ExitInfo {
-	out := report.ExitInfo{
-		Family: family,
-		Source: "ident.me/json",
-	}
-	if err != nil {
-		out.Error = err.Error()
-		return out
-	}
-	out.IP = info.IP
-	out.Geo = report.GeoInfo{
-		Country:     info.Country,
-		CountryCode: info.CountryCode,
-		Region:      info.Region,
-		City:        info.City,
-		ISP:         info.ISP,
-		ASN:         info.ASN,
-		Timezone:    info.Timezone,
+func mapWireGuardDevices(in []wireguard.Device) []report.WireGuardDevice {
+	out := make([]report.WireGuardDevice, 0, len(in))
+	for _, d := range in {
+		rd := report.WireGuardDevice{
+			Name:       d.Name,
+			PublicKey:  d.PublicKey,
+			ListenPort: d.ListenPort,
+		}
+		for _, p := range d.Peers {
+			rp := report.WireGuardPeer{
+				PublicKey:  p.PublicKey,
+				Endpoint:   p.Endpoint,
+				RxBytes:    p.RxBytes,
+				TxBytes:    p.TxBytes,
+				AllowedIPs: p.AllowedIPs,
+			}
+			if !p.LastHandshake.IsZero() {
+				t := p.LastHandshake
+				rp.LastHandshakeUTC = &t
+			}
+			rd.Peers = append(rd.Peers, rp)
+		}
+		out = append(out, rd)
 	}
 	return out
 }
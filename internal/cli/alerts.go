@@ -0,0 +1,135 @@
+// File: internal/cli/alerts.go (complete file)
+
+package cli
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/baptistax/vpn-leak-identifier/internal/alert"
+	"github.com/baptistax/vpn-leak-identifier/internal/monitor"
+	"github.com/baptistax/vpn-leak-identifier/internal/report"
+)
+
+type alertFlags struct {
+	Webhook         string
+	WebhookTemplate string
+	WebhookSecret   string
+	ChatWebhook     string
+	Command         string
+	MinSeverity     string
+	Retries         int
+	DeadLetter      string
+}
+
+func bindAlerts(fs *flag.FlagSet) *alertFlags {
+	a := &alertFlags{}
+	fs.StringVar(&a.Webhook, "webhook", "", "POST alerts as JSON to this URL")
+	fs.StringVar(&a.WebhookTemplate, "webhook-template", "", "File with a text/template for the --webhook JSON body")
+	fs.StringVar(&a.WebhookSecret, "webhook-secret", "", "HMAC-SHA256 secret for X-Signature-256 (default: $VLI_WEBHOOK_SECRET)")
+	fs.StringVar(&a.ChatWebhook, "chat-webhook", "", "Slack/Matrix-compatible incoming webhook URL")
+	fs.StringVar(&a.Command, "alert-cmd", "", "Command to run per alert; the alert JSON is written to its stdin")
+	fs.StringVar(&a.MinSeverity, "alert-min-severity", "warning", "Lowest severity to deliver: info|warning|critical")
+	fs.IntVar(&a.Retries, "alert-retries", 3, "Delivery retries per sink (exponential backoff)")
+	fs.StringVar(&a.DeadLetter, "alert-dead-letter", "", "JSONL file for undeliverable alerts (default: <exports>/alerts_dead_letter.jsonl)")
+	return a
+}
+
+// dispatcher builds the configured sinks; it returns nil when none are set.
+func (a *alertFlags) dispatcher(exports string) (*alert.Dispatcher, error) {
+	var sinks []alert.Sink
+
+	if a.Webhook != "" {
+		w := &alert.WebhookSink{URL: a.Webhook}
+		secret := a.WebhookSecret
+		if secret == "" {
+			secret = os.Getenv("VLI_WEBHOOK_SECRET")
+		}
+		if secret != "" {
+			w.Secret = []byte(secret)
+		}
+		if a.WebhookTemplate != "" {
+			b, err := os.ReadFile(a.WebhookTemplate)
+			if err != nil {
+				return nil, err
+			}
+			t, err := alert.ParseBodyTemplate(string(b))
+			if err != nil {
+				return nil, err
+			}
+			w.Template = t
+		}
+		sinks = append(sinks, w)
+	}
+	if a.ChatWebhook != "" {
+		sinks = append(sinks, &alert.ChatSink{URL: a.ChatWebhook})
+	}
+	if a.Command != "" {
+		sinks = append(sinks, &alert.CommandSink{Argv: strings.Fields(a.Command)})
+	}
+	if len(sinks) == 0 {
+		return nil, nil
+	}
+
+	switch a.MinSeverity {
+	case "info", "warning", "critical":
+	default:
+		return nil, errors.New("invalid --alert-min-severity: " + a.MinSeverity)
+	}
+
+	dead := a.DeadLetter
+	if dead == "" {
+		dead = filepath.Join(exports, "alerts_dead_letter.jsonl")
+	}
+	return &alert.Dispatcher{
+		Sinks:       sinks,
+		MinSeverity: a.MinSeverity,
+		Retries:     a.Retries,
+		Backoff:     time.Second,
+		DeadLetter:  dead,
+	}, nil
+}
+
+func monitorAlert(ev monitor.Event) alert.Alert {
+	// Snapshots make payloads large; the changes carry what a receiver needs.
+	ev.Previous = nil
+	ev.Current = nil
+	return alert.Alert{
+		AtUTC:    ev.AtUTC,
+		Source:   "monitor",
+		Kind:     string(ev.Kind),
+		Severity: string(ev.Severity),
+		Message:  ev.Message,
+		Details:  ev,
+	}
+}
+
+func verdictAlert(rep report.RunReport) alert.Alert {
+	sev := "info"
+	switch rep.Verdict.Overall {
+	case "FAIL":
+		sev = "critical"
+	case "INCONCLUSIVE":
+		sev = "warning"
+	}
+	msg := "Verdict: " + rep.Verdict.Overall
+	if rep.Verdict.Reason != "" {
+		msg += " - " + rep.Verdict.Reason
+	}
+	return alert.Alert{
+		AtUTC:    time.Now().UTC(),
+		Source:   "test",
+		Kind:     "verdict",
+		Severity: sev,
+		Message:  msg,
+		Details: map[string]any{
+			"run_id":  rep.RunID,
+			"mode":    rep.Mode,
+			"verdict": rep.Verdict,
+		},
+	}
+}
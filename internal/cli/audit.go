@@ -0,0 +1,59 @@
+// File: internal/cli/audit.go (complete file)
+
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/baptistax/vpn-leak-identifier/internal/app"
+	"github.com/baptistax/vpn-leak-identifier/internal/report"
+)
+
+// runAudit prints the host configuration audit. Exit codes follow test: 0
+// when nothing critical was found, exitVerdictFail otherwise.
+func runAudit(args []string) int {
+	fs := flag.NewFlagSet("audit", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	var format, root, names string
+	fs.StringVar(&format, "format", "text", "Output format: json|text")
+	fs.StringVar(&root, "root", "/", "Read /proc, /sys, /etc and /run below this directory")
+	fs.StringVar(&names, "name", "", "Comma-separated names to predict the systemd-resolved link for")
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if runtime.GOOS != "linux" {
+		fmt.Fprintln(os.Stderr, "audit is only supported on Linux")
+		return 1
+	}
+
+	var predict []string
+	for _, n := range strings.Split(names, ",") {
+		if n = strings.TrimSpace(n); n != "" {
+			predict = append(predict, n)
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	h := app.AuditHost(ctx, root, predict...)
+	if strings.ToLower(format) == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(h)
+	} else {
+		fmt.Print(strings.TrimPrefix(report.RenderHostConfigText(h), "\n"))
+	}
+	if len(h.Critical()) > 0 {
+		return exitVerdictFail
+	}
+	return 0
+}
@@ -8,6 +8,8 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"log/slog"
+	"net"
 	"os"
 	"os/signal"
 	"path/filepath"
@@ -16,10 +18,13 @@ import (
 	"time"
 
 	"github.com/baptistax/vpn-leak-identifier/internal/app"
+	"github.com/baptistax/vpn-leak-identifier/internal/geo"
+	"github.com/baptistax/vpn-leak-identifier/internal/history"
 	"github.com/baptistax/vpn-leak-identifier/internal/logging"
 	"github.com/baptistax/vpn-leak-identifier/internal/monitor"
 	"github.com/baptistax/vpn-leak-identifier/internal/report"
 	"github.com/baptistax/vpn-leak-identifier/internal/runctx"
+	"github.com/baptistax/vpn-leak-identifier/internal/sdnotify"
 	"github.com/baptistax/vpn-leak-identifier/internal/version"
 )
 
@@ -38,6 +43,22 @@ func Run(args []string) int {
 		return runSnapshot(args[1:])
 	case "monitor":
 		return runMonitor(args[1:])
+	case "exporter":
+		return runExporter(args[1:])
+	case "nm":
+		return runNM(args[1:])
+	case "audit":
+		return runAudit(args[1:])
+	case "history":
+		return runHistory(args[1:])
+	case "diff":
+		return runDiff(args[1:])
+	case "report":
+		return runReport(args[1:])
+	case "schema":
+		return runSchema(args[1:])
+	case "install-service":
+		return runInstallService(args[1:])
 	case "version":
 		fmt.Printf("vpnleakidentifier %s (commit=%s build_date=%s)\n", version.Version, version.Commit, version.BuildDate)
 		return 0
@@ -52,12 +73,20 @@ func Run(args []string) int {
 }
 
 func printHelp() {
-	fmt.Println(`vpnleakidentifier
+	fmt.Print(`vpnleakidentifier
 
 Usage:
   vpnleakidentifier [test] [flags]
   vpnleakidentifier snapshot [flags]
   vpnleakidentifier monitor  [flags]
+  vpnleakidentifier exporter [flags]
+  vpnleakidentifier nm list|up|down [name] [flags]
+  vpnleakidentifier audit [--format text|json] [--name host,...]
+  vpnleakidentifier history [flags]
+  vpnleakidentifier diff <a> <b> [flags]
+  vpnleakidentifier report render <file> [flags]
+  vpnleakidentifier schema run|snapshot [--output dir]
+  vpnleakidentifier install-service [flags] [-- monitor flags]
   vpnleakidentifier version
 
 Default command:
@@ -67,32 +96,64 @@ Commands:
   test      Run a timed VPN + kill-switch validation (default)
   snapshot  Run one leak snapshot and write outputs to ./exports/run_<id>/
   monitor   Re-run snapshot every interval and print an event when changes occur
+  exporter  Run the monitor loop and serve Prometheus metrics on /metrics
+  nm        List, activate or deactivate NetworkManager VPN/WireGuard connections
+  audit     Check sysctls, resolv.conf, nsswitch.conf and systemd-resolved for DNS/IPv6 leaks (Linux)
+  history   List past runs, snapshots and monitor sessions with verdict and exit
+  diff      Compare two run.json/snapshot.json files (or run IDs) field by field
+  report    Render a saved run.json/snapshot.json as html, md, text, junit or tap
+  schema    Print the JSON Schema of run.json or snapshot.json (schema_version 3)
+  install-service  Generate a systemd unit (Type=notify, watchdog, reload) for monitor
+
+Exit codes (test, audit):
+  0  PASS or OK
+  1  runtime error
+  2  invalid arguments
+  3  FAIL (leak detected; critical audit finding)
+  4  INCONCLUSIVE or NOT TESTED
 
 Examples:
   vpnleakidentifier
   vpnleakidentifier test
   vpnleakidentifier test -nks
+  vpnleakidentifier test --nm-cycle "Work VPN" --nm-down 10s
+  vpnleakidentifier test --format tap --junit results/vli.xml
   vpnleakidentifier snapshot --format text
+  vpnleakidentifier snapshot --geo-providers ident.me,ipinfo.io,ifconfig.co --geo-quorum 3
   vpnleakidentifier monitor --interval 5s --format text
+  vpnleakidentifier history --kind test
+  vpnleakidentifier audit --format json
+  vpnleakidentifier audit --name intranet.example.com,example.org
+  vpnleakidentifier diff 20250101_120000 20250102_120000
+  vpnleakidentifier report render exports/run_20250101_120000/run.json --format html --output report.html
+  vpnleakidentifier install-service --config /etc/vpnleakidentifier/monitor.conf --output auto
+  vpnleakidentifier exporter --listen :9725 --known-isp 203.0.113.0/24
 `)
 }
 
 type commonFlags struct {
-	LogLevel string
-	Format   string // json|text
-	Exports  string
-	Timeout  time.Duration
+	LogLevel  string
+	LogOutput string
+	Format    string // json|text
+	Exports   string
+	Timeout   time.Duration
 
 	EnableDNSLeakTest bool
 	EnableSTUN        bool
 	DNSQueries        int
 	STUNServers       string
+	GeoProviders      string
+	GeoQuorum         int
+	GeoIPDB           string
+	Cymru             bool
+	VPNDNS            string
 }
 
 func bindCommon(fs *flag.FlagSet) *commonFlags {
 	c := &commonFlags{}
 
 	fs.StringVar(&c.LogLevel, "log-level", "info", "Log level: debug|info|warn|error")
+	fs.StringVar(&c.LogOutput, "log-output", "stderr", "Log destination: stderr|journald|syslog|unix:///path|udp://host:port|tcp://host:port")
 	fs.StringVar(&c.Format, "format", "text", "Output format: json|text")
 	fs.StringVar(&c.Exports, "exports", defaultExportsDir, "Base exports directory")
 	fs.DurationVar(&c.Timeout, "timeout", 60*time.Second, "Overall CLI timeout")
@@ -102,10 +163,92 @@ func bindCommon(fs *flag.FlagSet) *commonFlags {
 	fs.BoolVar(&c.EnableSTUN, "stun", true, "Enable STUN observed IP checks")
 	fs.IntVar(&c.DNSQueries, "dns-queries", 6, "DNS queries for dnsleaktest.com flow")
 	fs.StringVar(&c.STUNServers, "stun-servers", "", "Comma-separated STUN servers (host:port)")
+	fs.StringVar(&c.GeoProviders, "geo-providers", "", "Comma-separated exit IP/geo providers (default: ident.me,ipinfo.io,ip-api.com,ifconfig.co)")
+	fs.IntVar(&c.GeoQuorum, "geo-quorum", geo.DefaultQuorum, "Geo providers that must agree on an exit IP")
+	fs.StringVar(&c.GeoIPDB, "geoip-db", "", "Comma-separated MMDB files for offline geo/ASN of every observed IP (default: *.mmdb in /var/lib/GeoIP, /usr/share/GeoIP; none disables)")
+	fs.StringVar(&c.VPNDNS, "vpn-dns", "", "Comma-separated resolver IPs, CIDRs or ASNs (AS64500) of your VPN provider; other recursors are classified as public, ISP (--known-isp) or third-party")
+	fs.BoolVar(&c.Cymru, "cymru", false, "Annotate observed IPs with origin ASN, prefix and registry via Team Cymru DNS TXT lookups")
 
 	return c
 }
 
+// geoIP opens the --geoip-db files. Databases found in the default
+// directories are optional: a broken one is logged and skipped.
+func (c *commonFlags) geoIP() (*geo.Offline, error) {
+	if strings.EqualFold(c.GeoIPDB, "none") {
+		return nil, nil
+	}
+	if paths := splitCSV(c.GeoIPDB); len(paths) > 0 {
+		db, err := geo.OpenOffline(paths)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --geoip-db: %w", err)
+		}
+		return db, nil
+	}
+	found := geo.FindMMDB()
+	if len(found) == 0 {
+		return nil, nil
+	}
+	db, err := geo.OpenOffline(found)
+	if err != nil {
+		slog.Warn("ignoring GeoIP databases", "err", err)
+		return nil, nil
+	}
+	return db, nil
+}
+
+// bindKnownISP registers --known-isp for the one-shot commands; monitor and
+// exporter get it from bindMonitor.
+func bindKnownISP(fs *flag.FlagSet) *string {
+	return fs.String("known-isp", "", "Comma-separated IPs/CIDRs of your real ISP; DNS recursors inside them are a DNS leak")
+}
+
+func parseKnownISP(v string) ([]*net.IPNet, error) {
+	n, err := monitor.ParseNetworks(splitCSV(v))
+	if err != nil {
+		return nil, fmt.Errorf("invalid --known-isp: %w", err)
+	}
+	return n, nil
+}
+
+// dnsProfile parses --vpn-dns; isp adds the user's own networks.
+func (c *commonFlags) dnsProfile(isp []*net.IPNet) (report.DNSProfile, error) {
+	p := report.DNSProfile{ISPNetworks: isp}
+	var nets []string
+	for _, v := range splitCSV(c.VPNDNS) {
+		if strings.HasPrefix(strings.ToUpper(v), "AS") {
+			p.VPNASNs = append(p.VPNASNs, strings.ToUpper(v))
+			continue
+		}
+		nets = append(nets, v)
+	}
+	n, err := monitor.ParseNetworks(nets)
+	if err != nil {
+		return report.DNSProfile{}, fmt.Errorf("invalid --vpn-dns: %w", err)
+	}
+	p.VPNNetworks = n
+	return p, nil
+}
+
+// cymru returns the origin resolver when --cymru is set.
+func (c *commonFlags) cymru() *geo.Cymru {
+	if !c.Cymru {
+		return nil
+	}
+	return geo.NewCymru(nil)
+}
+
+func (c *commonFlags) geoProviders() ([]geo.Provider, error) {
+	if c.GeoQuorum < 1 {
+		return nil, fmt.Errorf("invalid --geo-quorum %d", c.GeoQuorum)
+	}
+	providers, err := geo.Providers(splitCSV(c.GeoProviders))
+	if err != nil {
+		return nil, fmt.Errorf("invalid --geo-providers: %w", err)
+	}
+	return providers, nil
+}
+
 func runTest(args []string) int {
 	fs := flag.NewFlagSet("test", flag.ContinueOnError)
 	fs.SetOutput(io.Discard)
@@ -114,12 +257,57 @@ func runTest(args []string) int {
 
 	var nks bool
 	fs.BoolVar(&nks, "nks", false, "No kill-switch validation (5s VPN test only)")
+	knownISP := bindKnownISP(fs)
+
+	var nmWatch bool
+	var nmCycle string
+	var nmDown time.Duration
+	fs.BoolVar(&nmWatch, "nm-watch", true, "Record NetworkManager VPN/device state changes on the probe timeline")
+	fs.StringVar(&nmCycle, "nm-cycle", "", "NetworkManager connection (id or UUID) to deactivate after the baseline and reactivate later")
+	fs.DurationVar(&nmDown, "nm-down", 10*time.Second, "How long --nm-cycle keeps the connection down")
+
+	fs.Lookup("format").Usage = "Output format: json|text|junit|tap"
+	var junitPath, tapPath string
+	fs.StringVar(&junitPath, "junit", "", "Also write the checks as JUnit XML to this file")
+	fs.StringVar(&tapPath, "tap", "", "Also write the checks as TAP to this file")
+
+	af := bindAlerts(fs)
 
 	if err := fs.Parse(args); err != nil {
 		return 2
 	}
 
-	logging.Setup(c.LogLevel)
+	if err := logging.SetupOutput(c.LogLevel, c.LogOutput); err != nil {
+		fmt.Fprintln(os.Stderr, "invalid --log-output:", err)
+		return 2
+	}
+
+	alerts, err := af.dispatcher(c.Exports)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "invalid alert configuration:", err)
+		return 2
+	}
+
+	providers, err := c.geoProviders()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	geoIP, err := c.geoIP()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	knownNets, err := parseKnownISP(*knownISP)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	dnsProfile, err := c.dnsProfile(knownNets)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
 
 	rc, err := runctx.New(c.Exports)
 	if err != nil {
@@ -145,6 +333,16 @@ func runTest(args []string) int {
 		Interval:    1 * time.Second,
 		EnableSTUN:  c.EnableSTUN,
 		StunServers: splitCSV(c.STUNServers),
+
+		GeoProviders: providers,
+		GeoQuorum:    c.GeoQuorum,
+		GeoIP:        geoIP,
+		Cymru:        c.cymru(),
+		DNSProfile:   dnsProfile,
+
+		WatchNetworkManager: nmWatch,
+		NMCycle:             nmCycle,
+		NMDownFor:           nmDown,
 	}
 	if nks {
 		opt.Mode = report.RunModeVPNOnly
@@ -160,17 +358,51 @@ func runTest(args []string) int {
 
 	_ = report.WriteRunJSON(outJSON, rep)
 	_ = report.WriteRunText(outTXT, rep)
+	_ = report.WriteRunHTML(filepath.Join(rc.OutputDir, "run.html"), rep)
+	_ = history.Record(c.Exports, history.FromRun(rc.OutputDir, rep))
+
+	alerts.Notify(verdictAlert(rep))
+	alerts.Close(30 * time.Second)
+
+	code := verdictExitCode(rep.Verdict)
+	checks := report.RunChecks(rep)
+	if junitPath != "" {
+		out, err := renderJUnitRun(rep, checks)
+		if err == nil {
+			err = writeReportFile(junitPath, out)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "failed to write JUnit report:", err)
+		}
+	}
+	if tapPath != "" {
+		if err := writeReportFile(tapPath, report.RenderTAP(checks)); err != nil {
+			fmt.Fprintln(os.Stderr, "failed to write TAP report:", err)
+		}
+	}
 
-	if strings.ToLower(c.Format) == "json" {
+	switch strings.ToLower(c.Format) {
+	case "json":
 		enc := json.NewEncoder(os.Stdout)
 		enc.SetIndent("", "  ")
 		_ = enc.Encode(rep)
-		return 0
+		return code
+	case "junit":
+		out, err := renderJUnitRun(rep, checks)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "failed to render JUnit report:", err)
+			return 1
+		}
+		fmt.Print(out)
+		return code
+	case "tap":
+		fmt.Print(report.RenderTAP(checks))
+		return code
 	}
 
 	fmt.Print(report.RenderRunText(rep))
 	fmt.Printf("\nOutputs written to: %s\n", rc.OutputDir)
-	return 0
+	return code
 }
 
 func runSnapshot(args []string) int {
@@ -178,12 +410,37 @@ func runSnapshot(args []string) int {
 	fs.SetOutput(io.Discard)
 
 	c := bindCommon(fs)
+	knownISP := bindKnownISP(fs)
 
 	if err := fs.Parse(args); err != nil {
 		return 2
 	}
 
-	logging.Setup(c.LogLevel)
+	if err := logging.SetupOutput(c.LogLevel, c.LogOutput); err != nil {
+		fmt.Fprintln(os.Stderr, "invalid --log-output:", err)
+		return 2
+	}
+
+	providers, err := c.geoProviders()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	geoIP, err := c.geoIP()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	knownNets, err := parseKnownISP(*knownISP)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	dnsProfile, err := c.dnsProfile(knownNets)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
 
 	rc, err := runctx.New(c.Exports)
 	if err != nil {
@@ -199,6 +456,11 @@ func runSnapshot(args []string) int {
 		EnableSTUN:        c.EnableSTUN,
 		DNSQueries:        c.DNSQueries,
 		StunServers:       splitCSV(c.STUNServers),
+		GeoProviders:      providers,
+		GeoQuorum:         c.GeoQuorum,
+		GeoIP:             geoIP,
+		Cymru:             c.cymru(),
+		DNSProfile:        dnsProfile,
 	}
 
 	s := app.TakeSnapshot(ctx, opt)
@@ -208,6 +470,7 @@ func runSnapshot(args []string) int {
 
 	_ = report.WriteJSON(outJSON, s)
 	_ = report.WriteText(outTXT, s)
+	_ = history.Record(c.Exports, history.FromSnapshot(rc.OutputDir, s))
 
 	if strings.ToLower(c.Format) == "json" {
 		enc := json.NewEncoder(os.Stdout)
@@ -222,43 +485,104 @@ func runSnapshot(args []string) int {
 }
 
 func runMonitor(args []string) int {
-	fs := flag.NewFlagSet("monitor", flag.ContinueOnError)
-	fs.SetOutput(io.Discard)
+	var c *commonFlags
+	var mf *monitorFlags
+	var af *alertFlags
+	fs, err := parseWithConfig("monitor", args, func(fs *flag.FlagSet) *string {
+		c = bindCommon(fs)
+		mf = bindMonitor(fs)
+		af = bindAlerts(fs)
+		return &mf.Config
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
 
-	c := bindCommon(fs)
+	if err := logging.SetupOutput(c.LogLevel, c.LogOutput); err != nil {
+		fmt.Fprintln(os.Stderr, "invalid --log-output:", err)
+		return 2
+	}
 
-	var interval time.Duration
-	fs.DurationVar(&interval, "interval", 5*time.Second, "Snapshot interval (e.g. 5s)")
+	alerts, err := af.dispatcher(c.Exports)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "invalid alert configuration:", err)
+		return 2
+	}
 
-	if err := fs.Parse(args); err != nil {
+	opt, err := mf.options(c)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
 		return 2
 	}
 
-	logging.Setup(c.LogLevel)
+	rc, err := runctx.New(c.Exports)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to create run directory:", err)
+		return 1
+	}
+	statePath := ""
+	if mf.Resume {
+		statePath = filepath.Join(c.Exports, "monitor_state.json")
+	}
+	journal, err := monitor.OpenJournal(filepath.Join(rc.OutputDir, "monitor.jsonl"), statePath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to open monitor log:", err)
+		return 1
+	}
+	defer journal.Close()
 
-	ctx, cancel := context.WithTimeout(context.Background(), c.Timeout)
+	// Monitoring runs until stopped; --timeout only applies when given.
+	ctx, cancel := context.WithCancel(context.Background())
+	if flagSet(fs, "timeout") {
+		ctx, cancel = context.WithTimeout(context.Background(), c.Timeout)
+	}
 	defer cancel()
 
+	sd := newSystemdNotifier()
+	reload := make(chan monitor.Options)
+
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
 	go func() {
-		<-stop
-		cancel()
+		for {
+			select {
+			case <-stop:
+				cancel()
+				return
+			case <-hup:
+				sd.notify(sdnotify.Reloading)
+				if n, err := reloadMonitorOptions(args); err != nil {
+					slog.Error("reload failed; keeping current configuration", "err", err)
+				} else {
+					sd.setStall(n)
+					select {
+					case reload <- n:
+					case <-ctx.Done():
+						return
+					}
+				}
+				sd.notify(sdnotify.Ready)
+			}
+		}
 	}()
 
-	opt := monitor.Options{
-		Interval: interval,
-		Timeout:  20 * time.Second,
-		Snapshot: app.SnapshotOptions{
-			EnableDNSLeakTest: c.EnableDNSLeakTest,
-			EnableSTUN:        c.EnableSTUN,
-			DNSQueries:        c.DNSQueries,
-			StunServers:       splitCSV(c.STUNServers),
-		},
-	}
+	opt.Journal = journal
+	opt.Reload = reload
+	opt.OnStatus = sd.status
+	sd.setStall(opt)
+	sd.watchdog(ctx)
+	sd.notify(sdnotify.Ready, sdnotify.Status("waiting for first snapshot"))
 
 	format := strings.ToLower(c.Format)
-	monitor.Run(ctx, opt, func(ev monitor.Event) {
+	summary := monitor.Run(ctx, opt, func(ev monitor.Event) {
+		alerts.Notify(monitorAlert(ev))
+		if c.LogOutput != "stderr" {
+			logEvent(ev)
+		}
+
 		if format == "json" {
 			enc := json.NewEncoder(os.Stdout)
 			enc.SetIndent("", "  ")
@@ -266,16 +590,133 @@ func runMonitor(args []string) int {
 			return
 		}
 
-		fmt.Printf("[%s] %s\n", ev.AtUTC.Format("2006-01-02T15:04:05Z"), ev.Message)
-		if ev.Previous != nil && ev.Current != nil {
+		fmt.Printf("[%s] %s %s: %s\n", ev.AtUTC.Format("2006-01-02T15:04:05Z"), strings.ToUpper(string(ev.Severity)), ev.Kind, ev.Message)
+		for _, t := range ev.Triggers {
+			fmt.Printf("  Trigger: %s\n", t)
+		}
+		if ev.Timing.SkippedTicks > 0 {
+			fmt.Printf("  Snapshot took %dms (%d ticks skipped)\n", ev.Timing.DurationMS, ev.Timing.SkippedTicks)
+		}
+		if ev.Count > 0 {
+			fmt.Printf("  Changes: %d\n", ev.Count)
+		}
+		for _, ch := range ev.Changes {
+			fmt.Printf("  %s: %s -> %s\n", ch.Field, printableIP(ch.From), printableIP(ch.To))
+		}
+		if ev.Kind == monitor.KindChanged && ev.Previous != nil && ev.Current != nil {
 			printSnapshotDeltaText(*ev.Previous, *ev.Current)
 		}
 		fmt.Println()
 	})
 
+	_ = writeJSONFile(filepath.Join(rc.OutputDir, "summary.json"), summary)
+	_ = os.WriteFile(filepath.Join(rc.OutputDir, "summary.txt"), []byte(monitor.RenderSummaryText(summary)), 0o644)
+	_ = history.Record(c.Exports, history.FromSummary(rc.OutputDir, summary))
+	sd.notify(sdnotify.Stopping)
+	alerts.Close(30 * time.Second)
+
+	if format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(summary)
+		return 0
+	}
+	fmt.Print(monitor.RenderSummaryText(summary))
+	fmt.Printf("Outputs written to: %s\n", rc.OutputDir)
 	return 0
 }
 
+// reloadMonitorOptions re-reads the command line and --config for SIGHUP.
+func reloadMonitorOptions(args []string) (monitor.Options, error) {
+	var c *commonFlags
+	var mf *monitorFlags
+	if _, err := parseWithConfig("monitor", args, func(fs *flag.FlagSet) *string {
+		c = bindCommon(fs)
+		mf = bindMonitor(fs)
+		bindAlerts(fs)
+		return &mf.Config
+	}); err != nil {
+		return monitor.Options{}, err
+	}
+	return mf.options(c)
+}
+
+type monitorFlags struct {
+	Interval      time.Duration
+	FullInterval  time.Duration
+	WatchKernel   bool
+	KnownISP      string
+	Confirm       int
+	FlapThreshold int
+	FlapWindow    time.Duration
+	Resume        bool
+	Config        string
+}
+
+// bindMonitor registers the monitor loop flags shared by monitor and exporter.
+func bindMonitor(fs *flag.FlagSet) *monitorFlags {
+	m := &monitorFlags{}
+	fs.DurationVar(&m.Interval, "interval", 5*time.Second, "Snapshot interval (e.g. 5s)")
+	fs.DurationVar(&m.FullInterval, "full-interval", 30*time.Second, "Interval for expensive probes (STUN, dnsleaktest); 0 runs them every snapshot")
+	fs.BoolVar(&m.WatchKernel, "netlink", true, "Also snapshot immediately on kernel route/link/address changes (Linux)")
+	fs.StringVar(&m.KnownISP, "known-isp", "", "Comma-separated IPs/CIDRs of your real ISP; any match is reported as leak_to_known_isp, and DNS recursors inside them as a DNS leak")
+	fs.IntVar(&m.Confirm, "confirm", 2, "Consecutive snapshots that must agree before a change is reported (leaks are reported at once)")
+	fs.IntVar(&m.FlapThreshold, "flap-threshold", 5, "Changes within --flap-window that mark the state as unstable (0 disables)")
+	fs.DurationVar(&m.FlapWindow, "flap-window", 2*time.Minute, "Window for flap detection")
+	fs.BoolVar(&m.Resume, "resume", true, "Resume change detection from the last saved monitor state")
+	fs.StringVar(&m.Config, "config", "", "File with one flag per line (e.g. interval = 10s); re-read on SIGHUP by monitor")
+	return m
+}
+
+func (m *monitorFlags) options(c *commonFlags) (monitor.Options, error) {
+	knownNets, err := parseKnownISP(m.KnownISP)
+	if err != nil {
+		return monitor.Options{}, err
+	}
+	providers, err := c.geoProviders()
+	if err != nil {
+		return monitor.Options{}, err
+	}
+	geoIP, err := c.geoIP()
+	if err != nil {
+		return monitor.Options{}, err
+	}
+	dnsProfile, err := c.dnsProfile(knownNets)
+	if err != nil {
+		return monitor.Options{}, err
+	}
+	return monitor.Options{
+		Interval:     m.Interval,
+		FullInterval: m.FullInterval,
+		Timeout:      20 * time.Second,
+		Snapshot: app.SnapshotOptions{
+			EnableDNSLeakTest: c.EnableDNSLeakTest,
+			EnableSTUN:        c.EnableSTUN,
+			DNSQueries:        c.DNSQueries,
+			StunServers:       splitCSV(c.STUNServers),
+			GeoProviders:      providers,
+			GeoQuorum:         c.GeoQuorum,
+			GeoIP:             geoIP,
+			Cymru:             c.cymru(),
+			DNSProfile:        dnsProfile,
+		},
+		WatchKernel: m.WatchKernel,
+		KnownISP:    knownNets,
+
+		Confirmations: m.Confirm,
+		FlapThreshold: m.FlapThreshold,
+		FlapWindow:    m.FlapWindow,
+	}, nil
+}
+
+func writeJSONFile(path string, v any) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o644)
+}
+
 func printSnapshotDeltaText(prev, cur report.Snapshot) {
 	prevV4 := findPublicIP(prev.PublicIPs, "ipv4")
 	curV4 := findPublicIP(cur.PublicIPs, "ipv4")
@@ -297,8 +738,11 @@ func printSnapshotDeltaText(prev, cur report.Snapshot) {
 		}
 	}
 
-	if !equalStringSets(prev.DnsRecursors, cur.DnsRecursors) {
-		fmt.Printf("  DNS recursors: %s -> %s\n", strings.Join(prev.DnsRecursors, ", "), strings.Join(cur.DnsRecursors, ", "))
+	if !equalStringSets(prev.DNSRecursors, cur.DNSRecursors) {
+		fmt.Printf("  DNS recursors: %s -> %s\n", strings.Join(prev.DNSRecursors, ", "), strings.Join(cur.DNSRecursors, ", "))
+	}
+	for _, d := range report.DiffRecursors(prev.Observation, cur.Observation) {
+		fmt.Printf("  DNS recursors [%s]: %s -> %s\n", d.Label(), strings.Join(d.From, ", "), strings.Join(d.To, ", "))
 	}
 
 	if !equalStringSets(prev.StunObserved, cur.StunObserved) {
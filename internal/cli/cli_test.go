@@ -0,0 +1,86 @@
+// File: internal/cli/cli_test.go (complete file)
+
+package cli
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadConfigArgs(t *testing.T) {
+	cases := []struct {
+		name string
+		in   string
+		want []string
+	}{
+		{"equals", "interval = 10s\n", []string{"--interval=10s"}},
+		{"dashes and space", "--known-isp 203.0.113.0/24\n", []string{"--known-isp=203.0.113.0/24"}},
+		{"bool without value", "netlink=false\nresume\n", []string{"--netlink=false", "--resume"}},
+		{"comments and blanks", "# comment\n\n  confirm=3  \n", []string{"--confirm=3"}},
+		{"quoted value", `alert-cmd = "notify-send 'leak'"` + "\n", []string{"--alert-cmd=notify-send 'leak'"}},
+		{"single quotes", "known-isp = '203.0.113.0/24'\n", []string{"--known-isp=203.0.113.0/24"}},
+		{"empty file", "", nil},
+	}
+	dir := t.TempDir()
+	for i, c := range cases {
+		path := filepath.Join(dir, "cfg"+string(rune('a'+i)))
+		if err := os.WriteFile(path, []byte(c.in), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		got, err := readConfigArgs(path)
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: got %q, want %q", c.name, got, c.want)
+		}
+	}
+
+	if _, err := readConfigArgs(filepath.Join(dir, "missing")); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
+
+func TestParseWithConfig_CommandLineWins(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "monitor.conf")
+	if err := os.WriteFile(path, []byte("interval = 10s\nlisten = :9999\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var mf *monitorFlags
+	var listen string
+	_, err := parseWithConfig("exporter", []string{"--config", path, "--listen", ":9100"}, func(fs *flag.FlagSet) *string {
+		bindCommon(fs)
+		mf = bindMonitor(fs)
+		fs.StringVar(&listen, "listen", ":9725", "")
+		return &mf.Config
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mf.Interval.String() != "10s" || listen != ":9100" {
+		t.Fatalf("interval = %s, listen = %s", mf.Interval, listen)
+	}
+}
+
+func TestSystemdQuote(t *testing.T) {
+	cases := []struct {
+		name string
+		in   []string
+		want string
+	}{
+		{"plain", []string{"/usr/bin/vli", "monitor", "--interval=5s"}, "/usr/bin/vli monitor --interval=5s"},
+		{"space", []string{"vli", "--alert-cmd=notify-send leak"}, `vli "--alert-cmd=notify-send leak"`},
+		{"empty word", []string{"vli", ""}, `vli ""`},
+		{"quotes and backslash", []string{`a"b`, `c\d`}, `"a\"b" "c\\d"`},
+		{"specifiers", []string{"--out=%h/$HOME"}, "--out=%%h/$$HOME"},
+	}
+	for _, c := range cases {
+		if got := systemdQuote(c.in); got != c.want {
+			t.Errorf("%s: got %s, want %s", c.name, got, c.want)
+		}
+	}
+}
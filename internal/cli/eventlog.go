@@ -0,0 +1,64 @@
+// File: internal/cli/eventlog.go (complete file)
+
+package cli
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"github.com/baptistax/vpn-leak-identifier/internal/logging"
+	"github.com/baptistax/vpn-leak-identifier/internal/monitor"
+)
+
+// logEvent writes a monitor event to the structured log. With journald the
+// keys become EVENT_KIND, SEVERITY, FAMILY and EXIT_IP; with syslog they are
+// SD-PARAMs and the kind is the MSGID.
+func logEvent(ev monitor.Event) {
+	level := slog.LevelInfo
+	switch ev.Severity {
+	case monitor.SeverityCritical:
+		level = logging.LevelCritical
+	case monitor.SeverityWarning:
+		level = slog.LevelWarn
+	}
+
+	family, exitIP := eventExit(ev)
+	attrs := []any{
+		"event_kind", string(ev.Kind),
+		"severity", string(ev.Severity),
+	}
+	if family != "" {
+		attrs = append(attrs, "family", family)
+	}
+	if exitIP != "" {
+		attrs = append(attrs, "exit_ip", exitIP)
+	}
+	if len(ev.Changes) > 0 {
+		parts := make([]string, 0, len(ev.Changes))
+		for _, ch := range ev.Changes {
+			parts = append(parts, ch.Field+": "+printableIP(ch.From)+" -> "+printableIP(ch.To))
+		}
+		attrs = append(attrs, "changes", strings.Join(parts, "; "))
+	}
+	slog.Log(context.Background(), level, ev.Message, attrs...)
+}
+
+// eventExit picks the family and exit IP an event is about: the first changed
+// exit field, else the current IPv4 (or any-family) exit.
+func eventExit(ev monitor.Event) (family, ip string) {
+	for _, ch := range ev.Changes {
+		if f, ok := strings.CutPrefix(ch.Field, "exit."); ok {
+			return f, ch.To
+		}
+	}
+	if ev.Current == nil {
+		return "", ""
+	}
+	for _, f := range []string{"ipv4", "any", "ipv6"} {
+		if ip := findPublicIP(ev.Current.PublicIPs, f); ip != "" {
+			return f, ip
+		}
+	}
+	return "", ""
+}
@@ -0,0 +1,124 @@
+// File: internal/cli/exporter.go (complete file)
+
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/baptistax/vpn-leak-identifier/internal/exporter"
+	"github.com/baptistax/vpn-leak-identifier/internal/logging"
+	"github.com/baptistax/vpn-leak-identifier/internal/monitor"
+	"github.com/baptistax/vpn-leak-identifier/internal/report"
+)
+
+func runExporter(args []string) int {
+	var c *commonFlags
+	var mf *monitorFlags
+	var af *alertFlags
+	var listen string
+	fs, err := parseWithConfig("exporter", args, func(fs *flag.FlagSet) *string {
+		c = bindCommon(fs)
+		mf = bindMonitor(fs)
+		af = bindAlerts(fs)
+		fs.StringVar(&listen, "listen", ":9725", "Address to serve /metrics on")
+		return &mf.Config
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	if err := logging.SetupOutput(c.LogLevel, c.LogOutput); err != nil {
+		fmt.Fprintln(os.Stderr, "invalid --log-output:", err)
+		return 2
+	}
+
+	alerts, err := af.dispatcher(c.Exports)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "invalid alert configuration:", err)
+		return 2
+	}
+
+	opt, err := mf.options(c)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	// An exporter runs until stopped; --timeout only applies when given.
+	ctx, cancel := context.WithCancel(context.Background())
+	if flagSet(fs, "timeout") {
+		ctx, cancel = context.WithTimeout(context.Background(), c.Timeout)
+	}
+	defer cancel()
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-stop
+		cancel()
+	}()
+
+	exp := exporter.New(nil, opt.Snapshot.GeoProviders, opt.Snapshot.GeoQuorum)
+	opt.OnSnapshot = func(s report.Snapshot, t monitor.Timing) {
+		exp.ObserveSnapshot(ctx, s, t.Full)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", exp.Handler())
+	srv := &http.Server{Addr: listen, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	srvErr := make(chan error, 1)
+	go func() {
+		srvErr <- srv.ListenAndServe()
+	}()
+	slog.Info("serving metrics", "addr", listen, "path", "/metrics")
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		monitor.Run(ctx, opt, func(ev monitor.Event) {
+			exp.ObserveEvent(ev)
+			alerts.Notify(monitorAlert(ev))
+			logEvent(ev)
+		})
+	}()
+
+	code := 0
+	select {
+	case err := <-srvErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			fmt.Fprintln(os.Stderr, "metrics server failed:", err)
+			code = 1
+		}
+		cancel()
+	case <-ctx.Done():
+	}
+	<-done
+
+	shutCtx, shutCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	_ = srv.Shutdown(shutCtx)
+	shutCancel()
+	alerts.Close(30 * time.Second)
+	return code
+}
+
+// flagSet reports whether name was given explicitly on the command line.
+func flagSet(fs *flag.FlagSet, name string) bool {
+	found := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			found = true
+		}
+	})
+	return found
+}
@@ -0,0 +1,164 @@
+// File: internal/cli/history.go (complete file)
+
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/baptistax/vpn-leak-identifier/internal/history"
+)
+
+func runHistory(args []string) int {
+	fs := flag.NewFlagSet("history", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	var exports, format, kind string
+	var limit int
+	var rebuild bool
+	fs.StringVar(&exports, "exports", defaultExportsDir, "Base exports directory")
+	fs.StringVar(&format, "format", "text", "Output format: json|text")
+	fs.StringVar(&kind, "kind", "", "Only list one kind: test|snapshot|monitor")
+	fs.IntVar(&limit, "limit", 20, "Maximum entries to list (0 lists all)")
+	fs.BoolVar(&rebuild, "rebuild", false, "Rewrite the index from the run directories on disk")
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if rebuild {
+		if _, err := history.Rebuild(exports); err != nil {
+			fmt.Fprintln(os.Stderr, "failed to rebuild index:", err)
+			return 1
+		}
+	}
+	entries, err := history.Load(exports)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to read history:", err)
+		return 1
+	}
+
+	filtered := entries[:0]
+	for _, e := range entries {
+		if kind == "" || e.Kind == kind {
+			filtered = append(filtered, e)
+		}
+	}
+	if limit > 0 && len(filtered) > limit {
+		filtered = filtered[:limit]
+	}
+
+	if strings.ToLower(format) == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(filtered)
+		return 0
+	}
+
+	if len(filtered) == 0 {
+		fmt.Printf("No runs found in %s\n", exports)
+		return 0
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tKIND\tSTARTED (UTC)\tVERDICT\tEXIT IP\tASN\tCOUNTRY")
+	for _, e := range filtered {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			e.ID, e.Kind, e.StartedUTC.Format("2006-01-02T15:04:05Z"),
+			dash(e.Verdict), dash(e.ExitIP), dash(e.ASN), dash(e.Country))
+	}
+	_ = tw.Flush()
+	return 0
+}
+
+func runDiff(args []string) int {
+	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	var exports, format string
+	var all bool
+	fs.StringVar(&exports, "exports", defaultExportsDir, "Base exports directory (for run IDs)")
+	fs.StringVar(&format, "format", "text", "Output format: json|text")
+	fs.BoolVar(&all, "all", false, "Include the per-second probe timeline and probe timings")
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 2 {
+		fmt.Fprintln(os.Stderr, "usage: vpnleakidentifier diff [flags] <a> <b>  (run.json/snapshot.json, run directory or run ID)")
+		return 2
+	}
+
+	a, err := history.Open(exports, fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	b, err := history.Open(exports, fs.Arg(1))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	var skip []string
+	if !all {
+		skip = []string{"probes"}
+	}
+	changes, err := history.Diff(a, b, skip...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	if strings.ToLower(format) == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(struct {
+			A       string                `json:"a"`
+			B       string                `json:"b"`
+			Kind    string                `json:"kind"`
+			Changes []history.FieldChange `json:"changes"`
+		}{a.Path, b.Path, a.Kind, changes})
+		return 0
+	}
+
+	fmt.Printf("--- %s\n+++ %s\n", a.Path, b.Path)
+	if a.Kind == "test" {
+		fmt.Printf("Verdict: %s -> %s\n", a.Run.Verdict.Overall, b.Run.Verdict.Overall)
+		fmt.Println("Baseline:")
+		printSnapshotDeltaText(history.ProbeSetSnapshot(a.Run.Baseline), history.ProbeSetSnapshot(b.Run.Baseline))
+	} else {
+		fmt.Println("Snapshot:")
+		printSnapshotDeltaText(*a.Snapshot, *b.Snapshot)
+	}
+
+	fmt.Printf("\nFields changed: %d\n", len(changes))
+	for _, ch := range changes {
+		fmt.Printf("  %s: %s -> %s\n", ch.Path, diffValue(ch.From), diffValue(ch.To))
+	}
+	return 0
+}
+
+func diffValue(v any) string {
+	if v == nil {
+		return "(none)"
+	}
+	if s, ok := v.(string); ok {
+		if s == "" {
+			return `""`
+		}
+		return s
+	}
+	return fmt.Sprint(v)
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
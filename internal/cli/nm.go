@@ -0,0 +1,86 @@
+// File: internal/cli/nm.go (complete file)
+
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/baptistax/vpn-leak-identifier/internal/nm"
+)
+
+func runNM(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: vpnleakidentifier nm list|up|down [name]")
+		return 2
+	}
+	action := args[0]
+
+	fs := flag.NewFlagSet("nm", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	var format string
+	var timeout time.Duration
+	fs.StringVar(&format, "format", "text", "Output format: json|text")
+	fs.DurationVar(&timeout, "timeout", 30*time.Second, "Overall timeout")
+
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	client, err := nm.Connect()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to connect to NetworkManager:", err)
+		return 1
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	switch action {
+	case "list":
+		conns, err := client.Connections(ctx)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "failed to list connections:", err)
+			return 1
+		}
+		if strings.ToLower(format) == "json" {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			_ = enc.Encode(conns)
+			return 0
+		}
+		for _, c := range conns {
+			fmt.Printf("%-24s %-10s %-12s %s\n", c.ID, c.Type, c.State, c.UUID)
+		}
+		return 0
+
+	case "up", "down":
+		if fs.NArg() != 1 {
+			fmt.Fprintf(os.Stderr, "usage: vpnleakidentifier nm %s <name>\n", action)
+			return 2
+		}
+		name := fs.Arg(0)
+		if action == "up" {
+			_, err = client.Activate(ctx, name)
+		} else {
+			err = client.Deactivate(ctx, name)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "nm %s failed: %v\n", action, err)
+			return 1
+		}
+		return 0
+
+	default:
+		fmt.Fprintf(os.Stderr, "unknown nm action: %s\n", action)
+		return 2
+	}
+}
@@ -0,0 +1,143 @@
+// File: internal/cli/report.go (complete file)
+
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/baptistax/vpn-leak-identifier/internal/report"
+)
+
+func runReport(args []string) int {
+	if len(args) == 0 || args[0] != "render" {
+		fmt.Fprintln(os.Stderr, "usage: vpnleakidentifier report render <run.json|snapshot.json> [--format html|md|text|junit|tap] [--output path]")
+		return 2
+	}
+
+	fs := flag.NewFlagSet("report", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	var format, output string
+	fs.StringVar(&format, "format", "text", "Output format: html|md|text|junit|tap")
+	fs.StringVar(&output, "output", "-", "Write to this file instead of stdout")
+
+	files, err := parseInterleaved(fs, args[1:])
+	if err != nil {
+		return 2
+	}
+	if len(files) != 1 {
+		fmt.Fprintln(os.Stderr, "usage: vpnleakidentifier report render <run.json|snapshot.json> [--format html|md|text|junit|tap] [--output path]")
+		return 2
+	}
+
+	doc, err := report.Load(files[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	for _, w := range doc.Warnings {
+		fmt.Fprintln(os.Stderr, "warning:", w)
+	}
+
+	out, err := renderDocument(doc, strings.ToLower(format))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	if output == "-" {
+		fmt.Print(out)
+		return 0
+	}
+	if err := writeReportFile(output, out); err != nil {
+		fmt.Fprintln(os.Stderr, "failed to write report:", err)
+		return 1
+	}
+	return 0
+}
+
+func writeReportFile(path, out string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(out), 0o644)
+}
+
+func renderJUnitRun(r report.RunReport, checks []report.Check) (string, error) {
+	return report.RenderJUnit("vpnleakidentifier."+string(r.Mode), r.StartedUTC, r.Duration.Duration(), checks)
+}
+
+// Exit codes of the test command. 1 and 2 keep their meaning (runtime error,
+// usage error), so CI can tell a leak apart from a broken run.
+const (
+	exitVerdictFail         = 3
+	exitVerdictInconclusive = 4
+)
+
+// verdictExitCode maps a run verdict to the process exit code: PASS/OK exit
+// 0, FAIL exits 3, and INCONCLUSIVE/NOT TESTED exit 4.
+func verdictExitCode(v report.Verdict) int {
+	switch v.Overall {
+	case "PASS", "OK":
+		return 0
+	case "FAIL":
+		return exitVerdictFail
+	default:
+		return exitVerdictInconclusive
+	}
+}
+
+func renderDocument(doc *report.Document, format string) (string, error) {
+	if doc.Kind == report.KindRun {
+		r := *doc.Run
+		switch format {
+		case "text":
+			return report.RenderRunText(r), nil
+		case "md", "markdown":
+			return report.RenderRunMarkdown(r), nil
+		case "html":
+			return report.RenderRunHTML(r)
+		case "junit":
+			return renderJUnitRun(r, report.RunChecks(r))
+		case "tap":
+			return report.RenderTAP(report.RunChecks(r)), nil
+		}
+	} else {
+		s := *doc.Snapshot
+		switch format {
+		case "text":
+			return report.RenderSnapshotText(s), nil
+		case "md", "markdown":
+			return report.RenderSnapshotMarkdown(s), nil
+		case "html":
+			return report.RenderSnapshotHTML(s)
+		case "junit":
+			return report.RenderJUnit("vpnleakidentifier.snapshot", s.TimestampUTC, 0, report.SnapshotChecks(s))
+		case "tap":
+			return report.RenderTAP(report.SnapshotChecks(s)), nil
+		}
+	}
+	return "", fmt.Errorf("unknown format %q (want html|md|text|junit|tap)", format)
+}
+
+// parseInterleaved parses flags that may appear before or after positional
+// arguments and returns the positionals.
+func parseInterleaved(fs *flag.FlagSet, args []string) ([]string, error) {
+	var pos []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return pos, nil
+		}
+		pos = append(pos, args[0])
+		args = args[1:]
+	}
+}
@@ -0,0 +1,52 @@
+// File: internal/cli/schema.go (complete file)
+
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/baptistax/vpn-leak-identifier/internal/report"
+)
+
+func runSchema(args []string) int {
+	fs := flag.NewFlagSet("schema", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	var outDir string
+	fs.StringVar(&outDir, "output", "", "Write run.schema.json and snapshot.schema.json to this directory")
+
+	kinds, err := parseInterleaved(fs, args)
+	if err != nil {
+		return 2
+	}
+	if len(kinds) > 1 || (len(kinds) == 0 && outDir == "") {
+		fmt.Fprintln(os.Stderr, "usage: vpnleakidentifier schema run|snapshot  |  vpnleakidentifier schema [run|snapshot] --output <dir>")
+		return 2
+	}
+	if len(kinds) == 0 {
+		kinds = []string{string(report.KindRun), string(report.KindSnapshot)}
+	}
+
+	for _, k := range kinds {
+		b, err := report.JSONSchema(report.DocumentKind(k))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		if outDir == "" {
+			fmt.Println(string(b))
+			continue
+		}
+		path := filepath.Join(outDir, k+".schema.json")
+		if err := writeReportFile(path, string(b)+"\n"); err != nil {
+			fmt.Fprintln(os.Stderr, "failed to write schema:", err)
+			return 1
+		}
+		fmt.Println(path)
+	}
+	return 0
+}
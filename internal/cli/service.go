@@ -0,0 +1,277 @@
+// File: internal/cli/service.go (complete file)
+
+package cli
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"text/template"
+	"time"
+
+	"github.com/baptistax/vpn-leak-identifier/internal/monitor"
+	"github.com/baptistax/vpn-leak-identifier/internal/sdnotify"
+)
+
+// parseWithConfig parses args after the flags found in --config, so the
+// command line wins over the file. bind registers every flag of the command
+// on a fresh set and returns where --config is stored; it runs once per
+// parse pass. The final flag set is returned for flagSet checks.
+func parseWithConfig(name string, args []string, bind func(fs *flag.FlagSet) *string) (*flag.FlagSet, error) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	cfg := bind(fs)
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if *cfg == "" {
+		return fs, nil
+	}
+
+	fileArgs, err := readConfigArgs(*cfg)
+	if err != nil {
+		return nil, err
+	}
+	fs = flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	bind(fs)
+	if err := fs.Parse(append(fileArgs, args...)); err != nil {
+		return nil, fmt.Errorf("%s: %w", *cfg, err)
+	}
+	return fs, nil
+}
+
+// readConfigArgs turns a config file into flags. Each non-comment line holds
+// one flag, with or without dashes: "interval = 10s", "--known-isp 203.0.113.0/24"
+// or "netlink=false".
+func readConfigArgs(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var out []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimLeft(line, "-")
+		key, val, ok := strings.Cut(line, "=")
+		if !ok {
+			key, val, ok = strings.Cut(line, " ")
+		}
+		key = strings.TrimSpace(key)
+		if !ok {
+			out = append(out, "--"+key)
+			continue
+		}
+		out = append(out, "--"+key+"="+unquote(strings.TrimSpace(val)))
+	}
+	return out, sc.Err()
+}
+
+// unquote strips one pair of matching surrounding quotes.
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// systemdNotifier reports readiness, status and watchdog pings when run
+// under systemd (Type=notify); every method is a no-op otherwise.
+type systemdNotifier struct {
+	enabled  bool
+	progress atomic.Int64 // unix nanos of the last completed snapshot
+	stall    atomic.Int64 // see watchdog
+}
+
+func newSystemdNotifier() *systemdNotifier {
+	n := &systemdNotifier{enabled: sdnotify.Enabled()}
+	n.progress.Store(time.Now().UnixNano())
+	return n
+}
+
+func (n *systemdNotifier) notify(states ...string) {
+	if !n.enabled {
+		return
+	}
+	if err := sdnotify.Notify(states...); err != nil {
+		slog.Debug("sd_notify failed", "err", err)
+	}
+}
+
+func (n *systemdNotifier) status(st monitor.Status) {
+	n.progress.Store(time.Now().UnixNano())
+	n.notify(sdnotify.Status(st.String()))
+}
+
+// setStall sets how long the loop may go without a snapshot, derived from
+// the (possibly reloaded) interval and per-snapshot timeout.
+func (n *systemdNotifier) setStall(opt monitor.Options) {
+	n.stall.Store(int64(opt.Interval + opt.Timeout + sdnotify.WatchdogInterval()))
+}
+
+// watchdog pings at half of WatchdogSec while snapshots keep completing. A
+// loop that stops producing snapshots for longer than the stall limit stops
+// the pings, so systemd restarts the service.
+func (n *systemdNotifier) watchdog(ctx context.Context) {
+	every := sdnotify.WatchdogInterval() / 2
+	if !n.enabled || every <= 0 {
+		return
+	}
+	go func() {
+		t := time.NewTicker(every)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				last := time.Unix(0, n.progress.Load())
+				if time.Since(last) > time.Duration(n.stall.Load()) {
+					slog.Warn("monitor loop stalled; withholding watchdog ping", "since", time.Since(last).Round(time.Second))
+					continue
+				}
+				n.notify(sdnotify.Watchdog)
+			}
+		}
+	}()
+}
+
+var unitTemplate = template.Must(template.New("unit").Parse(`[Unit]
+Description=VPN leak monitor (vpnleakidentifier)
+Wants=network-online.target
+After=network-online.target
+
+[Service]
+Type=notify
+NotifyAccess=main
+ExecStart={{.ExecStart}}
+ExecReload=/bin/kill -HUP $MAINPID
+WatchdogSec={{.WatchdogSec}}
+Restart=on-failure
+RestartSec=5s
+{{- if not .User}}
+StateDirectory=vpnleakidentifier
+{{- end}}
+
+[Install]
+WantedBy={{if .User}}default.target{{else}}multi-user.target{{end}}
+`))
+
+func runInstallService(args []string) int {
+	fs := flag.NewFlagSet("install-service", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	var name, output, config, exports, logOutput string
+	var user bool
+	var watchdog time.Duration
+	fs.StringVar(&name, "name", "vpnleakidentifier-monitor", "Unit name (without .service)")
+	fs.StringVar(&output, "output", "-", "Where to write the unit file; - prints it, \"auto\" installs it into the systemd unit directory")
+	fs.BoolVar(&user, "user", false, "Generate a user unit (systemctl --user) instead of a system unit")
+	fs.StringVar(&config, "config", "", "Monitor config file passed as --config (reloaded on systemctl reload)")
+	fs.StringVar(&exports, "exports", "", "Exports directory for the service (default: /var/lib/vpnleakidentifier, or ./exports for --user)")
+	fs.StringVar(&logOutput, "log-output", "journald", "Log destination for the service")
+	fs.DurationVar(&watchdog, "watchdog", 2*time.Minute, "WatchdogSec for the unit (0 disables)")
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "cannot determine executable path:", err)
+		return 1
+	}
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+
+	if exports == "" && !user {
+		exports = "/var/lib/vpnleakidentifier"
+	}
+	cmd := []string{exe, "monitor", "--log-output", logOutput}
+	if exports != "" {
+		cmd = append(cmd, "--exports", exports)
+	}
+	if config != "" {
+		cmd = append(cmd, "--config", config)
+	}
+	// Anything after the flags is passed through, e.g. "-- --interval 10s".
+	cmd = append(cmd, fs.Args()...)
+
+	wd := "0"
+	if watchdog > 0 {
+		wd = fmt.Sprintf("%ds", int(watchdog.Seconds()))
+	}
+
+	var b strings.Builder
+	err = unitTemplate.Execute(&b, struct {
+		ExecStart   string
+		WatchdogSec string
+		User        bool
+	}{ExecStart: systemdQuote(cmd), WatchdogSec: wd, User: user})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to render unit:", err)
+		return 1
+	}
+
+	switch output {
+	case "-":
+		fmt.Print(b.String())
+		return 0
+	case "auto":
+		dir := "/etc/systemd/system"
+		if user {
+			home, err := os.UserConfigDir()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 1
+			}
+			dir = filepath.Join(home, "systemd", "user")
+		}
+		output = filepath.Join(dir, name+".service")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(output), 0o755); err != nil {
+		fmt.Fprintln(os.Stderr, "failed to create unit directory:", err)
+		return 1
+	}
+	if err := os.WriteFile(output, []byte(b.String()), 0o644); err != nil {
+		fmt.Fprintln(os.Stderr, "failed to write unit:", err)
+		return 1
+	}
+
+	ctl := "systemctl"
+	if user {
+		ctl = "systemctl --user"
+	}
+	fmt.Printf("Unit written to: %s\n", output)
+	fmt.Printf("Enable with: %s daemon-reload && %s enable --now %s\n", ctl, ctl, filepath.Base(output))
+	return 0
+}
+
+// systemdQuote joins a command line for ExecStart, quoting words with spaces
+// or quotes and escaping "%" and "$" specifiers.
+func systemdQuote(args []string) string {
+	out := make([]string, 0, len(args))
+	for _, a := range args {
+		a = strings.NewReplacer("%", "%%", "$", "$$").Replace(a)
+		if a == "" || strings.ContainsAny(a, " \t\"'\\") {
+			a = `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(a) + `"`
+		}
+		out = append(out, a)
+	}
+	return strings.Join(out, " ")
+}
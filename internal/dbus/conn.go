@@ -0,0 +1,435 @@
+// File: internal/dbus/conn.go (complete file)
+
+package dbus
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// This is a minimal D-Bus client to avoid external dependencies. It supports
+// SASL EXTERNAL over unix sockets, method calls, signal subscriptions and
+// exporting simple objects (enough to stand in for a service in tests).
+
+const (
+	busName      = "org.freedesktop.DBus"
+	busPath      = ObjectPath("/org/freedesktop/DBus")
+	busInterface = "org.freedesktop.DBus"
+
+	propertiesInterface = "org.freedesktop.DBus.Properties"
+
+	defaultSystemBusAddress = "unix:path=/var/run/dbus/system_bus_socket"
+)
+
+var ErrClosed = errors.New("dbus: connection closed")
+
+// Handler serves method calls on an exported object. The returned signature
+// and values form the reply body.
+type Handler func(member string, args []any) (Signature, []any, error)
+
+type handlerKey struct {
+	path  ObjectPath
+	iface string
+}
+
+type Conn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	name   string
+
+	wmu    sync.Mutex
+	serial atomic.Uint32
+
+	mu       sync.Mutex
+	pending  map[uint32]chan *Message
+	signals  []chan *Message
+	handlers map[handlerKey]Handler
+	closed   bool
+}
+
+// SystemBus connects to the system message bus.
+func SystemBus() (*Conn, error) {
+	addr := os.Getenv("DBUS_SYSTEM_BUS_ADDRESS")
+	if addr == "" {
+		addr = defaultSystemBusAddress
+	}
+	return Dial(addr)
+}
+
+// SessionBus connects to the session bus of the current user.
+func SessionBus() (*Conn, error) {
+	addr := os.Getenv("DBUS_SESSION_BUS_ADDRESS")
+	if addr == "" {
+		return nil, errors.New("dbus: DBUS_SESSION_BUS_ADDRESS is not set")
+	}
+	return Dial(addr)
+}
+
+// Dial connects to a bus address (e.g. "unix:path=/run/dbus/system_bus_socket"),
+// authenticates and registers with the bus.
+func Dial(address string) (*Conn, error) {
+	var lastErr error
+	for _, a := range strings.Split(address, ";") {
+		nc, err := dialAddress(a)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		c, err := newConn(nc)
+		if err != nil {
+			_ = nc.Close()
+			lastErr = err
+			continue
+		}
+		return c, nil
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("dbus: no usable address in %q", address)
+	}
+	return nil, lastErr
+}
+
+func dialAddress(addr string) (net.Conn, error) {
+	transport, params, ok := strings.Cut(strings.TrimSpace(addr), ":")
+	if !ok || transport != "unix" {
+		return nil, fmt.Errorf("dbus: unsupported address %q", addr)
+	}
+
+	kv := map[string]string{}
+	for _, p := range strings.Split(params, ",") {
+		k, v, _ := strings.Cut(p, "=")
+		kv[k] = unescapeAddress(v)
+	}
+
+	d := net.Dialer{Timeout: 5 * time.Second}
+	switch {
+	case kv["path"] != "":
+		return d.Dial("unix", kv["path"])
+	case kv["abstract"] != "":
+		return d.Dial("unix", "@"+kv["abstract"])
+	default:
+		return nil, fmt.Errorf("dbus: unsupported address %q", addr)
+	}
+}
+
+func unescapeAddress(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '%' && i+2 < len(s) {
+			if v, err := strconv.ParseUint(s[i+1:i+3], 16, 8); err == nil {
+				b.WriteByte(byte(v))
+				i += 2
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+func newConn(nc net.Conn) (*Conn, error) {
+	c := &Conn{
+		conn:     nc,
+		reader:   bufio.NewReader(nc),
+		pending:  map[uint32]chan *Message{},
+		handlers: map[handlerKey]Handler{},
+	}
+
+	_ = nc.SetDeadline(time.Now().Add(5 * time.Second))
+	if err := c.auth(); err != nil {
+		return nil, err
+	}
+	_ = nc.SetDeadline(time.Time{})
+
+	go c.readLoop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	out, err := c.Call(ctx, busName, busPath, busInterface, "Hello", "")
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+	if len(out) > 0 {
+		c.name, _ = out[0].(string)
+	}
+	return c, nil
+}
+
+func (c *Conn) auth() error {
+	uid := strconv.Itoa(os.Getuid())
+	if _, err := c.conn.Write([]byte("\x00AUTH EXTERNAL " + hex.EncodeToString([]byte(uid)) + "\r\n")); err != nil {
+		return err
+	}
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(line, "OK ") {
+		return fmt.Errorf("dbus: authentication rejected: %s", strings.TrimSpace(line))
+	}
+	_, err = c.conn.Write([]byte("BEGIN\r\n"))
+	return err
+}
+
+// UniqueName returns the bus-assigned name of this connection (e.g. ":1.42").
+func (c *Conn) UniqueName() string {
+	return c.name
+}
+
+func (c *Conn) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	for _, ch := range c.pending {
+		close(ch)
+	}
+	c.pending = map[uint32]chan *Message{}
+	for _, ch := range c.signals {
+		close(ch)
+	}
+	c.signals = nil
+	c.mu.Unlock()
+
+	return c.conn.Close()
+}
+
+func (c *Conn) send(m *Message) error {
+	m.Serial = c.serial.Add(1)
+	b, err := m.marshal()
+	if err != nil {
+		return err
+	}
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	_, err = c.conn.Write(b)
+	return err
+}
+
+func (c *Conn) readLoop() {
+	for {
+		m, err := readMessage(c.reader)
+		if err != nil {
+			c.Close()
+			return
+		}
+
+		switch m.Type {
+		case TypeMethodReturn, TypeError:
+			c.mu.Lock()
+			ch := c.pending[m.ReplySerial]
+			delete(c.pending, m.ReplySerial)
+			c.mu.Unlock()
+			if ch != nil {
+				ch <- m
+			}
+		case TypeSignal:
+			c.mu.Lock()
+			for _, ch := range c.signals {
+				// Slow subscribers lose signals rather than blocking the connection.
+				select {
+				case ch <- m:
+				default:
+				}
+			}
+			c.mu.Unlock()
+		case TypeMethodCall:
+			go c.serve(m)
+		}
+	}
+}
+
+// Call invokes a method and waits for its reply body.
+func (c *Conn) Call(ctx context.Context, dest string, path ObjectPath, iface, member string, sig Signature, args ...any) ([]any, error) {
+	m := &Message{
+		Type:        TypeMethodCall,
+		Path:        path,
+		Interface:   iface,
+		Member:      member,
+		Destination: dest,
+		Signature:   sig,
+		Body:        args,
+	}
+
+	ch := make(chan *Message, 1)
+
+	// Register the reply channel before writing so a fast reply cannot be missed.
+	c.wmu.Lock()
+	m.Serial = c.serial.Add(1)
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		c.wmu.Unlock()
+		return nil, ErrClosed
+	}
+	c.pending[m.Serial] = ch
+	c.mu.Unlock()
+	b, err := m.marshal()
+	if err == nil {
+		_, err = c.conn.Write(b)
+	}
+	c.wmu.Unlock()
+
+	if err != nil {
+		c.mu.Lock()
+		delete(c.pending, m.Serial)
+		c.mu.Unlock()
+		return nil, err
+	}
+
+	select {
+	case <-ctx.Done():
+		c.mu.Lock()
+		delete(c.pending, m.Serial)
+		c.mu.Unlock()
+		return nil, ctx.Err()
+	case r, ok := <-ch:
+		if !ok {
+			return nil, ErrClosed
+		}
+		if r.Type == TypeError {
+			return nil, errorFromReply(r)
+		}
+		return r.Body, nil
+	}
+}
+
+// GetProperty reads one property through org.freedesktop.DBus.Properties.
+func (c *Conn) GetProperty(ctx context.Context, dest string, path ObjectPath, iface, prop string) (any, error) {
+	out, err := c.Call(ctx, dest, path, propertiesInterface, "Get", "ss", iface, prop)
+	if err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, errors.New("dbus: empty property reply")
+	}
+	if v, ok := out[0].(Variant); ok {
+		return v.Value, nil
+	}
+	return out[0], nil
+}
+
+// GetAllProperties reads every property of an interface, unwrapping variants.
+func (c *Conn) GetAllProperties(ctx context.Context, dest string, path ObjectPath, iface string) (map[string]any, error) {
+	out, err := c.Call(ctx, dest, path, propertiesInterface, "GetAll", "s", iface)
+	if err != nil {
+		return nil, err
+	}
+	props := map[string]any{}
+	if len(out) == 0 {
+		return props, nil
+	}
+	m, _ := out[0].(map[any]any)
+	for k, v := range m {
+		name, _ := k.(string)
+		if vv, ok := v.(Variant); ok {
+			props[name] = vv.Value
+		} else {
+			props[name] = v
+		}
+	}
+	return props, nil
+}
+
+// Subscribe installs a match rule on the bus and returns a channel that
+// receives every signal delivered to this connection. Callers filter by
+// interface/member; the channel is closed when the connection closes.
+func (c *Conn) Subscribe(ctx context.Context, rule string) (<-chan *Message, error) {
+	ch := make(chan *Message, 64)
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil, ErrClosed
+	}
+	c.signals = append(c.signals, ch)
+	c.mu.Unlock()
+
+	if _, err := c.Call(ctx, busName, busPath, busInterface, "AddMatch", "s", rule); err != nil {
+		return nil, err
+	}
+	return ch, nil
+}
+
+// RequestName claims a well-known bus name for this connection.
+func (c *Conn) RequestName(ctx context.Context, name string) error {
+	const flagDoNotQueue = 0x4
+	out, err := c.Call(ctx, busName, busPath, busInterface, "RequestName", "su", name, uint32(flagDoNotQueue))
+	if err != nil {
+		return err
+	}
+	// 1 = primary owner, 4 = already owner.
+	if len(out) > 0 {
+		if r, _ := out[0].(uint32); r != 1 && r != 4 {
+			return fmt.Errorf("dbus: could not acquire name %s (reply %d)", name, r)
+		}
+	}
+	return nil
+}
+
+// Export serves method calls for iface on path.
+func (c *Conn) Export(path ObjectPath, iface string, h Handler) {
+	c.mu.Lock()
+	c.handlers[handlerKey{path: path, iface: iface}] = h
+	c.mu.Unlock()
+}
+
+// Emit broadcasts a signal from path.
+func (c *Conn) Emit(path ObjectPath, iface, member string, sig Signature, args ...any) error {
+	return c.send(&Message{
+		Type:      TypeSignal,
+		Path:      path,
+		Interface: iface,
+		Member:    member,
+		Signature: sig,
+		Body:      args,
+	})
+}
+
+func (c *Conn) serve(m *Message) {
+	c.mu.Lock()
+	h := c.handlers[handlerKey{path: m.Path, iface: m.Interface}]
+	c.mu.Unlock()
+
+	reply := &Message{
+		Type:        TypeMethodReturn,
+		ReplySerial: m.Serial,
+		Destination: m.Sender,
+	}
+	if h == nil {
+		reply.Type = TypeError
+		reply.ErrorName = "org.freedesktop.DBus.Error.UnknownMethod"
+		reply.Signature = "s"
+		reply.Body = []any{fmt.Sprintf("no handler for %s.%s on %s", m.Interface, m.Member, m.Path)}
+	} else if sig, out, err := h(m.Member, m.Body); err != nil {
+		reply.Type = TypeError
+		reply.ErrorName = "org.freedesktop.DBus.Error.Failed"
+		var de *Error
+		if errors.As(err, &de) {
+			reply.ErrorName = de.Name
+			err = errors.New(de.Message)
+		}
+		reply.Signature = "s"
+		reply.Body = []any{err.Error()}
+	} else {
+		reply.Signature = sig
+		reply.Body = out
+	}
+
+	if m.Flags&FlagNoReplyExpected != 0 {
+		return
+	}
+	_ = c.send(reply)
+}
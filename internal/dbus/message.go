@@ -0,0 +1,194 @@
+// File: internal/dbus/message.go (complete file)
+
+package dbus
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+type MessageType byte
+
+const (
+	TypeMethodCall   MessageType = 1
+	TypeMethodReturn MessageType = 2
+	TypeError        MessageType = 3
+	TypeSignal       MessageType = 4
+)
+
+const (
+	FlagNoReplyExpected byte = 0x1
+
+	protocolVersion = 1
+	maxMessageSize  = 128 << 20
+
+	fieldPath        byte = 1
+	fieldInterface   byte = 2
+	fieldMember      byte = 3
+	fieldErrorName   byte = 4
+	fieldReplySerial byte = 5
+	fieldDestination byte = 6
+	fieldSender      byte = 7
+	fieldSignature   byte = 8
+)
+
+type Message struct {
+	Type        MessageType
+	Flags       byte
+	Serial      uint32
+	Path        ObjectPath
+	Interface   string
+	Member      string
+	ErrorName   string
+	ReplySerial uint32
+	Destination string
+	Sender      string
+	Signature   Signature
+	Body        []any
+}
+
+func (m *Message) marshal() ([]byte, error) {
+	body := newEncoder()
+	if m.Signature != "" {
+		if err := body.encodeAll(string(m.Signature), m.Body); err != nil {
+			return nil, err
+		}
+	}
+
+	var fields []any
+	addField := func(code byte, sig string, v any) {
+		fields = append(fields, []any{code, Variant{Sig: Signature(sig), Value: v}})
+	}
+	if m.Path != "" {
+		addField(fieldPath, "o", m.Path)
+	}
+	if m.Interface != "" {
+		addField(fieldInterface, "s", m.Interface)
+	}
+	if m.Member != "" {
+		addField(fieldMember, "s", m.Member)
+	}
+	if m.ErrorName != "" {
+		addField(fieldErrorName, "s", m.ErrorName)
+	}
+	if m.ReplySerial != 0 {
+		addField(fieldReplySerial, "u", m.ReplySerial)
+	}
+	if m.Destination != "" {
+		addField(fieldDestination, "s", m.Destination)
+	}
+	if m.Signature != "" {
+		addField(fieldSignature, "g", m.Signature)
+	}
+
+	hdr := newEncoder()
+	hdr.buf = append(hdr.buf, 'l', byte(m.Type), m.Flags, protocolVersion)
+	hdr.u32(uint32(len(body.buf)))
+	hdr.u32(m.Serial)
+	if err := hdr.encode("a(yv)", fields); err != nil {
+		return nil, err
+	}
+	hdr.align(8)
+
+	return append(hdr.buf, body.buf...), nil
+}
+
+func readMessage(r io.Reader) (*Message, error) {
+	fixed := make([]byte, 16)
+	if _, err := io.ReadFull(r, fixed); err != nil {
+		return nil, err
+	}
+
+	var order binary.ByteOrder
+	switch fixed[0] {
+	case 'l':
+		order = binary.LittleEndian
+	case 'B':
+		order = binary.BigEndian
+	default:
+		return nil, fmt.Errorf("dbus: invalid endianness %q", fixed[0])
+	}
+
+	bodyLen := int(order.Uint32(fixed[4:8]))
+	fieldsLen := int(order.Uint32(fixed[12:16]))
+	hdrLen := 16 + fieldsLen
+	if hdrLen%8 != 0 {
+		hdrLen += 8 - hdrLen%8
+	}
+	if hdrLen+bodyLen > maxMessageSize {
+		return nil, errors.New("dbus: message too large")
+	}
+
+	buf := make([]byte, hdrLen+bodyLen)
+	copy(buf, fixed)
+	if _, err := io.ReadFull(r, buf[16:]); err != nil {
+		return nil, err
+	}
+
+	m := &Message{
+		Type:   MessageType(fixed[1]),
+		Flags:  fixed[2],
+		Serial: order.Uint32(fixed[8:12]),
+	}
+
+	hd := &decoder{buf: buf[:16+fieldsLen], pos: 12, order: order}
+	raw, err := hd.decode("a(yv)")
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range raw.([]any) {
+		pair := f.([]any)
+		code := pair[0].(byte)
+		val := pair[1].(Variant).Value
+		switch code {
+		case fieldPath:
+			m.Path, _ = val.(ObjectPath)
+		case fieldInterface:
+			m.Interface, _ = val.(string)
+		case fieldMember:
+			m.Member, _ = val.(string)
+		case fieldErrorName:
+			m.ErrorName, _ = val.(string)
+		case fieldReplySerial:
+			m.ReplySerial, _ = val.(uint32)
+		case fieldDestination:
+			m.Destination, _ = val.(string)
+		case fieldSender:
+			m.Sender, _ = val.(string)
+		case fieldSignature:
+			m.Signature, _ = val.(Signature)
+		}
+	}
+
+	if m.Signature != "" {
+		bd := &decoder{buf: buf[hdrLen:], order: order}
+		m.Body, err = bd.decodeAll(string(m.Signature))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// Error is a D-Bus error reply.
+type Error struct {
+	Name    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return e.Name
+	}
+	return e.Name + ": " + e.Message
+}
+
+func errorFromReply(m *Message) error {
+	e := &Error{Name: m.ErrorName}
+	if len(m.Body) > 0 {
+		e.Message, _ = m.Body[0].(string)
+	}
+	return e
+}
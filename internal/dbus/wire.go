@@ -0,0 +1,533 @@
+// File: internal/dbus/wire.go (complete file)
+
+package dbus
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+)
+
+// Wire format marshalling for the D-Bus type system. Values are driven by an
+// explicit signature so no reflection-based type inference is needed on the
+// encode side beyond walking slices and maps.
+//
+// Decoded Go representations:
+//   y byte, b bool, n int16, q uint16, i int32, u uint32, x int64, t uint64,
+//   d float64, h uint32, s string, o ObjectPath, g Signature, v Variant,
+//   ay []byte, a{..} map[any]any, a.. []any, (..) []any
+
+type ObjectPath string
+
+type Signature string
+
+type Variant struct {
+	Sig   Signature
+	Value any
+}
+
+// MakeVariant wraps a value whose signature can be inferred from its Go type.
+func MakeVariant(v any) Variant {
+	return Variant{Sig: Signature(signatureOf(v)), Value: v}
+}
+
+func signatureOf(v any) string {
+	switch v.(type) {
+	case byte:
+		return "y"
+	case bool:
+		return "b"
+	case int16:
+		return "n"
+	case uint16:
+		return "q"
+	case int32, int:
+		return "i"
+	case uint32:
+		return "u"
+	case int64:
+		return "x"
+	case uint64:
+		return "t"
+	case float64:
+		return "d"
+	case string:
+		return "s"
+	case ObjectPath:
+		return "o"
+	case Signature:
+		return "g"
+	case Variant:
+		return "v"
+	case []byte:
+		return "ay"
+	case []string:
+		return "as"
+	case []ObjectPath:
+		return "ao"
+	case map[string]Variant:
+		return "a{sv}"
+	case map[string]any:
+		return "a{sv}"
+	default:
+		return "v"
+	}
+}
+
+// splitType returns the first complete type of sig and the remainder.
+func splitType(sig string) (string, string, error) {
+	if sig == "" {
+		return "", "", errors.New("dbus: empty signature")
+	}
+	switch sig[0] {
+	case 'a':
+		elem, rest, err := splitType(sig[1:])
+		if err != nil {
+			return "", "", err
+		}
+		return "a" + elem, rest, nil
+	case '(', '{':
+		closer := byte(')')
+		if sig[0] == '{' {
+			closer = '}'
+		}
+		depth := 0
+		for i := 0; i < len(sig); i++ {
+			switch sig[i] {
+			case '(', '{':
+				depth++
+			case ')', '}':
+				depth--
+				if depth == 0 {
+					if sig[i] != closer {
+						return "", "", fmt.Errorf("dbus: mismatched signature %q", sig)
+					}
+					return sig[:i+1], sig[i+1:], nil
+				}
+			}
+		}
+		return "", "", fmt.Errorf("dbus: unterminated signature %q", sig)
+	default:
+		return sig[:1], sig[1:], nil
+	}
+}
+
+func splitTypes(sig string) ([]string, error) {
+	var out []string
+	for sig != "" {
+		t, rest, err := splitType(sig)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+		sig = rest
+	}
+	return out, nil
+}
+
+func alignment(t byte) int {
+	switch t {
+	case 'y', 'g', 'v':
+		return 1
+	case 'n', 'q':
+		return 2
+	case 'x', 't', 'd', '(', '{':
+		return 8
+	default:
+		return 4
+	}
+}
+
+// encoder always writes little-endian messages.
+type encoder struct {
+	buf []byte
+}
+
+func newEncoder() *encoder {
+	return &encoder{}
+}
+
+func (e *encoder) align(n int) {
+	for len(e.buf)%n != 0 {
+		e.buf = append(e.buf, 0)
+	}
+}
+
+func (e *encoder) u32(v uint32) {
+	e.align(4)
+	e.buf = binary.LittleEndian.AppendUint32(e.buf, v)
+}
+
+func (e *encoder) encodeAll(sig string, vals []any) error {
+	types, err := splitTypes(sig)
+	if err != nil {
+		return err
+	}
+	if len(types) != len(vals) {
+		return fmt.Errorf("dbus: signature %q expects %d values, got %d", sig, len(types), len(vals))
+	}
+	for i, t := range types {
+		if err := e.encode(t, vals[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *encoder) encode(sig string, v any) error {
+	switch sig[0] {
+	case 'y':
+		n, err := toUint(v)
+		if err != nil {
+			return err
+		}
+		e.buf = append(e.buf, byte(n))
+	case 'b':
+		b, ok := v.(bool)
+		if !ok {
+			return fmt.Errorf("dbus: expected bool, got %T", v)
+		}
+		var n uint32
+		if b {
+			n = 1
+		}
+		e.u32(n)
+	case 'n', 'q':
+		n, err := toUint(v)
+		if err != nil {
+			return err
+		}
+		e.align(2)
+		e.buf = binary.LittleEndian.AppendUint16(e.buf, uint16(n))
+	case 'i', 'u', 'h':
+		n, err := toUint(v)
+		if err != nil {
+			return err
+		}
+		e.u32(uint32(n))
+	case 'x', 't':
+		n, err := toUint(v)
+		if err != nil {
+			return err
+		}
+		e.align(8)
+		e.buf = binary.LittleEndian.AppendUint64(e.buf, n)
+	case 'd':
+		f, ok := v.(float64)
+		if !ok {
+			return fmt.Errorf("dbus: expected float64, got %T", v)
+		}
+		e.align(8)
+		e.buf = binary.LittleEndian.AppendUint64(e.buf, math.Float64bits(f))
+	case 's', 'o':
+		s, err := toString(v)
+		if err != nil {
+			return err
+		}
+		e.u32(uint32(len(s)))
+		e.buf = append(e.buf, s...)
+		e.buf = append(e.buf, 0)
+	case 'g':
+		s, err := toString(v)
+		if err != nil {
+			return err
+		}
+		e.buf = append(e.buf, byte(len(s)))
+		e.buf = append(e.buf, s...)
+		e.buf = append(e.buf, 0)
+	case 'v':
+		vv, ok := v.(Variant)
+		if !ok {
+			vv = MakeVariant(v)
+			if vv.Sig == "v" {
+				return fmt.Errorf("dbus: cannot infer variant signature for %T", v)
+			}
+		}
+		if err := e.encode("g", string(vv.Sig)); err != nil {
+			return err
+		}
+		return e.encode(string(vv.Sig), vv.Value)
+	case 'a':
+		return e.encodeArray(sig[1:], v)
+	case '(':
+		fields, ok := v.([]any)
+		if !ok {
+			return fmt.Errorf("dbus: expected []any for struct, got %T", v)
+		}
+		e.align(8)
+		return e.encodeAll(sig[1:len(sig)-1], fields)
+	default:
+		return fmt.Errorf("dbus: unsupported type %q", sig)
+	}
+	return nil
+}
+
+func (e *encoder) encodeArray(elem string, v any) error {
+	e.u32(0)
+	lenPos := len(e.buf) - 4
+	e.align(alignment(elem[0]))
+	start := len(e.buf)
+
+	rv := reflect.ValueOf(v)
+	if elem[0] == '{' {
+		if rv.Kind() != reflect.Map {
+			return fmt.Errorf("dbus: expected map for dict, got %T", v)
+		}
+		types, err := splitTypes(elem[1 : len(elem)-1])
+		if err != nil || len(types) != 2 {
+			return fmt.Errorf("dbus: invalid dict signature %q", elem)
+		}
+		// Sort keys so the wire form is deterministic.
+		keys := rv.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+		for _, k := range keys {
+			e.align(8)
+			if err := e.encode(types[0], k.Interface()); err != nil {
+				return err
+			}
+			if err := e.encode(types[1], rv.MapIndex(k).Interface()); err != nil {
+				return err
+			}
+		}
+	} else {
+		if v != nil && rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+			return fmt.Errorf("dbus: expected slice for array, got %T", v)
+		}
+		if v != nil {
+			for i := 0; i < rv.Len(); i++ {
+				if err := e.encode(elem, rv.Index(i).Interface()); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	binary.LittleEndian.PutUint32(e.buf[lenPos:], uint32(len(e.buf)-start))
+	return nil
+}
+
+func toUint(v any) (uint64, error) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return uint64(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return rv.Uint(), nil
+	}
+	return 0, fmt.Errorf("dbus: expected integer, got %T", v)
+}
+
+func toString(v any) (string, error) {
+	switch t := v.(type) {
+	case string:
+		return t, nil
+	case ObjectPath:
+		return string(t), nil
+	case Signature:
+		return string(t), nil
+	}
+	return "", fmt.Errorf("dbus: expected string, got %T", v)
+}
+
+type decoder struct {
+	buf   []byte
+	pos   int
+	order binary.ByteOrder
+}
+
+var errShort = errors.New("dbus: message truncated")
+
+func (d *decoder) align(n int) error {
+	for d.pos%n != 0 {
+		d.pos++
+	}
+	if d.pos > len(d.buf) {
+		return errShort
+	}
+	return nil
+}
+
+func (d *decoder) take(n int) ([]byte, error) {
+	if n < 0 || d.pos+n > len(d.buf) {
+		return nil, errShort
+	}
+	b := d.buf[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+func (d *decoder) u32() (uint32, error) {
+	if err := d.align(4); err != nil {
+		return 0, err
+	}
+	b, err := d.take(4)
+	if err != nil {
+		return 0, err
+	}
+	return d.order.Uint32(b), nil
+}
+
+func (d *decoder) decodeAll(sig string) ([]any, error) {
+	types, err := splitTypes(sig)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]any, 0, len(types))
+	for _, t := range types {
+		v, err := d.decode(t)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+func (d *decoder) decode(sig string) (any, error) {
+	switch sig[0] {
+	case 'y':
+		b, err := d.take(1)
+		if err != nil {
+			return nil, err
+		}
+		return b[0], nil
+	case 'b':
+		n, err := d.u32()
+		return n != 0, err
+	case 'n', 'q':
+		if err := d.align(2); err != nil {
+			return nil, err
+		}
+		b, err := d.take(2)
+		if err != nil {
+			return nil, err
+		}
+		if sig[0] == 'n' {
+			return int16(d.order.Uint16(b)), nil
+		}
+		return d.order.Uint16(b), nil
+	case 'i':
+		n, err := d.u32()
+		return int32(n), err
+	case 'u', 'h':
+		return d.u32()
+	case 'x', 't', 'd':
+		if err := d.align(8); err != nil {
+			return nil, err
+		}
+		b, err := d.take(8)
+		if err != nil {
+			return nil, err
+		}
+		n := d.order.Uint64(b)
+		switch sig[0] {
+		case 'x':
+			return int64(n), nil
+		case 'd':
+			return math.Float64frombits(n), nil
+		}
+		return n, nil
+	case 's', 'o':
+		l, err := d.u32()
+		if err != nil {
+			return nil, err
+		}
+		b, err := d.take(int(l) + 1)
+		if err != nil {
+			return nil, err
+		}
+		if sig[0] == 'o' {
+			return ObjectPath(b[:l]), nil
+		}
+		return string(b[:l]), nil
+	case 'g':
+		lb, err := d.take(1)
+		if err != nil {
+			return nil, err
+		}
+		b, err := d.take(int(lb[0]) + 1)
+		if err != nil {
+			return nil, err
+		}
+		return Signature(b[:lb[0]]), nil
+	case 'v':
+		s, err := d.decode("g")
+		if err != nil {
+			return nil, err
+		}
+		sig := string(s.(Signature))
+		if _, rest, err := splitType(sig); err != nil || rest != "" {
+			return nil, fmt.Errorf("dbus: invalid variant signature %q", sig)
+		}
+		v, err := d.decode(sig)
+		if err != nil {
+			return nil, err
+		}
+		return Variant{Sig: Signature(sig), Value: v}, nil
+	case 'a':
+		return d.decodeArray(sig[1:])
+	case '(':
+		if err := d.align(8); err != nil {
+			return nil, err
+		}
+		return d.decodeAll(sig[1 : len(sig)-1])
+	}
+	return nil, fmt.Errorf("dbus: unsupported type %q", sig)
+}
+
+func (d *decoder) decodeArray(elem string) (any, error) {
+	l, err := d.u32()
+	if err != nil {
+		return nil, err
+	}
+	if err := d.align(alignment(elem[0])); err != nil {
+		return nil, err
+	}
+	end := d.pos + int(l)
+	if end > len(d.buf) {
+		return nil, errShort
+	}
+
+	if elem == "y" {
+		b, _ := d.take(int(l))
+		return append([]byte(nil), b...), nil
+	}
+
+	if elem[0] == '{' {
+		types, err := splitTypes(elem[1 : len(elem)-1])
+		if err != nil || len(types) != 2 {
+			return nil, fmt.Errorf("dbus: invalid dict signature %q", elem)
+		}
+		m := map[any]any{}
+		for d.pos < end {
+			if err := d.align(8); err != nil {
+				return nil, err
+			}
+			k, err := d.decode(types[0])
+			if err != nil {
+				return nil, err
+			}
+			v, err := d.decode(types[1])
+			if err != nil {
+				return nil, err
+			}
+			m[k] = v
+		}
+		return m, nil
+	}
+
+	out := []any{}
+	for d.pos < end {
+		v, err := d.decode(elem)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
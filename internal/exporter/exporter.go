@@ -0,0 +1,196 @@
+// File: internal/exporter/exporter.go (complete file)
+
+package exporter
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/baptistax/vpn-leak-identifier/internal/geo"
+	"github.com/baptistax/vpn-leak-identifier/internal/monitor"
+	"github.com/baptistax/vpn-leak-identifier/internal/netutil"
+	"github.com/baptistax/vpn-leak-identifier/internal/report"
+)
+
+// leakKinds are the monitor events counted in vli_leak_events_total. They are
+// published at zero from the start so increase() works on the first leak. Exit
+// and recursor changes are routine on a VPN and only show in
+// vli_monitor_events_total.
+var leakKinds = []monitor.EventKind{
+	monitor.KindIPv6Appeared,
+	monitor.KindSTUNMismatch,
+	monitor.KindLeakToKnownISP,
+}
+
+// GeoLookup resolves the current exit of a family ("ipv4"/"ipv6") to its IP
+// and geo data.
+type GeoLookup func(ctx context.Context, family string) (ip string, geo report.GeoInfo, err error)
+
+// Exporter turns monitor snapshots and events into Prometheus metrics.
+type Exporter struct {
+	reg *Registry
+
+	online           *Vec
+	exitInfo         *Vec
+	recursors        *Vec
+	snapshots        *Vec
+	events           *Vec
+	leakEvents       *Vec
+	probeDuration    *Vec
+	probeLastSuccess *Vec
+	lastSuccess      *Vec
+
+	lookupGeo GeoLookup
+
+	mu  sync.Mutex
+	geo map[string]report.GeoInfo // exit IP -> geo
+}
+
+// New creates an exporter. Geo data comes from the snapshot's exits; when
+// they have none, lookup is queried the first time an exit IP is seen. A nil
+// lookup asks providers, quorum of which must agree.
+func New(lookup GeoLookup, providers []geo.Provider, quorum int) *Exporter {
+	if lookup == nil {
+		lookup = consensusGeo(providers, quorum)
+	}
+	r := &Registry{}
+	e := &Exporter{
+		reg:       r,
+		lookupGeo: lookup,
+		geo:       map[string]report.GeoInfo{},
+
+		online:    r.Gauge("vli_online", "Whether the last snapshot reached the internet over this family (1/0).", "family"),
+		exitInfo:  r.Gauge("vli_exit_info", "Current exit IP per family with geo labels; always 1.", "family", "ip", "asn", "country"),
+		recursors: r.Gauge("vli_dns_recursors", "DNS recursors seen by the last snapshot."),
+		snapshots: r.Counter("vli_snapshots_total", "Snapshots taken, by probe depth.", "full"),
+		events:    r.Counter("vli_monitor_events_total", "Monitor events by kind and severity.", "kind", "severity"),
+		leakEvents: r.Counter("vli_leak_events_total",
+			"Leak monitor events (IPv6 appearance, STUN mismatch, known ISP).", "kind"),
+		probeDuration: r.Histogram("vli_probe_duration_seconds", "Probe latency by prober and endpoint.",
+			[]float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 20}, "prober", "endpoint"),
+		probeLastSuccess: r.Gauge("vli_probe_last_success_timestamp_seconds", "Unix time of the last successful probe.", "prober", "endpoint"),
+		lastSuccess:      r.Gauge("vli_last_success_timestamp_seconds", "Unix time of the last snapshot that reached the internet."),
+	}
+	for _, k := range leakKinds {
+		e.leakEvents.Add(0, string(k))
+	}
+	return e
+}
+
+// ObserveSnapshot updates the state gauges and probe metrics.
+func (e *Exporter) ObserveSnapshot(ctx context.Context, snap report.Snapshot, full bool) {
+	e.snapshots.Add(1, boolLabel(full))
+
+	anyOnline := false
+	e.exitInfo.Reset()
+	for _, r := range snap.PublicIPs {
+		up := r.IP != "" && r.Error == ""
+		e.online.Set(boolValue(up), r.Family)
+		if !up {
+			continue
+		}
+		anyOnline = true
+		if r.Family == "any" {
+			continue
+		}
+		x := snap.Exit(r.Family)
+		geo := x.Geo
+		if x.IP != r.IP || (geo.ASN == "" && geo.CountryCode == "") {
+			geo = e.geoFor(ctx, r.Family, r.IP)
+		}
+		country := geo.CountryCode
+		if country == "" {
+			country = geo.Country
+		}
+		e.exitInfo.Set(1, r.Family, r.IP, geo.ASN, country)
+	}
+	if anyOnline {
+		e.lastSuccess.Set(float64(snap.TimestampUTC.Unix()))
+	}
+
+	e.recursors.Set(float64(len(snap.DNSRecursors)))
+
+	at := float64(snap.TimestampUTC.Unix())
+	for _, p := range snap.Probes {
+		e.probeDuration.Observe(float64(p.DurationMS)/1000, p.Prober, p.Endpoint)
+		if p.OK {
+			e.probeLastSuccess.Set(at, p.Prober, p.Endpoint)
+		}
+	}
+}
+
+// ObserveEvent counts a monitor event.
+func (e *Exporter) ObserveEvent(ev monitor.Event) {
+	e.events.Add(1, string(ev.Kind), string(ev.Severity))
+	for _, k := range leakKinds {
+		if ev.Kind == k {
+			e.leakEvents.Add(1, string(k))
+		}
+	}
+}
+
+// Handler serves /metrics.
+func (e *Exporter) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = e.reg.WriteText(w)
+	})
+}
+
+func (e *Exporter) geoFor(ctx context.Context, family, ip string) report.GeoInfo {
+	e.mu.Lock()
+	geo, ok := e.geo[ip]
+	e.mu.Unlock()
+	if ok {
+		return geo
+	}
+
+	lctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	gotIP, geo, err := e.lookupGeo(lctx, family)
+	if err != nil || gotIP != ip {
+		// Retry on the next snapshot; the exit may have moved in between.
+		return report.GeoInfo{}
+	}
+
+	e.mu.Lock()
+	e.geo[ip] = geo
+	e.mu.Unlock()
+	return geo
+}
+
+// consensusGeo returns a GeoLookup backed by the given geo providers.
+func consensusGeo(providers []geo.Provider, quorum int) GeoLookup {
+	return func(ctx context.Context, family string) (string, report.GeoInfo, error) {
+		res := geo.Query(ctx, netutil.HTTPClientForFamily(family), family, providers, quorum)
+		if err := res.Err(); err != nil {
+			return "", report.GeoInfo{}, err
+		}
+		g := res.Geo
+		return g.IP, report.GeoInfo{
+			Country:     g.Country,
+			CountryCode: g.CountryCode,
+			Region:      g.Region,
+			City:        g.City,
+			ISP:         g.ISP,
+			ASN:         g.ASN,
+			Timezone:    g.Timezone,
+		}, nil
+	}
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func boolLabel(b bool) string {
+	if b {
+		return "true"
+	}
+	return "false"
+}
@@ -0,0 +1,83 @@
+package exporter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/baptistax/vpn-leak-identifier/internal/geo"
+	"github.com/baptistax/vpn-leak-identifier/internal/monitor"
+	"github.com/baptistax/vpn-leak-identifier/internal/report"
+)
+
+func TestExporter_Metrics(t *testing.T) {
+	lookups := 0
+	e := New(func(ctx context.Context, family string) (string, report.GeoInfo, error) {
+		lookups++
+		return "198.51.100.7", report.GeoInfo{ASN: "AS64500", CountryCode: "NL"}, nil
+	}, nil, 0)
+
+	snap := report.Snapshot{
+		TimestampUTC: time.Unix(1700000000, 0).UTC(),
+		PublicIPs: []report.PublicIPResult{
+			{Source: "ipify", Family: "ipv4", IP: "198.51.100.7"},
+			{Source: "ipify", Family: "ipv6", Error: "disabled"},
+		},
+		Observation: report.Observation{DNSRecursors: []string{"198.51.100.53", "198.51.100.54"}},
+		Probes: []report.ProbeTiming{
+			{Prober: "ipify", Endpoint: "api.ipify.org", DurationMS: 180, OK: true},
+		},
+	}
+	e.ObserveSnapshot(context.Background(), snap, true)
+	e.ObserveSnapshot(context.Background(), snap, false)
+	e.ObserveEvent(monitor.Event{Kind: monitor.KindSTUNMismatch, Severity: monitor.SeverityCritical})
+	e.ObserveEvent(monitor.Event{Kind: monitor.KindVPNExitChanged, Severity: monitor.SeverityWarning})
+
+	var b strings.Builder
+	if err := e.reg.WriteText(&b); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+
+	for _, want := range []string{
+		`vli_online{family="ipv4"} 1`,
+		`vli_online{family="ipv6"} 0`,
+		`vli_exit_info{family="ipv4",ip="198.51.100.7",asn="AS64500",country="NL"} 1`,
+		`vli_dns_recursors 2`,
+		`vli_snapshots_total{full="true"} 1`,
+		`vli_leak_events_total{kind="stun_mismatch"} 1`,
+		`vli_leak_events_total{kind="leak_to_known_isp"} 0`,
+		`vli_monitor_events_total{kind="vpn_exit_changed",severity="warning"} 1`,
+		`vli_probe_duration_seconds_bucket{prober="ipify",endpoint="api.ipify.org",le="0.1"} 0`,
+		`vli_probe_duration_seconds_bucket{prober="ipify",endpoint="api.ipify.org",le="0.25"} 2`,
+		`vli_probe_duration_seconds_count{prober="ipify",endpoint="api.ipify.org"} 2`,
+		`vli_last_success_timestamp_seconds 1.7e+09`,
+		"# TYPE vli_probe_duration_seconds histogram",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+	if lookups != 1 {
+		t.Fatalf("expected geo to be cached after one lookup, got %d", lookups)
+	}
+}
+
+func TestExporter_DefaultLookupUsesConfiguredProviders(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"ip":"198.51.100.7","asn":64500,"cc":"nl"}`))
+	}))
+	defer srv.Close()
+
+	e := New(nil, []geo.Provider{{Name: "local", Style: geo.StyleIdent, URL: srv.URL}}, 1)
+	ip, g, err := e.lookupGeo(context.Background(), "ipv4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ip != "198.51.100.7" || g.ASN != "AS64500" || g.CountryCode != "NL" {
+		t.Fatalf("unexpected lookup: %s %+v", ip, g)
+	}
+}
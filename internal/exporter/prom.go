@@ -0,0 +1,186 @@
+// File: internal/exporter/prom.go (complete file)
+
+package exporter
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Registry is a minimal Prometheus text-format (0.0.4) metric store, enough
+// for the handful of gauges, counters and histograms the exporter publishes.
+type Registry struct {
+	mu       sync.Mutex
+	families []*Vec
+}
+
+type metricType string
+
+const (
+	typeGauge     metricType = "gauge"
+	typeCounter   metricType = "counter"
+	typeHistogram metricType = "histogram"
+)
+
+// Vec is a metric family with a fixed label schema.
+type Vec struct {
+	reg     *Registry
+	name    string
+	help    string
+	typ     metricType
+	labels  []string
+	buckets []float64
+	series  map[string]*series
+}
+
+type series struct {
+	labelValues []string
+	value       float64
+	counts      []uint64 // histogram: per bucket, non-cumulative
+	sum         float64
+	count       uint64
+}
+
+func (r *Registry) add(name, help string, typ metricType, buckets []float64, labels []string) *Vec {
+	v := &Vec{reg: r, name: name, help: help, typ: typ, labels: labels, buckets: buckets, series: map[string]*series{}}
+	r.mu.Lock()
+	r.families = append(r.families, v)
+	r.mu.Unlock()
+	return v
+}
+
+func (r *Registry) Gauge(name, help string, labels ...string) *Vec {
+	return r.add(name, help, typeGauge, nil, labels)
+}
+
+func (r *Registry) Counter(name, help string, labels ...string) *Vec {
+	return r.add(name, help, typeCounter, nil, labels)
+}
+
+func (r *Registry) Histogram(name, help string, buckets []float64, labels ...string) *Vec {
+	b := append([]float64(nil), buckets...)
+	sort.Float64s(b)
+	return r.add(name, help, typeHistogram, b, labels)
+}
+
+func (v *Vec) get(lv []string) *series {
+	if len(lv) != len(v.labels) {
+		panic(fmt.Sprintf("metric %s: got %d label values, want %d", v.name, len(lv), len(v.labels)))
+	}
+	key := strings.Join(lv, "\xff")
+	s := v.series[key]
+	if s == nil {
+		s = &series{labelValues: append([]string(nil), lv...)}
+		if v.typ == typeHistogram {
+			s.counts = make([]uint64, len(v.buckets))
+		}
+		v.series[key] = s
+	}
+	return s
+}
+
+func (v *Vec) Set(val float64, lv ...string) {
+	v.reg.mu.Lock()
+	defer v.reg.mu.Unlock()
+	v.get(lv).value = val
+}
+
+func (v *Vec) Add(val float64, lv ...string) {
+	v.reg.mu.Lock()
+	defer v.reg.mu.Unlock()
+	v.get(lv).value += val
+}
+
+func (v *Vec) Observe(val float64, lv ...string) {
+	v.reg.mu.Lock()
+	defer v.reg.mu.Unlock()
+	s := v.get(lv)
+	for i, le := range v.buckets {
+		if val <= le {
+			s.counts[i]++
+			break
+		}
+	}
+	s.sum += val
+	s.count++
+}
+
+// Reset drops every series, e.g. before republishing an info metric.
+func (v *Vec) Reset() {
+	v.reg.mu.Lock()
+	defer v.reg.mu.Unlock()
+	v.series = map[string]*series{}
+}
+
+// WriteText writes all metrics in the Prometheus text exposition format.
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var b strings.Builder
+	for _, v := range r.families {
+		fmt.Fprintf(&b, "# HELP %s %s\n", v.name, escapeHelp(v.help))
+		fmt.Fprintf(&b, "# TYPE %s %s\n", v.name, v.typ)
+
+		keys := make([]string, 0, len(v.series))
+		for k := range v.series {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			s := v.series[k]
+			if v.typ != typeHistogram {
+				fmt.Fprintf(&b, "%s%s %s\n", v.name, formatLabels(v.labels, s.labelValues, "", ""), formatFloat(s.value))
+				continue
+			}
+			var cum uint64
+			for i, le := range v.buckets {
+				cum += s.counts[i]
+				fmt.Fprintf(&b, "%s_bucket%s %d\n", v.name, formatLabels(v.labels, s.labelValues, "le", formatFloat(le)), cum)
+			}
+			fmt.Fprintf(&b, "%s_bucket%s %d\n", v.name, formatLabels(v.labels, s.labelValues, "le", "+Inf"), s.count)
+			fmt.Fprintf(&b, "%s_sum%s %s\n", v.name, formatLabels(v.labels, s.labelValues, "", ""), formatFloat(s.sum))
+			fmt.Fprintf(&b, "%s_count%s %d\n", v.name, formatLabels(v.labels, s.labelValues, "", ""), s.count)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func formatLabels(names, values []string, extraName, extraValue string) string {
+	if len(names) == 0 && extraName == "" {
+		return ""
+	}
+	parts := make([]string, 0, len(names)+1)
+	for i, n := range names {
+		parts = append(parts, n+`="`+escapeLabel(values[i])+`"`)
+	}
+	if extraName != "" {
+		parts = append(parts, extraName+`="`+extraValue+`"`)
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,201 @@
+// File: internal/geo/consensus.go (complete file)
+
+package geo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Status says how far the providers agreed on the exit IP.
+type Status string
+
+const (
+	// StatusAgreed: at least quorum providers returned the same IP and none
+	// returned another.
+	StatusAgreed Status = "agreed"
+	// StatusDisputed: providers returned different IPs.
+	StatusDisputed Status = "disputed"
+	// StatusInsufficient: one IP, but fewer than quorum providers answered.
+	StatusInsufficient Status = "insufficient"
+	// StatusFailed: no provider answered.
+	StatusFailed Status = "failed"
+)
+
+// DefaultQuorum is how many providers must agree on an exit.
+const DefaultQuorum = 2
+
+// Result is the consensus over several providers. Geo holds the exit IP and,
+// per field, the value most providers reporting that IP agreed on.
+type Result struct {
+	Status        Status
+	Quorum        int
+	Agreeing      []string // providers that reported Geo.IP
+	Geo           Answer
+	Disagreements []Disagreement
+	Answers       []Answer // one per provider, in query order
+}
+
+// Disagreement is a field on which successful providers differed.
+type Disagreement struct {
+	Field  string
+	Values map[string]string // provider -> value
+}
+
+// Err joins the provider errors when none answered.
+func (r Result) Err() error {
+	if r.Status != StatusFailed {
+		return nil
+	}
+	var msgs []string
+	for _, a := range r.Answers {
+		if a.Err != nil {
+			msgs = append(msgs, a.Provider+": "+a.Err.Error())
+		}
+	}
+	if len(msgs) == 0 {
+		return errors.New("no geo providers configured")
+	}
+	return errors.New(strings.Join(msgs, "; "))
+}
+
+// Query asks every provider in parallel over client and builds the consensus.
+// A quorum <= 0 uses DefaultQuorum; it is capped at the number of providers
+// that serve the family.
+func Query(ctx context.Context, client *http.Client, family string, providers []Provider, quorum int) Result {
+	providers = forFamily(providers, family)
+	answers := make([]Answer, len(providers))
+	var wg sync.WaitGroup
+	for i, p := range providers {
+		wg.Add(1)
+		go func(i int, p Provider) {
+			defer wg.Done()
+			answers[i] = p.Fetch(ctx, client, family)
+		}(i, p)
+	}
+	wg.Wait()
+	return Consensus(answers, quorum)
+}
+
+// forFamily drops the providers that cannot answer for family.
+func forFamily(providers []Provider, family string) []Provider {
+	if family != "ipv6" {
+		return providers
+	}
+	var out []Provider
+	for _, p := range providers {
+		if !p.IPv4Only {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// Consensus builds a Result from provider answers.
+func Consensus(answers []Answer, quorum int) Result {
+	if quorum <= 0 {
+		quorum = DefaultQuorum
+	}
+	if quorum > len(answers) {
+		quorum = len(answers)
+	}
+	r := Result{Quorum: quorum, Answers: answers, Status: StatusFailed}
+
+	var ok []Answer
+	byIP := map[string][]string{}
+	var ips []string
+	for _, a := range answers {
+		if a.Err != nil {
+			continue
+		}
+		ok = append(ok, a)
+		if _, seen := byIP[a.IP]; !seen {
+			ips = append(ips, a.IP)
+		}
+		byIP[a.IP] = append(byIP[a.IP], a.Provider)
+	}
+	if len(ok) == 0 {
+		return r
+	}
+
+	// Most votes wins; ties go to the provider queried first.
+	sort.SliceStable(ips, func(i, j int) bool { return len(byIP[ips[i]]) > len(byIP[ips[j]]) })
+	ip := ips[0]
+	r.Agreeing = byIP[ip]
+
+	switch {
+	case len(ips) > 1:
+		r.Status = StatusDisputed
+	case len(r.Agreeing) >= quorum:
+		r.Status = StatusAgreed
+	default:
+		r.Status = StatusInsufficient
+	}
+
+	// Geo fields only from providers that saw the chosen IP.
+	var same []Answer
+	for _, a := range ok {
+		if a.IP == ip {
+			same = append(same, a)
+		}
+	}
+	r.Geo = Answer{
+		IP:          ip,
+		Country:     majority(same, func(a Answer) string { return a.Country }),
+		CountryCode: majority(same, func(a Answer) string { return a.CountryCode }),
+		Region:      majority(same, func(a Answer) string { return a.Region }),
+		City:        majority(same, func(a Answer) string { return a.City }),
+		ISP:         majority(same, func(a Answer) string { return a.ISP }),
+		ASN:         majority(same, func(a Answer) string { return a.ASN }),
+		Timezone:    majority(same, func(a Answer) string { return a.Timezone }),
+	}
+
+	// City and ISP names are spelled differently by every database, so only
+	// fields that identify the network or country are compared.
+	for _, f := range []struct {
+		name string
+		get  func(Answer) string
+	}{
+		{"ip", func(a Answer) string { return a.IP }},
+		{"asn", func(a Answer) string { return a.ASN }},
+		{"country_code", func(a Answer) string { return a.CountryCode }},
+	} {
+		if d, differ := disagreement(ok, f.name, f.get); differ {
+			r.Disagreements = append(r.Disagreements, d)
+		}
+	}
+	return r
+}
+
+// majority returns the most common non-empty value, ties going to the first.
+func majority(answers []Answer, get func(Answer) string) string {
+	count := map[string]int{}
+	best := ""
+	for _, a := range answers {
+		v := get(a)
+		if v == "" {
+			continue
+		}
+		count[v]++
+		if count[v] > count[best] {
+			best = v
+		}
+	}
+	return best
+}
+
+func disagreement(answers []Answer, field string, get func(Answer) string) (Disagreement, bool) {
+	d := Disagreement{Field: field, Values: map[string]string{}}
+	distinct := map[string]bool{}
+	for _, a := range answers {
+		if v := get(a); v != "" {
+			d.Values[a.Provider] = v
+			distinct[v] = true
+		}
+	}
+	return d, len(distinct) > 1
+}
@@ -0,0 +1,160 @@
+// File: internal/geo/cymru.go (complete file)
+
+package geo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Origin is the routing origin of an IP: the announcing ASN and prefix with
+// the registry that allocated it.
+type Origin struct {
+	IP          string
+	ASN         string // "AS64500"; the first one when several originate the prefix
+	ASName      string
+	Prefix      string
+	CountryCode string
+	Registry    string // arin, ripencc, apnic, lacnic, afrinic
+}
+
+// Cymru resolves origins from Team Cymru's IP-to-ASN DNS zones:
+//
+//	4.3.2.1.origin.asn.cymru.com TXT "64500 | 1.2.3.0/24 | NL | ripencc | 2010-01-01"
+//	AS64500.asn.cymru.com        TXT "64500 | NL | ripencc | 2010-01-01 | EXAMPLE-AS, NL"
+//
+// Answers, including "not announced", are cached for TTL.
+type Cymru struct {
+	// Resolver sends the TXT queries; nil uses net.DefaultResolver.
+	Resolver *net.Resolver
+	// Zone4, Zone6 and ASZone override the Team Cymru zones.
+	Zone4, Zone6, ASZone string
+	TTL                  time.Duration
+
+	mu    sync.Mutex
+	cache map[string]cymruEntry
+	now   func() time.Time
+}
+
+type cymruEntry struct {
+	origin  Origin
+	err     error
+	expires time.Time
+}
+
+// ErrNotAnnounced is returned for IPs no ASN originates (private, reserved
+// or unrouted space).
+var ErrNotAnnounced = errors.New("not announced")
+
+// NewCymru returns a resolver for the public Team Cymru zones caching
+// answers for an hour.
+func NewCymru(r *net.Resolver) *Cymru {
+	return &Cymru{
+		Resolver: r,
+		Zone4:    "origin.asn.cymru.com",
+		Zone6:    "origin6.asn.cymru.com",
+		ASZone:   "asn.cymru.com",
+		TTL:      time.Hour,
+	}
+}
+
+// Lookup returns the origin of ip.
+func (c *Cymru) Lookup(ctx context.Context, ip string) (Origin, error) {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return Origin{}, fmt.Errorf("invalid ip %q", ip)
+	}
+	key := parsed.String()
+
+	c.mu.Lock()
+	if c.now == nil {
+		c.now = time.Now
+	}
+	e, ok := c.cache[key]
+	c.mu.Unlock()
+	if ok && c.now().Before(e.expires) {
+		return e.origin, e.err
+	}
+
+	o, err := c.lookup(ctx, parsed)
+	if err != nil && !errors.Is(err, ErrNotAnnounced) {
+		// Timeouts and server failures say nothing about the IP.
+		return o, err
+	}
+
+	c.mu.Lock()
+	if c.cache == nil {
+		c.cache = map[string]cymruEntry{}
+	}
+	c.cache[key] = cymruEntry{origin: o, err: err, expires: c.now().Add(c.TTL)}
+	c.mu.Unlock()
+	return o, err
+}
+
+func (c *Cymru) lookup(ctx context.Context, ip net.IP) (Origin, error) {
+	o := Origin{IP: ip.String()}
+	fields, err := c.txt(ctx, originName(ip, c.Zone4, c.Zone6))
+	if err != nil {
+		return o, err
+	}
+	if len(fields) < 4 {
+		return o, fmt.Errorf("malformed origin answer %q", strings.Join(fields, " | "))
+	}
+	if asns := strings.Fields(fields[0]); len(asns) > 0 {
+		o.ASN = normalizeASN(asns[0])
+	}
+	if o.ASN == "" {
+		return o, fmt.Errorf("malformed origin ASN %q", fields[0])
+	}
+	o.Prefix, o.CountryCode, o.Registry = fields[1], fields[2], fields[3]
+
+	// The AS name is a bonus; its absence does not fail the lookup.
+	if names, err := c.txt(ctx, o.ASN+"."+c.ASZone); err == nil && len(names) >= 5 {
+		o.ASName = names[4]
+	}
+	return o, nil
+}
+
+// txt returns the "|"-separated fields of the first TXT record of name.
+func (c *Cymru) txt(ctx context.Context, name string) ([]string, error) {
+	r := c.Resolver
+	if r == nil {
+		r = net.DefaultResolver
+	}
+	recs, err := r.LookupTXT(ctx, name)
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return nil, ErrNotAnnounced
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(recs) == 0 || strings.TrimSpace(recs[0]) == "" {
+		return nil, ErrNotAnnounced
+	}
+	parts := strings.Split(recs[0], "|")
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+	}
+	return parts, nil
+}
+
+// originName builds the reverse query name: octets for IPv4, nibbles for
+// IPv6, most specific first.
+func originName(ip net.IP, zone4, zone6 string) string {
+	if v4 := ip.To4(); v4 != nil {
+		return fmt.Sprintf("%d.%d.%d.%d.%s", v4[3], v4[2], v4[1], v4[0], zone4)
+	}
+	const hex = "0123456789abcdef"
+	v6 := ip.To16()
+	b := make([]byte, 0, 64+len(zone6))
+	for i := len(v6) - 1; i >= 0; i-- {
+		b = append(b, hex[v6[i]&0x0F], '.', hex[v6[i]>>4], '.')
+	}
+	return string(append(b, zone6...))
+}
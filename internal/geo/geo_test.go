@@ -0,0 +1,338 @@
+package geo
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestQuery_AdaptersAndConsensus(t *testing.T) {
+	bodies := map[string]string{
+		"/ident":    `{"ip":"198.51.100.7","asn":64500,"aso":"Example VPN","cc":"nl","country":"Netherlands","city":"Amsterdam","tz":"Europe/Amsterdam"}`,
+		"/ipinfo":   `{"ip":"198.51.100.7","city":"Amsterdam","region":"North Holland","country":"NL","org":"AS64500 Example VPN B.V.","timezone":"Europe/Amsterdam"}`,
+		"/ipapi":    `{"status":"success","query":"198.51.100.7","country":"Netherlands","countryCode":"NL","city":"Amsterdam","isp":"Example VPN","as":"AS64500 Example VPN","timezone":"Europe/Amsterdam"}`,
+		"/ifconfig": `{"ip":"198.51.100.7","country":"Netherlands","country_iso":"NL","city":"Amsterdam","asn":"AS64500","asn_org":"Example VPN"}`,
+		// A stale answer from before the exit moved.
+		"/stale": `{"ip":"203.0.113.9","country_iso":"DE","asn":"AS64501"}`,
+		"/fail":  `{"status":"fail","message":"reserved range"}`,
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Cache-Control") != "no-cache" {
+			t.Errorf("%s: missing Cache-Control: no-cache", r.URL.Path)
+		}
+		_, _ = w.Write([]byte(bodies[r.URL.Path]))
+	}))
+	defer srv.Close()
+
+	p := func(name string, style Style) Provider {
+		return Provider{Name: name, Style: style, URL: srv.URL + "/" + name}
+	}
+	all := []Provider{p("ident", StyleIdent), p("ipinfo", StyleIPInfo), p("ipapi", StyleIPAPI), p("ifconfig", StyleIfConfig)}
+	ctx := context.Background()
+
+	res := Query(ctx, srv.Client(), "ipv4", all, 3)
+	if res.Status != StatusAgreed || len(res.Agreeing) != 4 || res.Err() != nil {
+		t.Fatalf("expected agreement: %+v", res)
+	}
+	g := res.Geo
+	if g.IP != "198.51.100.7" || g.ASN != "AS64500" || g.CountryCode != "NL" || g.City != "Amsterdam" || g.ISP != "Example VPN" {
+		t.Fatalf("unexpected merged geo: %+v", g)
+	}
+	if len(res.Disagreements) != 0 {
+		t.Fatalf("unexpected disagreements: %+v", res.Disagreements)
+	}
+
+	// One stale provider is outvoted but recorded.
+	res = Query(ctx, srv.Client(), "ipv4", []Provider{p("ident", StyleIdent), p("stale", StyleIfConfig), p("ifconfig", StyleIfConfig)}, 2)
+	if res.Status != StatusDisputed || res.Geo.IP != "198.51.100.7" {
+		t.Fatalf("expected a dispute won by the majority: %+v", res)
+	}
+	if len(res.Disagreements) != 3 || res.Disagreements[0].Field != "ip" || res.Disagreements[0].Values["stale"] != "203.0.113.9" {
+		t.Fatalf("unexpected disagreements: %+v", res.Disagreements)
+	}
+
+	// A single answer is not a quorum.
+	res = Query(ctx, srv.Client(), "ipv4", []Provider{p("stale", StyleIfConfig), p("fail", StyleIPAPI)}, 2)
+	if res.Status != StatusInsufficient || res.Answers[1].Err == nil {
+		t.Fatalf("expected insufficient: %+v", res)
+	}
+
+	// Wrong family and no answers at all.
+	res = Query(ctx, srv.Client(), "ipv6", []Provider{p("ident", StyleIdent)}, 1)
+	if res.Status != StatusFailed || res.Err() == nil {
+		t.Fatalf("expected failure for an ipv4 answer over ipv6: %+v", res)
+	}
+
+	// IPv4-only providers are not asked for the IPv6 exit.
+	v4only := p("ipapi", StyleIPAPI)
+	v4only.IPv4Only = true
+	res = Query(ctx, srv.Client(), "ipv6", []Provider{v4only}, 1)
+	if res.Status != StatusFailed || len(res.Answers) != 0 {
+		t.Fatalf("expected no ipv6 answers from an ipv4-only provider: %+v", res)
+	}
+}
+
+func TestSplitASOrg(t *testing.T) {
+	for in, want := range map[string][2]string{
+		"AS64500 Example VPN": {"AS64500", "Example VPN"},
+		"as64500":             {"AS64500", ""},
+		"Example Networks":    {"", "Example Networks"},
+	} {
+		asn, org := splitASOrg(in)
+		if asn != want[0] || org != want[1] {
+			t.Errorf("splitASOrg(%q) = %q, %q", in, asn, org)
+		}
+	}
+}
+
+// mmdbWriter builds a small IPv6 MaxMind DB with 24-bit records.
+type mmdbWriter struct {
+	nodes [][2]int // child node index, or -1 empty, or -(2+data offset)
+	data  []byte
+}
+
+func (w *mmdbWriter) node() int {
+	w.nodes = append(w.nodes, [2]int{-1, -1})
+	return len(w.nodes) - 1
+}
+
+func (w *mmdbWriter) insert(cidr string, dataOff int) {
+	_, n, _ := net.ParseCIDR(cidr)
+	ones, _ := n.Mask.Size()
+	ip := n.IP.To16()
+	if v4 := n.IP.To4(); v4 != nil {
+		// IPv4 lives under ::/96 in an IPv6 tree.
+		ip = append(make(net.IP, 12), v4...)
+		ones += 96
+	}
+	if len(w.nodes) == 0 {
+		w.node()
+	}
+	cur := 0
+	for i := 0; i < ones; i++ {
+		bit := int(ip[i/8]>>(7-uint(i%8))) & 1
+		if i == ones-1 {
+			w.nodes[cur][bit] = -(2 + dataOff)
+			return
+		}
+		if w.nodes[cur][bit] < 0 {
+			w.nodes[cur][bit] = w.node()
+		}
+		cur = w.nodes[cur][bit]
+	}
+}
+
+func mmdbCtrl(typ, size int) []byte {
+	var b []byte
+	if typ > 7 {
+		b = []byte{byte(size), byte(typ - 7)}
+	} else {
+		b = []byte{byte(typ<<5 | size)}
+	}
+	if size >= 29 { // sizes up to 284 only
+		b[0] = b[0]&^0x1F | 29
+		b = append(b, byte(size-29))
+	}
+	return b
+}
+
+func mmdbStr(s string) []byte { return append(mmdbCtrl(mmdbString, len(s)), s...) }
+
+func mmdbUint(typ int, v uint64, n int) []byte {
+	b := mmdbCtrl(typ, n)
+	for i := n - 1; i >= 0; i-- {
+		b = append(b, byte(v>>(8*uint(i))))
+	}
+	return b
+}
+
+// encMap encodes key/value pairs; values are already encoded.
+func encMap(kv ...any) []byte {
+	b := mmdbCtrl(mmdbMap, len(kv)/2)
+	for i := 0; i < len(kv); i += 2 {
+		b = append(b, mmdbStr(kv[i].(string))...)
+		b = append(b, kv[i+1].([]byte)...)
+	}
+	return b
+}
+
+func (w *mmdbWriter) bytes(meta []byte) []byte {
+	count := len(w.nodes)
+	var out []byte
+	for _, n := range w.nodes {
+		for _, r := range n {
+			v := count // empty
+			if r >= 0 {
+				v = r
+			} else if r <= -2 {
+				v = count + 16 + (-r - 2)
+			}
+			out = append(out, byte(v>>16), byte(v>>8), byte(v))
+		}
+	}
+	out = append(out, make([]byte, 16)...)
+	out = append(out, w.data...)
+	out = append(out, mmdbMetadataMarker...)
+	return append(out, meta...)
+}
+
+func TestMMDB_LookupCityAndASN(t *testing.T) {
+	w := &mmdbWriter{}
+
+	// A City record, then an ASN record whose organization is a pointer
+	// to a string stored before it.
+	nl := mmdbStr("Netherlands")
+	city := encMap(
+		"city", encMap("names", encMap("en", mmdbStr("Amsterdam"))),
+		"country", encMap("iso_code", mmdbStr("NL"), "names", encMap("en", nl)),
+		"location", encMap("time_zone", mmdbStr("Europe/Amsterdam")),
+	)
+	w.data = append(w.data, city...)
+	orgOff := len(w.data)
+	w.data = append(w.data, mmdbStr("Example ISP")...)
+	asnOff := len(w.data)
+	w.data = append(w.data, mmdbCtrl(mmdbMap, 2)...)
+	w.data = append(w.data, mmdbStr("autonomous_system_number")...)
+	w.data = append(w.data, mmdbUint(mmdbUint32, 64501, 3)...)
+	w.data = append(w.data, mmdbStr("autonomous_system_organization")...)
+	w.data = append(w.data, byte(mmdbPointer<<5|orgOff>>8), byte(orgOff))
+
+	w.insert("198.51.100.0/24", 0)
+	w.insert("2001:db8::/32", asnOff)
+
+	meta := encMap(
+		"node_count", mmdbUint(mmdbUint32, uint64(len(w.nodes)), 4),
+		"record_size", mmdbUint(mmdbUint16, 24, 1),
+		"ip_version", mmdbUint(mmdbUint16, 6, 1),
+		"database_type", mmdbStr("Test-City"),
+		"build_epoch", mmdbUint(mmdbUint64, 1700000000, 4),
+	)
+	db, err := NewMMDB(w.bytes(meta))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if db.Metadata.DatabaseType != "Test-City" || db.Metadata.BuildEpoch != 1700000000 {
+		t.Fatalf("unexpected metadata: %+v", db.Metadata)
+	}
+
+	o := &Offline{dbs: []*MMDB{db}, names: []string{"test.mmdb"}}
+	a, err := o.Lookup("198.51.100.53")
+	if err != nil || a.CountryCode != "NL" || a.Country != "Netherlands" || a.City != "Amsterdam" || a.Timezone != "Europe/Amsterdam" || a.Provider != "test.mmdb" {
+		t.Fatalf("v4 lookup: %+v %v", a, err)
+	}
+	a, err = o.Lookup("2001:db8::53")
+	if err != nil || a.ASN != "AS64501" || a.ISP != "Example ISP" {
+		t.Fatalf("v6 lookup: %+v %v", a, err)
+	}
+	a, err = o.Lookup("203.0.113.1")
+	if err != nil || a.Provider != "" || a.ASN != "" {
+		t.Fatalf("unknown network should be empty: %+v %v", a, err)
+	}
+}
+
+// txtServer is a DNS stand-in answering TXT queries from records (names
+// without the trailing dot) and NXDOMAIN for anything else.
+type txtServer struct {
+	records map[string]string
+	mu      sync.Mutex
+	queries int
+}
+
+func (s *txtServer) resolver(t *testing.T) *net.Resolver {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { pc.Close() })
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, addr, err := pc.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if resp := s.answer(buf[:n]); resp != nil {
+				_, _ = pc.WriteTo(resp, addr)
+			}
+		}
+	}()
+	return &net.Resolver{PreferGo: true, Dial: func(ctx context.Context, _, _ string) (net.Conn, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, "udp", pc.LocalAddr().String())
+	}}
+}
+
+func (s *txtServer) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.queries
+}
+
+func (s *txtServer) answer(q []byte) []byte {
+	if len(q) < 12 {
+		return nil
+	}
+	var labels []string
+	i := 12
+	for i < len(q) && q[i] != 0 {
+		l := int(q[i])
+		if i+1+l > len(q) {
+			return nil
+		}
+		labels = append(labels, string(q[i+1:i+1+l]))
+		i += 1 + l
+	}
+	end := i + 5 // zero label, qtype, qclass
+	if end > len(q) {
+		return nil
+	}
+	s.mu.Lock()
+	s.queries++
+	s.mu.Unlock()
+
+	txt, ok := s.records[strings.Join(labels, ".")]
+	resp := append([]byte{q[0], q[1], 0x81, 0x80, 0, 1, 0, 0, 0, 0, 0, 0}, q[12:end]...)
+	if !ok {
+		resp[3] |= 3 // NXDOMAIN
+		return resp
+	}
+	resp[7] = 1
+	rdata := append([]byte{byte(len(txt))}, txt...)
+	resp = append(resp, 0xC0, 12, 0, 16, 0, 1, 0, 0, 0, 60, byte(len(rdata)>>8), byte(len(rdata)))
+	return append(resp, rdata...)
+}
+
+func TestCymru_OriginLookupsAndCache(t *testing.T) {
+	srv := &txtServer{records: map[string]string{
+		"53.100.51.198.origin.asn.cymru.com": "64500 64501 | 198.51.100.0/24 | NL | ripencc | 2010-01-01",
+		"AS64500.asn.cymru.com":              "64500 | NL | ripencc | 2010-01-01 | EXAMPLE-VPN, NL",
+		"1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.origin6.asn.cymru.com": "64502 | 2001:db8::/32 | DE | ripencc | 2012-01-01",
+	}}
+	c := NewCymru(srv.resolver(t))
+	ctx := context.Background()
+
+	o, err := c.Lookup(ctx, "198.51.100.53")
+	if err != nil || o.ASN != "AS64500" || o.Prefix != "198.51.100.0/24" || o.Registry != "ripencc" || o.ASName != "EXAMPLE-VPN, NL" {
+		t.Fatalf("v4 origin: %+v %v", o, err)
+	}
+	o, err = c.Lookup(ctx, "2001:db8::1")
+	if err != nil || o.ASN != "AS64502" || o.Prefix != "2001:db8::/32" || o.CountryCode != "DE" || o.ASName != "" {
+		t.Fatalf("v6 origin: %+v %v", o, err)
+	}
+	if _, err := c.Lookup(ctx, "10.0.0.1"); !errors.Is(err, ErrNotAnnounced) {
+		t.Fatalf("private space should not be announced: %v", err)
+	}
+
+	before := srv.count()
+	for _, ip := range []string{"198.51.100.53", "2001:db8::1", "10.0.0.1"} {
+		_, _ = c.Lookup(ctx, ip)
+	}
+	if n := srv.count() - before; n != 0 {
+		t.Fatalf("cached lookups sent %d queries", n)
+	}
+}
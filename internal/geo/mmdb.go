@@ -0,0 +1,335 @@
+// File: internal/geo/mmdb.go (complete file)
+
+package geo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"os"
+)
+
+// MMDB reads a MaxMind DB file (GeoIP2/GeoLite2, DB-IP and compatible).
+// The whole file is held in memory; lookups need no network.
+type MMDB struct {
+	Metadata MMDBMetadata
+
+	buf        []byte
+	data       []byte // data section
+	nodeCount  uint
+	recordSize uint
+	ipv4Start  uint // node reached after the 96 zero bits of ::/96
+}
+
+// MMDBMetadata is the part of the database metadata the reader uses.
+type MMDBMetadata struct {
+	DatabaseType string
+	IPVersion    uint
+	NodeCount    uint
+	RecordSize   uint
+	BuildEpoch   uint64
+}
+
+var mmdbMetadataMarker = []byte("\xAB\xCD\xEFMaxMind.com")
+
+// OpenMMDB loads a database file.
+func OpenMMDB(path string) (*MMDB, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	db, err := NewMMDB(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return db, nil
+}
+
+// NewMMDB parses a database held in b.
+func NewMMDB(b []byte) (*MMDB, error) {
+	i := bytes.LastIndex(b, mmdbMetadataMarker)
+	if i < 0 {
+		return nil, errors.New("not a MaxMind DB file (metadata marker missing)")
+	}
+	metaStart := i + len(mmdbMetadataMarker)
+	raw, _, err := (&mmdbDecoder{buf: b[metaStart:]}).decode(0)
+	if err != nil {
+		return nil, fmt.Errorf("metadata: %w", err)
+	}
+	meta, ok := raw.(map[string]any)
+	if !ok {
+		return nil, errors.New("metadata is not a map")
+	}
+
+	db := &MMDB{buf: b}
+	db.Metadata = MMDBMetadata{
+		DatabaseType: asString(meta["database_type"]),
+		IPVersion:    uint(asUint(meta["ip_version"])),
+		NodeCount:    uint(asUint(meta["node_count"])),
+		RecordSize:   uint(asUint(meta["record_size"])),
+		BuildEpoch:   asUint(meta["build_epoch"]),
+	}
+	db.nodeCount, db.recordSize = db.Metadata.NodeCount, db.Metadata.RecordSize
+	switch db.recordSize {
+	case 24, 28, 32:
+	default:
+		return nil, fmt.Errorf("unsupported record size %d", db.recordSize)
+	}
+
+	treeSize := db.nodeCount * db.recordSize / 4
+	dataStart := treeSize + 16 // 16 zero bytes separate tree and data
+	if dataStart > uint(i) {
+		return nil, errors.New("search tree larger than file")
+	}
+	db.data = b[dataStart:i]
+
+	if db.Metadata.IPVersion == 6 {
+		node := uint(0)
+		for n := 0; n < 96 && node < db.nodeCount; n++ {
+			node = db.record(node, 0)
+		}
+		db.ipv4Start = node
+	}
+	return db, nil
+}
+
+// Lookup returns the record for ip, or nil when the database has none.
+func (db *MMDB) Lookup(ip net.IP) (map[string]any, error) {
+	bits, node := ip.To16(), uint(0)
+	if ip4 := ip.To4(); ip4 != nil {
+		bits, node = ip4, db.ipv4Start
+	} else if bits == nil {
+		return nil, fmt.Errorf("invalid ip %v", ip)
+	} else if db.Metadata.IPVersion == 4 {
+		return nil, nil
+	}
+
+	for i := 0; i < len(bits)*8 && node < db.nodeCount; i++ {
+		bit := uint(bits[i/8]>>(7-uint(i%8))) & 1
+		node = db.record(node, bit)
+	}
+	switch {
+	case node == db.nodeCount:
+		return nil, nil // no data for this network
+	case node < db.nodeCount:
+		return nil, errors.New("search tree ended inside a node")
+	}
+
+	off := node - db.nodeCount - 16
+	if off >= uint(len(db.data)) {
+		return nil, errors.New("data pointer out of range")
+	}
+	v, _, err := (&mmdbDecoder{buf: db.data}).decode(off)
+	if err != nil {
+		return nil, err
+	}
+	m, _ := v.(map[string]any)
+	return m, nil
+}
+
+// record reads the left (bit 0) or right (bit 1) record of a node.
+func (db *MMDB) record(node, bit uint) uint {
+	b := db.buf[node*db.recordSize/4:]
+	switch db.recordSize {
+	case 24:
+		b = b[bit*3:]
+		return uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+	case 28:
+		if bit == 0 {
+			return uint(b[3]&0xF0)<<20 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+		}
+		return uint(b[3]&0x0F)<<24 | uint(b[4])<<16 | uint(b[5])<<8 | uint(b[6])
+	default:
+		return uint(binary.BigEndian.Uint32(b[bit*4:]))
+	}
+}
+
+// mmdbDecoder decodes the MaxMind DB data section format.
+type mmdbDecoder struct {
+	buf []byte
+}
+
+const (
+	mmdbPointer   = 1
+	mmdbString    = 2
+	mmdbDouble    = 3
+	mmdbBytes     = 4
+	mmdbUint16    = 5
+	mmdbUint32    = 6
+	mmdbMap       = 7
+	mmdbInt32     = 8
+	mmdbUint64    = 9
+	mmdbUint128   = 10
+	mmdbArray     = 11
+	mmdbContainer = 12
+	mmdbEnd       = 13
+	mmdbBool      = 14
+	mmdbFloat     = 15
+)
+
+// decode returns the value at off and the offset just past it. Pointers are
+// followed; the returned offset is then past the pointer itself.
+func (d *mmdbDecoder) decode(off uint) (any, uint, error) {
+	ctrl, err := d.byte(off)
+	if err != nil {
+		return nil, 0, err
+	}
+	off++
+	typ := uint(ctrl >> 5)
+
+	if typ == mmdbPointer {
+		ptr, next, err := d.pointer(ctrl, off)
+		if err != nil {
+			return nil, 0, err
+		}
+		v, _, err := d.decode(ptr)
+		return v, next, err
+	}
+
+	if typ == 0 {
+		ext, err := d.byte(off)
+		if err != nil {
+			return nil, 0, err
+		}
+		typ = 7 + uint(ext)
+		off++
+	}
+
+	size := uint(ctrl & 0x1F)
+	if size >= 29 {
+		n := size - 28 // 1, 2 or 3 extra bytes
+		b, err := d.slice(off, n)
+		if err != nil {
+			return nil, 0, err
+		}
+		off += n
+		extra := uint(0)
+		for _, x := range b {
+			extra = extra<<8 | uint(x)
+		}
+		size = []uint{29, 285, 65821}[n-1] + extra
+	}
+
+	switch typ {
+	case mmdbMap:
+		m := make(map[string]any, size)
+		for i := uint(0); i < size; i++ {
+			k, next, err := d.decode(off)
+			if err != nil {
+				return nil, 0, err
+			}
+			key, ok := k.(string)
+			if !ok {
+				return nil, 0, errors.New("map key is not a string")
+			}
+			v, next, err := d.decode(next)
+			if err != nil {
+				return nil, 0, err
+			}
+			m[key] = v
+			off = next
+		}
+		return m, off, nil
+	case mmdbArray:
+		a := make([]any, 0, size)
+		for i := uint(0); i < size; i++ {
+			v, next, err := d.decode(off)
+			if err != nil {
+				return nil, 0, err
+			}
+			a = append(a, v)
+			off = next
+		}
+		return a, off, nil
+	case mmdbBool:
+		return size != 0, off, nil
+	case mmdbEnd, mmdbContainer:
+		return nil, off, nil
+	}
+
+	b, err := d.slice(off, size)
+	if err != nil {
+		return nil, 0, err
+	}
+	off += size
+	switch typ {
+	case mmdbString:
+		return string(b), off, nil
+	case mmdbBytes, mmdbUint128:
+		return append([]byte(nil), b...), off, nil
+	case mmdbDouble:
+		if size != 8 {
+			return nil, 0, errors.New("bad double size")
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), off, nil
+	case mmdbFloat:
+		if size != 4 {
+			return nil, 0, errors.New("bad float size")
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), off, nil
+	case mmdbUint16, mmdbUint32, mmdbUint64:
+		var n uint64
+		for _, x := range b {
+			n = n<<8 | uint64(x)
+		}
+		return n, off, nil
+	case mmdbInt32:
+		var n uint32
+		for _, x := range b {
+			n = n<<8 | uint32(x)
+		}
+		return int64(int32(n)), off, nil
+	}
+	return nil, 0, fmt.Errorf("unknown data type %d", typ)
+}
+
+func (d *mmdbDecoder) pointer(ctrl byte, off uint) (uint, uint, error) {
+	n := uint(ctrl>>3)&0x3 + 1
+	b, err := d.slice(off, n)
+	if err != nil {
+		return 0, 0, err
+	}
+	v := uint(ctrl & 0x7)
+	if n == 4 {
+		v = 0
+	}
+	for _, x := range b {
+		v = v<<8 | uint(x)
+	}
+	v += []uint{0, 2048, 526336, 0}[n-1]
+	return v, off + n, nil
+}
+
+func (d *mmdbDecoder) byte(off uint) (byte, error) {
+	if off >= uint(len(d.buf)) {
+		return 0, errors.New("unexpected end of data")
+	}
+	return d.buf[off], nil
+}
+
+func (d *mmdbDecoder) slice(off, n uint) ([]byte, error) {
+	if off+n > uint(len(d.buf)) {
+		return nil, errors.New("unexpected end of data")
+	}
+	return d.buf[off : off+n], nil
+}
+
+func asString(v any) string {
+	s, _ := v.(string)
+	return s
+}
+
+func asUint(v any) uint64 {
+	switch n := v.(type) {
+	case uint64:
+		return n
+	case int64:
+		if n >= 0 {
+			return uint64(n)
+		}
+	}
+	return 0
+}
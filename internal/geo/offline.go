@@ -0,0 +1,127 @@
+// File: internal/geo/offline.go (complete file)
+
+package geo
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultMMDBDirs are where geoipupdate and distribution packages install
+// databases.
+var DefaultMMDBDirs = []string{"/var/lib/GeoIP", "/usr/share/GeoIP", "/usr/local/share/GeoIP"}
+
+// Offline answers geo/ASN lookups from local MMDB files, e.g. a
+// GeoLite2-City database for location and GeoLite2-ASN for the network.
+type Offline struct {
+	dbs   []*MMDB
+	names []string
+}
+
+// OpenOffline opens every database in paths.
+func OpenOffline(paths []string) (*Offline, error) {
+	o := &Offline{}
+	for _, p := range paths {
+		db, err := OpenMMDB(p)
+		if err != nil {
+			return nil, err
+		}
+		o.dbs = append(o.dbs, db)
+		o.names = append(o.names, filepath.Base(p))
+	}
+	if len(o.dbs) == 0 {
+		return nil, errors.New("no MMDB databases given")
+	}
+	return o, nil
+}
+
+// FindMMDB lists the *.mmdb files in DefaultMMDBDirs.
+func FindMMDB() []string {
+	var out []string
+	for _, dir := range DefaultMMDBDirs {
+		matches, _ := filepath.Glob(filepath.Join(dir, "*.mmdb"))
+		for _, m := range matches {
+			if st, err := os.Stat(m); err == nil && st.Mode().IsRegular() {
+				out = append(out, m)
+			}
+		}
+	}
+	return out
+}
+
+// Names returns the file names of the open databases.
+func (o *Offline) Names() []string {
+	return o.names
+}
+
+// Lookup merges what every database knows about ip; earlier databases win
+// per field. Provider names the databases that had a record. An IP none of
+// them knows returns an Answer with only IP set.
+func (o *Offline) Lookup(ip string) (Answer, error) {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return Answer{}, fmt.Errorf("invalid ip %q", ip)
+	}
+	a := Answer{IP: parsed.String()}
+	var found []string
+	for i, db := range o.dbs {
+		rec, err := db.Lookup(parsed)
+		if err != nil {
+			return Answer{}, fmt.Errorf("%s: %w", o.names[i], err)
+		}
+		if rec == nil {
+			continue
+		}
+		found = append(found, o.names[i])
+		fillFromRecord(&a, rec)
+	}
+	a.Provider = strings.Join(found, ",")
+	return a, nil
+}
+
+// fillFromRecord reads the GeoIP2/GeoLite2 layout (City, Country and ASN
+// databases; DB-IP uses the same one) into the empty fields of a.
+func fillFromRecord(a *Answer, rec map[string]any) {
+	set := func(dst *string, v string) {
+		if *dst == "" {
+			*dst = strings.TrimSpace(v)
+		}
+	}
+	country := path(rec, "country")
+	if country == nil {
+		country = path(rec, "registered_country")
+	}
+	set(&a.CountryCode, asString(path(country, "iso_code")))
+	set(&a.Country, englishName(country))
+	set(&a.City, englishName(path(rec, "city")))
+	if subs, ok := path(rec, "subdivisions").([]any); ok && len(subs) > 0 {
+		set(&a.Region, englishName(subs[0]))
+	}
+	set(&a.Timezone, asString(path(rec, "location", "time_zone")))
+
+	if n := asUint(rec["autonomous_system_number"]); n != 0 {
+		set(&a.ASN, fmt.Sprintf("AS%d", n))
+	}
+	set(&a.ISP, asString(rec["autonomous_system_organization"]))
+	set(&a.ISP, asString(rec["isp"]))
+	set(&a.ISP, asString(rec["organization"]))
+}
+
+func path(v any, keys ...string) any {
+	for _, k := range keys {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		v = m[k]
+	}
+	return v
+}
+
+func englishName(v any) string {
+	return asString(path(v, "names", "en"))
+}
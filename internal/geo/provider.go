@@ -0,0 +1,289 @@
+// File: internal/geo/provider.go (complete file)
+
+package geo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Style is the response shape of an IP echo/geo API.
+type Style string
+
+const (
+	StyleIdent    Style = "ident"    // ident.me/json
+	StyleIPInfo   Style = "ipinfo"   // ipinfo.io/json
+	StyleIPAPI    Style = "ip-api"   // ip-api.com/json
+	StyleIfConfig Style = "ifconfig" // ifconfig.co/json
+)
+
+// Provider is one echo/geo API. URL is fetched for ipv4 and "any"; URL6,
+// when set, for ipv6. The HTTP client passed to Query pins the family.
+// IPv4Only providers cannot be reached over IPv6 and are left out of ipv6
+// queries.
+type Provider struct {
+	Name     string
+	Style    Style
+	URL      string
+	URL6     string
+	IPv4Only bool
+}
+
+// DefaultProviders are independent operators, so one stale or cached answer
+// is outvoted. ip-api.com only serves plain HTTP, and only over IPv4, on its
+// free tier.
+var DefaultProviders = []Provider{
+	{Name: "ident.me", Style: StyleIdent, URL: "https://4.ident.me/json", URL6: "https://6.ident.me/json"},
+	{Name: "ipinfo.io", Style: StyleIPInfo, URL: "https://ipinfo.io/json", URL6: "https://v6.ipinfo.io/json"},
+	{Name: "ip-api.com", Style: StyleIPAPI, URL: "http://ip-api.com/json/", IPv4Only: true},
+	{Name: "ifconfig.co", Style: StyleIfConfig, URL: "https://ifconfig.co/json"},
+}
+
+// Providers picks DefaultProviders by name; an empty list returns all of them.
+func Providers(names []string) ([]Provider, error) {
+	if len(names) == 0 {
+		return DefaultProviders, nil
+	}
+	var out []Provider
+	for _, n := range names {
+		found := false
+		for _, p := range DefaultProviders {
+			if strings.EqualFold(p.Name, n) {
+				out = append(out, p)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown geo provider %q", n)
+		}
+	}
+	return out, nil
+}
+
+// Answer is what one provider reported for the exit.
+type Answer struct {
+	Provider    string
+	Endpoint    string // host queried
+	IP          string
+	Country     string
+	CountryCode string
+	Region      string
+	City        string
+	ISP         string
+	ASN         string // "AS123"
+	Timezone    string
+	Elapsed     time.Duration
+	Err         error
+}
+
+func (p Provider) endpoint(family string) string {
+	if family == "ipv6" && p.URL6 != "" {
+		return p.URL6
+	}
+	return p.URL
+}
+
+// Fetch queries the provider and normalizes its answer. An IP of the wrong
+// family is an error: the client should not have reached it that way.
+func (p Provider) Fetch(ctx context.Context, client *http.Client, family string) Answer {
+	start := time.Now()
+	target := p.endpoint(family)
+	a := Answer{Provider: p.Name, Endpoint: target}
+	if u, err := url.Parse(target); err == nil {
+		a.Endpoint = u.Host
+	}
+
+	a.Err = p.fetch(ctx, client, target, &a)
+	if a.Err == nil {
+		a.Err = normalize(&a, family)
+	}
+	a.Elapsed = time.Since(start)
+	return a
+}
+
+func (p Provider) fetch(ctx context.Context, client *http.Client, target string, a *Answer) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "vpnleakidentifier/0.1")
+	req.Header.Set("Accept", "application/json")
+	// Ask intermediaries not to answer from a cache.
+	req.Header.Set("Cache-Control", "no-cache")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("http status %d", resp.StatusCode)
+	}
+
+	switch p.Style {
+	case StyleIdent:
+		return parseIdent(body, a)
+	case StyleIPInfo:
+		return parseIPInfo(body, a)
+	case StyleIPAPI:
+		return parseIPAPI(body, a)
+	case StyleIfConfig:
+		return parseIfConfig(body, a)
+	}
+	return fmt.Errorf("unknown provider style %q", p.Style)
+}
+
+// text accepts a JSON string or number; providers disagree on how to encode
+// ASNs.
+type text string
+
+func (t *text) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*t = text(s)
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(b, &n); err != nil {
+		return err
+	}
+	*t = text(n.String())
+	return nil
+}
+
+func parseIdent(body []byte, a *Answer) error {
+	var r struct {
+		IP      string `json:"ip"`
+		ASN     text   `json:"asn"`
+		ASO     string `json:"aso"`
+		CC      string `json:"cc"`
+		Country string `json:"country"`
+		City    string `json:"city"`
+		TZ      string `json:"tz"`
+	}
+	if err := json.Unmarshal(body, &r); err != nil {
+		return err
+	}
+	a.IP, a.ASN, a.ISP = r.IP, string(r.ASN), r.ASO
+	a.CountryCode, a.Country, a.City, a.Timezone = r.CC, r.Country, r.City, r.TZ
+	return nil
+}
+
+func parseIPInfo(body []byte, a *Answer) error {
+	var r struct {
+		IP       string `json:"ip"`
+		City     string `json:"city"`
+		Region   string `json:"region"`
+		Country  string `json:"country"` // ISO code
+		Org      string `json:"org"`     // "AS123 Example"
+		Timezone string `json:"timezone"`
+	}
+	if err := json.Unmarshal(body, &r); err != nil {
+		return err
+	}
+	a.IP, a.City, a.Region, a.CountryCode, a.Timezone = r.IP, r.City, r.Region, r.Country, r.Timezone
+	a.ASN, a.ISP = splitASOrg(r.Org)
+	return nil
+}
+
+func parseIPAPI(body []byte, a *Answer) error {
+	var r struct {
+		Status      string `json:"status"`
+		Message     string `json:"message"`
+		Query       string `json:"query"`
+		Country     string `json:"country"`
+		CountryCode string `json:"countryCode"`
+		RegionName  string `json:"regionName"`
+		City        string `json:"city"`
+		ISP         string `json:"isp"`
+		AS          string `json:"as"` // "AS123 Example"
+		Timezone    string `json:"timezone"`
+	}
+	if err := json.Unmarshal(body, &r); err != nil {
+		return err
+	}
+	if r.Status != "" && r.Status != "success" {
+		return errors.New("ip-api: " + r.Message)
+	}
+	a.IP, a.Country, a.CountryCode, a.Region, a.City, a.ISP, a.Timezone =
+		r.Query, r.Country, r.CountryCode, r.RegionName, r.City, r.ISP, r.Timezone
+	a.ASN, _ = splitASOrg(r.AS)
+	return nil
+}
+
+func parseIfConfig(body []byte, a *Answer) error {
+	var r struct {
+		IP         string `json:"ip"`
+		Country    string `json:"country"`
+		CountryISO string `json:"country_iso"`
+		RegionName string `json:"region_name"`
+		City       string `json:"city"`
+		ASN        string `json:"asn"`
+		ASNOrg     string `json:"asn_org"`
+		TimeZone   string `json:"time_zone"`
+	}
+	if err := json.Unmarshal(body, &r); err != nil {
+		return err
+	}
+	a.IP, a.Country, a.CountryCode, a.Region, a.City, a.ASN, a.ISP, a.Timezone =
+		r.IP, r.Country, r.CountryISO, r.RegionName, r.City, r.ASN, r.ASNOrg, r.TimeZone
+	return nil
+}
+
+// splitASOrg splits "AS123 Example Org" into "AS123" and "Example Org".
+func splitASOrg(s string) (asn, org string) {
+	s = strings.TrimSpace(s)
+	head, rest, _ := strings.Cut(s, " ")
+	if normalizeASN(head) == "" {
+		return "", s
+	}
+	return normalizeASN(head), strings.TrimSpace(rest)
+}
+
+// normalizeASN turns "AS123", "as123" and "123" into "AS123"; anything else
+// is dropped.
+func normalizeASN(s string) string {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	s = strings.TrimPrefix(s, "AS")
+	if s == "" {
+		return ""
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return ""
+		}
+	}
+	return "AS" + s
+}
+
+func normalize(a *Answer, family string) error {
+	ip := net.ParseIP(strings.TrimSpace(a.IP))
+	if ip == nil {
+		return fmt.Errorf("invalid ip %q", a.IP)
+	}
+	is4 := ip.To4() != nil
+	if (family == "ipv4" && !is4) || (family == "ipv6" && is4) {
+		return fmt.Errorf("answered %s over %s", ip, family)
+	}
+	a.IP = ip.String()
+	a.ASN = normalizeASN(a.ASN)
+	a.CountryCode = strings.ToUpper(strings.TrimSpace(a.CountryCode))
+	for _, f := range []*string{&a.Country, &a.Region, &a.City, &a.ISP, &a.Timezone} {
+		*f = strings.TrimSpace(*f)
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+// File: internal/history/diff.go (complete file)
+
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/baptistax/vpn-leak-identifier/internal/report"
+)
+
+// Document is a loaded run.json or snapshot.json.
+type Document struct {
+	Path     string
+	Kind     string // test|snapshot
+	Run      *report.RunReport
+	Snapshot *report.Snapshot
+	raw      map[string]any
+}
+
+// FieldChange is one differing leaf, addressed like "baseline.exit_v4.ip" or
+// "public_ips[1].error". A missing side is reported as nil.
+type FieldChange struct {
+	Path string `json:"path"`
+	From any    `json:"from"`
+	To   any    `json:"to"`
+}
+
+// Open loads a report file, a run directory, or a run ID under exportsDir.
+func Open(exportsDir, ref string) (*Document, error) {
+	path := ref
+	st, err := os.Stat(path)
+	if err != nil {
+		for _, cand := range []string{filepath.Join(exportsDir, ref), filepath.Join(exportsDir, "run_"+ref)} {
+			if cst, cerr := os.Stat(cand); cerr == nil {
+				path, st, err = cand, cst, nil
+				break
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if st.IsDir() {
+		dir := path
+		path = ""
+		for _, name := range []string{"run.json", "snapshot.json"} {
+			if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+				path = filepath.Join(dir, name)
+				break
+			}
+		}
+		if path == "" {
+			return nil, fmt.Errorf("%s: no run.json or snapshot.json found", ref)
+		}
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	rd, err := report.Decode(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	d := &Document{Path: path, Run: rd.Run, Snapshot: rd.Snapshot, Kind: "snapshot"}
+	if rd.Kind == report.KindRun {
+		d.Kind = "test"
+	}
+	// Diff the migrated form so reports of different schema versions
+	// compare field by field.
+	var v any = rd.Run
+	if rd.Snapshot != nil {
+		v = rd.Snapshot
+	}
+	norm, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(norm, &d.raw); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return d, nil
+}
+
+// Diff compares two documents of the same kind field by field. Paths in skip
+// (top-level keys, e.g. the per-second probe timeline) are left out.
+func Diff(a, b *Document, skip ...string) ([]FieldChange, error) {
+	if a.Kind != b.Kind {
+		return nil, errors.New("cannot diff a " + a.Kind + " against a " + b.Kind)
+	}
+	fa, fb := map[string]any{}, map[string]any{}
+	flatten(fa, "", a.raw, skip)
+	flatten(fb, "", b.raw, skip)
+
+	keys := map[string]bool{}
+	for k := range fa {
+		keys[k] = true
+	}
+	for k := range fb {
+		keys[k] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	var out []FieldChange
+	for _, k := range sorted {
+		va, oka := fa[k]
+		vb, okb := fb[k]
+		if oka && okb && fmt.Sprint(va) == fmt.Sprint(vb) {
+			continue
+		}
+		out = append(out, FieldChange{Path: k, From: va, To: vb})
+	}
+	return out, nil
+}
+
+func flatten(out map[string]any, prefix string, v any, skip []string) {
+	switch t := v.(type) {
+	case map[string]any:
+		for k, child := range t {
+			if prefix == "" && contains(skip, k) {
+				continue
+			}
+			p := k
+			if prefix != "" {
+				p = prefix + "." + k
+			}
+			flatten(out, p, child, skip)
+		}
+	case []any:
+		for i, child := range t {
+			flatten(out, prefix+"["+strconv.Itoa(i)+"]", child, skip)
+		}
+	default:
+		out[prefix] = v
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+// ProbeSetSnapshot views a run's probe set as a snapshot, so run and snapshot
+// deltas render the same way.
+func ProbeSetSnapshot(ps report.ProbeSet) report.Snapshot {
+	s := report.Snapshot{
+		TimestampUTC: ps.AtUTC,
+		Observation:  ps.Observation,
+		Notes:        ps.Notes,
+	}
+	for _, e := range []report.ExitInfo{ps.ExitV4, ps.ExitV6} {
+		s.PublicIPs = append(s.PublicIPs, report.PublicIPResult{
+			Source: e.Source,
+			Family: e.Family,
+			IP:     e.IP,
+			Error:  e.Error,
+		})
+	}
+	return s
+}
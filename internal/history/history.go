@@ -0,0 +1,231 @@
+// File: internal/history/history.go (complete file)
+
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/baptistax/vpn-leak-identifier/internal/monitor"
+	"github.com/baptistax/vpn-leak-identifier/internal/report"
+)
+
+// IndexFile is the JSONL index kept at the root of the exports directory.
+const IndexFile = "index.jsonl"
+
+// Entry is one past run, snapshot or monitor session.
+type Entry struct {
+	ID         string    `json:"id"`   // run_<ts> directory name
+	Kind       string    `json:"kind"` // test|snapshot|monitor
+	StartedUTC time.Time `json:"started_utc"`
+	Dir        string    `json:"dir"`
+	File       string    `json:"file"` // run.json, snapshot.json or summary.json
+	Verdict    string    `json:"verdict,omitempty"`
+	ExitIP     string    `json:"exit_ip,omitempty"`
+	ASN        string    `json:"asn,omitempty"`
+	Country    string    `json:"country,omitempty"`
+}
+
+// Record appends an entry to the index under exportsDir.
+func Record(exportsDir string, e Entry) error {
+	if err := os.MkdirAll(exportsDir, 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(exportsDir, IndexFile), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return json.NewEncoder(f).Encode(e)
+}
+
+func FromRun(dir string, r report.RunReport) Entry {
+	e := Entry{
+		ID:         filepath.Base(dir),
+		Kind:       "test",
+		StartedUTC: r.StartedUTC,
+		Dir:        dir,
+		File:       filepath.Join(dir, "run.json"),
+		Verdict:    r.Verdict.Overall,
+	}
+	exit := r.Baseline.ExitV4
+	if exit.IP == "" {
+		exit = r.Baseline.ExitV6
+	}
+	e.ExitIP = exit.IP
+	e.setGeo(exit.Geo)
+	return e
+}
+
+func (e *Entry) setGeo(g report.GeoInfo) {
+	e.ASN = g.ASN
+	e.Country = g.CountryCode
+	if e.Country == "" {
+		e.Country = g.Country
+	}
+}
+
+func FromSnapshot(dir string, s report.Snapshot) Entry {
+	e := Entry{
+		ID:         filepath.Base(dir),
+		Kind:       "snapshot",
+		StartedUTC: s.TimestampUTC,
+		Dir:        dir,
+		File:       filepath.Join(dir, "snapshot.json"),
+		Verdict:    "OFFLINE",
+	}
+	for _, family := range []string{"ipv4", "any", "ipv6"} {
+		for _, r := range s.PublicIPs {
+			if r.Family == family && r.IP != "" && r.Error == "" && e.ExitIP == "" {
+				e.ExitIP = r.IP
+				e.Verdict = "OK"
+			}
+		}
+	}
+	for _, family := range []string{"ipv4", "ipv6"} {
+		if exit := s.Exit(family); exit.IP != "" && exit.IP == e.ExitIP {
+			e.setGeo(exit.Geo)
+		}
+	}
+	if s.DNS != nil && s.DNS.Verdict == report.DNSLeak {
+		e.Verdict = "LEAK"
+	}
+	return e
+}
+
+func FromSummary(dir string, s monitor.Summary) Entry {
+	e := Entry{
+		ID:         filepath.Base(dir),
+		Kind:       "monitor",
+		StartedUTC: s.StartedUTC,
+		Dir:        dir,
+		File:       filepath.Join(dir, "summary.json"),
+		Verdict:    "OK",
+	}
+	if s.Leaks > 0 {
+		e.Verdict = "LEAK"
+	}
+	if len(s.VPNExits) > 0 {
+		e.ExitIP = s.VPNExits[0]
+		e.setGeo(s.ExitGeo[e.ExitIP])
+	}
+	return e
+}
+
+// Load returns all known entries, newest first. Run directories missing from
+// the index (e.g. created before it existed) are scanned and included.
+func Load(exportsDir string) ([]Entry, error) {
+	entries, err := readIndex(filepath.Join(exportsDir, IndexFile))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	known := map[string]bool{}
+	for _, e := range entries {
+		known[e.ID] = true
+	}
+
+	scanned, err := Scan(exportsDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range scanned {
+		if !known[e.ID] {
+			entries = append(entries, e)
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].StartedUTC.After(entries[j].StartedUTC) })
+	return entries, nil
+}
+
+// Rebuild rewrites the index from the run directories on disk.
+func Rebuild(exportsDir string) ([]Entry, error) {
+	entries, err := Scan(exportsDir)
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(exportsDir, IndexFile)
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return nil, err
+	}
+	enc := json.NewEncoder(f)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+	}
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+	return entries, os.Rename(tmp, path)
+}
+
+// Scan derives entries from every run_* directory under exportsDir.
+func Scan(exportsDir string) ([]Entry, error) {
+	dirs, err := filepath.Glob(filepath.Join(exportsDir, "run_*"))
+	if err != nil {
+		return nil, err
+	}
+	var out []Entry
+	for _, dir := range dirs {
+		if e, ok := entryFromDir(dir); ok {
+			out = append(out, e)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].StartedUTC.Before(out[j].StartedUTC) })
+	return out, nil
+}
+
+func entryFromDir(dir string) (Entry, bool) {
+	if d, err := report.Load(filepath.Join(dir, "run.json")); err == nil && d.Run != nil {
+		return FromRun(dir, *d.Run), true
+	}
+	var sum monitor.Summary
+	if readJSON(filepath.Join(dir, "summary.json"), &sum) == nil {
+		return FromSummary(dir, sum), true
+	}
+	if d, err := report.Load(filepath.Join(dir, "snapshot.json")); err == nil && d.Snapshot != nil {
+		return FromSnapshot(dir, *d.Snapshot), true
+	}
+	return Entry{}, false
+}
+
+func readIndex(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var out []Entry
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+		var e Entry
+		// A torn last line (crash mid-append) is skipped, not fatal.
+		if json.Unmarshal([]byte(line), &e) == nil {
+			out = append(out, e)
+		}
+	}
+	return out, sc.Err()
+}
+
+func readJSON(path string, v any) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
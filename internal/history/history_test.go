@@ -0,0 +1,116 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/baptistax/vpn-leak-identifier/internal/monitor"
+	"github.com/baptistax/vpn-leak-identifier/internal/report"
+)
+
+func writeRun(t *testing.T, dir string, r report.RunReport) {
+	t.Helper()
+	if err := report.WriteRunJSON(filepath.Join(dir, "run.json"), r); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoad_IndexAndUnindexedDirs(t *testing.T) {
+	exports := t.TempDir()
+
+	old := report.RunReport{StartedUTC: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), Verdict: report.Verdict{Overall: "PASS"}}
+	old.Baseline.ExitV4 = report.ExitInfo{Family: "ipv4", IP: "198.51.100.1", Geo: report.GeoInfo{ASN: "AS64500", CountryCode: "NL"}}
+	writeRun(t, filepath.Join(exports, "run_20250101_000000"), old)
+
+	snapDir := filepath.Join(exports, "run_20250102_000000")
+	snap := report.Snapshot{
+		TimestampUTC: time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC),
+		PublicIPs:    []report.PublicIPResult{{Source: "ipify", Family: "ipv4", IP: "203.0.113.9"}},
+	}
+	if err := Record(exports, FromSnapshot(snapDir, snap)); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := Load(exports)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %+v", entries)
+	}
+	if entries[0].Kind != "snapshot" || entries[0].ExitIP != "203.0.113.9" {
+		t.Fatalf("newest entry should be the indexed snapshot: %+v", entries[0])
+	}
+	if e := entries[1]; e.Kind != "test" || e.Verdict != "PASS" || e.ASN != "AS64500" || e.Country != "NL" {
+		t.Fatalf("scanned run entry wrong: %+v", e)
+	}
+}
+
+func TestDiff_FieldsAndSkip(t *testing.T) {
+	dir := t.TempDir()
+	started := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	a := report.RunReport{StartedUTC: started, Mode: report.RunModeVPNOnly, Verdict: report.Verdict{Overall: "PASS"}, Probes: []report.ProbeSet{{AtSec: 0}}}
+	b := report.RunReport{StartedUTC: started, Mode: report.RunModeVPNOnly, Verdict: report.Verdict{Overall: "FAIL"}, Probes: []report.ProbeSet{{AtSec: 1}}}
+	writeRun(t, filepath.Join(dir, "a"), a)
+	writeRun(t, filepath.Join(dir, "b"), b)
+
+	da, err := Open(dir, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := Open(dir, filepath.Join(dir, "b", "run.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := Diff(da, db, "probes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || changes[0].Path != "verdict.overall" || changes[0].From != "PASS" || changes[0].To != "FAIL" {
+		t.Fatalf("unexpected changes: %+v", changes)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "s.json"), []byte(`{"timestamp_utc":"2025-01-01T00:00:00Z","public_ips":[]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ds, err := Open(dir, filepath.Join(dir, "s.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Diff(da, ds); err == nil {
+		t.Fatal("expected an error diffing a run against a snapshot")
+	}
+}
+
+func TestFromSnapshotAndSummary_Geo(t *testing.T) {
+	snap := report.Snapshot{
+		TimestampUTC: time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC),
+		PublicIPs: []report.PublicIPResult{
+			{Source: "ipify", Family: "ipv4", IP: "198.51.100.7"},
+			{Source: "ipify", Family: "ipv6", IP: "2001:db8::7"},
+		},
+	}
+	snap.ExitV4 = report.ExitInfo{Family: "ipv4", IP: "198.51.100.7", Geo: report.GeoInfo{ASN: "AS64500", Country: "Netherlands"}}
+	snap.ExitV6 = report.ExitInfo{Family: "ipv6", IP: "2001:db8::7", Geo: report.GeoInfo{ASN: "AS64501", CountryCode: "DE"}}
+	if e := FromSnapshot("run_snap", snap); e.ExitIP != "198.51.100.7" || e.ASN != "AS64500" || e.Country != "Netherlands" {
+		t.Fatalf("snapshot entry = %+v", e)
+	}
+	if e := FromSnapshot("run_snap", snap); e.Verdict != "OK" {
+		t.Fatalf("expected OK without a DNS verdict, got %q", e.Verdict)
+	}
+	snap.DNS = &report.DNSAssessment{Verdict: report.DNSLeak}
+	if e := FromSnapshot("run_snap", snap); e.Verdict != "LEAK" {
+		t.Fatalf("expected a DNS leak to mark the snapshot LEAK, got %q", e.Verdict)
+	}
+
+	sum := monitor.Summary{
+		VPNExits: []string{"2001:db8::7"},
+		ExitGeo:  map[string]report.GeoInfo{"2001:db8::7": {ASN: "AS64501", CountryCode: "DE"}},
+	}
+	if e := FromSummary("run_mon", sum); e.ExitIP != "2001:db8::7" || e.ASN != "AS64501" || e.Country != "DE" {
+		t.Fatalf("summary entry = %+v", e)
+	}
+}
@@ -0,0 +1,349 @@
+// File: internal/hostconf/audit.go (complete file)
+
+package hostconf
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/baptistax/vpn-leak-identifier/internal/netutil"
+	"github.com/baptistax/vpn-leak-identifier/internal/resolved"
+)
+
+// Finding severities.
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// Finding is one setting that lets DNS or IPv6 bypass the VPN.
+type Finding struct {
+	Severity string
+	Kind     string // ipv6, accept_ra, rp_filter, src_valid_mark, resolv_conf, resolved, nsswitch
+	Message  string
+}
+
+// Options configures Audit.
+type Options struct {
+	// Root is prepended to every path read; empty means "/".
+	Root string
+	// Interfaces are the local interfaces with their egress families; nil
+	// lists them with netutil.Interfaces for "/" and from the tree below
+	// any other root.
+	Interfaces []netutil.Interface
+	// Links is systemd-resolved's configuration as read over D-Bus; nil
+	// reads the state files resolved keeps under /run.
+	Links []resolved.Link
+}
+
+// Report is the audited host configuration.
+type Report struct {
+	// Tunnels are the up interfaces that look like VPN tunnels.
+	Tunnels       []string
+	Sysctls       []Sysctl
+	ResolvConf    ResolvConf
+	NSSwitchHosts []string
+	Links         []resolved.Link
+	Findings      []Finding
+	// Errors lists the sources that could not be read.
+	Errors []string
+}
+
+// tunnelPrefixes name VPN interfaces whose link type does not give them
+// away (tap is Ethernet).
+var tunnelPrefixes = []string{"wg", "tun", "tap", "ppp", "utun", "ipsec", "vti", "nordlynx", "proton", "tailscale", "mullvad"}
+
+// tunnelTypes are ARPHRD_* link types of tunnels in /sys/class/net/*/type:
+// none (WireGuard, tun), PPP, IPIP, SIT, GRE, IP6GRE.
+var tunnelTypes = map[int]bool{65534: true, 512: true, 768: true, 776: true, 778: true, 823: true}
+
+// Audit reads the host configuration and flags settings that leak DNS or
+// IPv6 around a VPN. Missing sources are listed in Errors, not fatal.
+func Audit(opt Options) Report {
+	root := opt.Root
+	if root == "" {
+		root = "/"
+	}
+	var r Report
+	fail := func(what string, err error) {
+		r.Errors = append(r.Errors, what+": "+err.Error())
+	}
+
+	ifaces := opt.Interfaces
+	if ifaces == nil {
+		var err error
+		if root == "/" {
+			ifaces, err = netutil.Interfaces()
+		} else {
+			ifaces, err = readInterfaces(root)
+		}
+		if err != nil {
+			fail("interfaces", err)
+		}
+	}
+	names := map[int]string{}
+	for _, i := range ifaces {
+		names[i.Index] = i.Name
+		if i.Up && IsTunnel(root, i.Name) {
+			r.Tunnels = append(r.Tunnels, i.Name)
+		}
+	}
+
+	var err error
+	if r.Sysctls, err = readSysctls(root); err != nil {
+		fail("sysctl", err)
+	}
+	if r.ResolvConf, err = readResolvConf(root); err != nil {
+		fail("resolv.conf", err)
+	}
+	if r.NSSwitchHosts, err = readNSSwitchHosts(root); err != nil {
+		fail("nsswitch.conf", err)
+	}
+	if opt.Links != nil {
+		r.Links = opt.Links
+	} else if r.Links, err = readResolvedLinks(root, names); err != nil && r.ResolvConf.Stub() {
+		fail("systemd-resolved", err)
+	}
+
+	a := auditor{r: &r, ifaces: ifaces}
+	a.ipv6()
+	a.reversePath()
+	a.resolver()
+	a.nsswitch()
+	return r
+}
+
+type auditor struct {
+	r      *Report
+	ifaces []netutil.Interface
+}
+
+func (a *auditor) add(sev, kind, format string, args ...any) {
+	a.r.Findings = append(a.r.Findings, Finding{Severity: sev, Kind: kind, Message: fmt.Sprintf(format, args...)})
+}
+
+// sysctl returns the value of key on iface, or -1 when it was not read.
+func (a *auditor) sysctl(family, iface, key string) int {
+	for _, s := range a.r.Sysctls {
+		if s.Family == family && s.Interface == iface && s.Key == key {
+			return s.Value
+		}
+	}
+	return -1
+}
+
+func (a *auditor) isTunnel(name string) bool {
+	for _, t := range a.r.Tunnels {
+		if t == name {
+			return true
+		}
+	}
+	return false
+}
+
+// physical returns the up, non-tunnel interfaces.
+func (a *auditor) physical() []netutil.Interface {
+	var out []netutil.Interface
+	for _, i := range a.ifaces {
+		if i.Up && !a.isTunnel(i.Name) {
+			out = append(out, i)
+		}
+	}
+	return out
+}
+
+func (a *auditor) egress(family string) string {
+	for _, i := range a.ifaces {
+		for _, f := range i.Egress {
+			if f == family {
+				return i.Name
+			}
+		}
+	}
+	return ""
+}
+
+// ipv6 flags IPv6 left outside a tunnel that does not carry it. Only the
+// interface holding the IPv6 default route actually leaks; on the others
+// IPv6 is a risk when they accept router advertisements, since any router
+// on the local network can then add that route.
+func (a *auditor) ipv6() {
+	if len(a.r.Tunnels) == 0 {
+		return
+	}
+	egress := a.egress("ipv6")
+	if a.isTunnel(egress) || a.sysctl("ipv6", "all", "disable_ipv6") == 1 {
+		return
+	}
+	for _, p := range a.physical() {
+		if a.sysctl("ipv6", p.Name, "disable_ipv6") != 0 {
+			continue
+		}
+		if egress == p.Name {
+			a.add(SeverityCritical, "ipv6",
+				"IPv6 is enabled on %s (net.ipv6.conf.%s.disable_ipv6=0) and holds the IPv6 default route, but no tunnel carries IPv6: IPv6 connections leave through %s outside the VPN",
+				p.Name, p.Name, p.Name)
+		}
+
+		ra, fwd := a.sysctl("ipv6", p.Name, "accept_ra"), a.sysctl("ipv6", p.Name, "forwarding")
+		if ra == 2 || (ra == 1 && fwd != 1) {
+			a.add(SeverityWarning, "accept_ra",
+				"%s accepts router advertisements (accept_ra=%d): any router on the local network can add an IPv6 default route and RDNSS resolvers outside the tunnel",
+				p.Name, ra)
+		}
+	}
+}
+
+// reversePath flags loose or disabled reverse-path filtering on physical
+// interfaces (CVE-2019-14899) and WireGuard fwmark routing without
+// src_valid_mark. The kernel applies the larger of "all" and the
+// interface value.
+func (a *auditor) reversePath() {
+	if len(a.r.Tunnels) == 0 {
+		return
+	}
+	all := a.sysctl("ipv4", "all", "rp_filter")
+	for _, p := range a.physical() {
+		v := a.sysctl("ipv4", p.Name, "rp_filter")
+		if v < 0 {
+			continue
+		}
+		if all > v {
+			v = all
+		}
+		if v != 1 {
+			a.add(SeverityWarning, "rp_filter",
+				"reverse-path filtering on %s is %s (rp_filter=%d): packets for the tunnel address arriving on %s are accepted, letting the local network probe and hijack tunnel connections",
+				p.Name, map[int]string{0: "off", 2: "loose"}[v], v, p.Name)
+		}
+	}
+	for _, t := range a.r.Tunnels {
+		if strings.HasPrefix(t, "wg") && all == 1 && a.sysctl("ipv4", "all", "src_valid_mark") == 0 {
+			a.add(SeverityInfo, "src_valid_mark",
+				"%s with strict rp_filter and net.ipv4.conf.all.src_valid_mark=0: fwmark-based routing (wg-quick) drops tunnel replies, which often ends with the policy rules being removed",
+				t)
+		}
+	}
+}
+
+func (a *auditor) resolver() {
+	rc := a.r.ResolvConf
+	if len(a.r.Tunnels) == 0 || len(rc.Nameservers) == 0 {
+		return
+	}
+	if rc.Stub() {
+		a.resolved()
+		return
+	}
+
+	for _, l := range a.r.Links {
+		if a.isTunnel(l.Name) && len(l.Servers) > 0 {
+			a.add(SeverityWarning, "resolv_conf",
+				"/etc/resolv.conf does not point at systemd-resolved (nameserver %s) although %s has per-link DNS: programs reading resolv.conf ignore the VPN's DNS",
+				strings.Join(rc.Nameservers, ", "), l.Name)
+			break
+		}
+	}
+	for _, ns := range rc.Nameservers {
+		ip := net.ParseIP(ns)
+		if ip == nil {
+			continue
+		}
+		if ip.IsLoopback() {
+			a.add(SeverityInfo, "resolv_conf", "nameserver %s is a local resolver; its upstream servers are not audited", ns)
+			continue
+		}
+		if iface := a.onLink(ip); iface != "" {
+			a.add(SeverityCritical, "resolv_conf",
+				"nameserver %s is on the local network of %s: queries to it never enter the tunnel", ns, iface)
+			continue
+		}
+		family := "ipv4"
+		if ip.To4() == nil {
+			family = "ipv6"
+		}
+		if e := a.egress(family); e != "" && !a.isTunnel(e) {
+			a.add(SeverityCritical, "resolv_conf",
+				"nameserver %s is reached over %s through %s, not the tunnel", ns, family, e)
+		}
+	}
+}
+
+// resolved flags physical links systemd-resolved may send queries to: every
+// default-route link gets names no routing domain claims, unless a link
+// routes "~.".
+func (a *auditor) resolved() {
+	var tunnelDNS, tunnelAll []string
+	for _, l := range a.r.Links {
+		if !a.isTunnel(l.Name) || len(l.Servers) == 0 {
+			continue
+		}
+		tunnelDNS = append(tunnelDNS, l.Name)
+		if l.RoutesAll() {
+			tunnelAll = append(tunnelAll, l.Name)
+		}
+	}
+	if len(tunnelAll) > 0 {
+		return
+	}
+	for _, l := range a.r.Links {
+		if a.isTunnel(l.Name) || len(l.Servers) == 0 || !l.DefaultRoute {
+			continue
+		}
+		if len(tunnelDNS) == 0 {
+			a.add(SeverityCritical, "resolved",
+				"no tunnel link has DNS servers in systemd-resolved, so every query goes to %s (%s) outside the VPN",
+				l.Name, strings.Join(l.Servers, ", "))
+			continue
+		}
+		a.add(SeverityCritical, "resolved",
+			"%s (%s) is a DNS default route next to %s: systemd-resolved sends names no routing domain claims to every default-route link; set the \"~.\" domain on the tunnel (resolvectl domain %s '~.') or DefaultRoute=no on %s",
+			l.Name, strings.Join(l.Servers, ", "), strings.Join(tunnelDNS, ", "), tunnelDNS[0], l.Name)
+	}
+}
+
+func (a *auditor) nsswitch() {
+	for _, src := range a.r.NSSwitchHosts {
+		switch {
+		case src == "wins":
+			a.add(SeverityWarning, "nsswitch", "hosts: uses wins: NetBIOS name lookups are broadcast on the local network")
+		case strings.HasPrefix(src, "mdns") && strings.HasSuffix(src, "_minimal"):
+			a.add(SeverityInfo, "nsswitch", "hosts: uses %s: .local names are resolved by multicast on the local network", src)
+		case strings.HasPrefix(src, "mdns"):
+			a.add(SeverityWarning, "nsswitch", "hosts: uses %s: names can be resolved by multicast on the local network, outside the tunnel", src)
+		}
+	}
+}
+
+// onLink returns the physical interface whose subnet contains ip.
+func (a *auditor) onLink(ip net.IP) string {
+	for _, p := range a.physical() {
+		for _, addr := range p.Addrs {
+			if _, n, err := net.ParseCIDR(addr); err == nil && n.Contains(ip) {
+				return p.Name
+			}
+		}
+	}
+	return ""
+}
+
+// IsTunnel recognises tunnels by link type in root/sys/class/net, falling
+// back to the name.
+func IsTunnel(root, name string) bool {
+	if b, err := os.ReadFile(filepath.Join(root, "sys/class/net", name, "type")); err == nil {
+		if t, err := strconv.Atoi(strings.TrimSpace(string(b))); err == nil && tunnelTypes[t] {
+			return true
+		}
+	}
+	for _, p := range tunnelPrefixes {
+		if strings.HasPrefix(name, p) {
+			return true
+		}
+	}
+	return false
+}
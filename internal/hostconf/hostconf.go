@@ -0,0 +1,155 @@
+// File: internal/hostconf/hostconf.go (complete file)
+
+// Package hostconf audits the Linux host settings that decide whether DNS
+// and IPv6 follow a VPN: per-interface sysctls, the resolver configuration
+// and systemd-resolved's per-link DNS.
+package hostconf
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Sysctl is one per-interface setting, e.g. Key "disable_ipv6" on
+// Interface "eth0" read from /proc/sys/net/ipv6/conf/eth0/disable_ipv6.
+type Sysctl struct {
+	Family    string // ipv4|ipv6
+	Interface string // an interface, "all" or "default"
+	Key       string
+	Value     int
+}
+
+// Name is the sysctl name, e.g. "net.ipv6.conf.eth0.disable_ipv6".
+func (s Sysctl) Name() string {
+	return "net." + s.Family + ".conf." + s.Interface + "." + s.Key
+}
+
+// ResolvConf is the parsed /etc/resolv.conf.
+type ResolvConf struct {
+	// Target is where the file points when it is a symlink (e.g.
+	// /run/systemd/resolve/stub-resolv.conf).
+	Target      string
+	Nameservers []string
+	Search      []string
+	Options     []string
+}
+
+// Stub reports whether the only nameservers are systemd-resolved's stub
+// listeners.
+func (r ResolvConf) Stub() bool {
+	if len(r.Nameservers) == 0 {
+		return false
+	}
+	for _, ns := range r.Nameservers {
+		if ns != "127.0.0.53" && ns != "127.0.0.54" {
+			return false
+		}
+	}
+	return true
+}
+
+// sysctlKeys are the settings read per interface.
+var sysctlKeys = map[string][]string{
+	"ipv6": {"disable_ipv6", "accept_ra", "forwarding"},
+	"ipv4": {"rp_filter", "src_valid_mark"},
+}
+
+// readSysctls reads sysctlKeys for every interface under root/proc/sys.
+func readSysctls(root string) ([]Sysctl, error) {
+	var out []Sysctl
+	var firstErr error
+	for _, family := range []string{"ipv4", "ipv6"} {
+		dir := filepath.Join(root, "proc/sys/net", family, "conf")
+		ents, err := os.ReadDir(dir)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		for _, e := range ents {
+			for _, key := range sysctlKeys[family] {
+				b, err := os.ReadFile(filepath.Join(dir, e.Name(), key))
+				if err != nil {
+					continue
+				}
+				v, err := strconv.Atoi(strings.TrimSpace(string(b)))
+				if err != nil {
+					continue
+				}
+				out = append(out, Sysctl{Family: family, Interface: e.Name(), Key: key, Value: v})
+			}
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Name() < out[j].Name() })
+	return out, firstErr
+}
+
+// readResolvConf parses the nameserver, search and options lines.
+func readResolvConf(root string) (ResolvConf, error) {
+	path := filepath.Join(root, "etc/resolv.conf")
+	var r ResolvConf
+	if t, err := os.Readlink(path); err == nil {
+		r.Target = t
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return r, err
+	}
+	for _, line := range strings.Split(string(b), "\n") {
+		fields := strings.Fields(stripComment(line))
+		if len(fields) < 2 {
+			continue
+		}
+		switch fields[0] {
+		case "nameserver":
+			// Drop a zone (fe80::1%eth0) for comparisons.
+			ns, _, _ := strings.Cut(fields[1], "%")
+			r.Nameservers = append(r.Nameservers, ns)
+		case "search", "domain":
+			r.Search = append(r.Search, fields[1:]...)
+		case "options":
+			r.Options = append(r.Options, fields[1:]...)
+		}
+	}
+	return r, nil
+}
+
+// readNSSwitchHosts returns the sources of the hosts: line, with actions
+// like [NOTFOUND=return] dropped.
+func readNSSwitchHosts(root string) ([]string, error) {
+	b, err := os.ReadFile(filepath.Join(root, "etc/nsswitch.conf"))
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range strings.Split(string(b), "\n") {
+		key, rest, ok := strings.Cut(stripComment(line), ":")
+		if !ok || strings.TrimSpace(key) != "hosts" {
+			continue
+		}
+		var out []string
+		inAction := false
+		for _, f := range strings.Fields(rest) {
+			switch {
+			case strings.HasPrefix(f, "["):
+				inAction = !strings.HasSuffix(f, "]")
+			case inAction:
+				inAction = !strings.HasSuffix(f, "]")
+			default:
+				out = append(out, f)
+			}
+		}
+		return out, nil
+	}
+	return nil, nil
+}
+
+func stripComment(line string) string {
+	if i := strings.IndexAny(line, "#;"); i >= 0 {
+		return line[:i]
+	}
+	return line
+}
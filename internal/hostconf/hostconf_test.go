@@ -0,0 +1,166 @@
+// File: internal/hostconf/hostconf_test.go (complete file)
+
+package hostconf
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/baptistax/vpn-leak-identifier/internal/netutil"
+)
+
+func writeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestAudit_LeakProneHost(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"proc/sys/net/ipv6/conf/all/disable_ipv6":   "0\n",
+		"proc/sys/net/ipv6/conf/eth0/disable_ipv6":  "0\n",
+		"proc/sys/net/ipv6/conf/eth0/accept_ra":     "1\n",
+		"proc/sys/net/ipv6/conf/eth0/forwarding":    "0\n",
+		"proc/sys/net/ipv6/conf/wg0/disable_ipv6":   "1\n",
+		"proc/sys/net/ipv4/conf/all/rp_filter":      "0\n",
+		"proc/sys/net/ipv4/conf/all/src_valid_mark": "1\n",
+		"proc/sys/net/ipv4/conf/eth0/rp_filter":     "2\n",
+		"sys/class/net/wg0/type":                    "65534\n",
+		"sys/class/net/eth0/type":                   "1\n",
+		"etc/resolv.conf":                           "# stub\nnameserver 127.0.0.53\noptions edns0 trust-ad\nsearch lan\n",
+		"etc/nsswitch.conf":                         "passwd: files\nhosts: files mdns4_minimal [NOTFOUND=return] resolve [!UNAVAIL=return] dns\n",
+		"run/systemd/resolve/netif/2":               "LLMNR=yes\nSERVERS=192.168.1.1\nDOMAINS=lan\n",
+		"run/systemd/resolve/netif/5":               "SERVERS=10.64.0.1:53\nDOMAINS=~vpn.example\n",
+	})
+	ifaces := []netutil.Interface{
+		{Name: "eth0", Index: 2, Up: true, Addrs: []string{"192.168.1.20/24", "2001:db8::20/64"}, Egress: []string{"ipv6"}},
+		{Name: "wg0", Index: 5, Up: true, Addrs: []string{"10.64.0.2/32"}, Egress: []string{"ipv4"}},
+	}
+
+	r := Audit(Options{Root: root, Interfaces: ifaces})
+	if len(r.Tunnels) != 1 || r.Tunnels[0] != "wg0" {
+		t.Fatalf("tunnels = %v", r.Tunnels)
+	}
+	if !r.ResolvConf.Stub() || len(r.NSSwitchHosts) != 4 || r.NSSwitchHosts[2] != "resolve" {
+		t.Fatalf("resolver: %+v hosts %v", r.ResolvConf, r.NSSwitchHosts)
+	}
+	if len(r.Links) != 2 || !r.Links[0].DefaultRoute || r.Links[1].DefaultRoute || r.Links[1].Servers[0] != "10.64.0.1" {
+		t.Fatalf("links = %+v", r.Links)
+	}
+
+	got := map[string]string{}
+	for _, f := range r.Findings {
+		got[f.Kind] = f.Severity
+	}
+	want := map[string]string{
+		"ipv6":      SeverityCritical,
+		"accept_ra": SeverityWarning,
+		"rp_filter": SeverityWarning,
+		"resolved":  SeverityCritical,
+		"nsswitch":  SeverityInfo,
+	}
+	for k, sev := range want {
+		if got[k] != sev {
+			t.Errorf("%s: severity %q, want %q", k, got[k], sev)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("findings = %+v", r.Findings)
+	}
+
+	// Without an IPv6 default route nothing leaves yet; accepting router
+	// advertisements remains the risk.
+	noV6 := []netutil.Interface{ifaces[0], ifaces[1]}
+	noV6[0].Egress = nil
+	kinds := map[string]string{}
+	for _, f := range Audit(Options{Root: root, Interfaces: noV6}).Findings {
+		kinds[f.Kind] = f.Severity
+	}
+	if _, ok := kinds["ipv6"]; ok || kinds["accept_ra"] != SeverityWarning {
+		t.Fatalf("without an IPv6 route: findings %v", kinds)
+	}
+
+	// "~." on the tunnel takes every name away from eth0.
+	writeTree(t, root, map[string]string{"run/systemd/resolve/netif/5": "SERVERS=10.64.0.1\nDOMAINS=~.\n"})
+	for _, f := range Audit(Options{Root: root, Interfaces: ifaces}).Findings {
+		if f.Kind == "resolved" {
+			t.Fatalf("unexpected finding with ~. on the tunnel: %+v", f)
+		}
+	}
+}
+
+func TestAudit_ResolvConfOnLocalNetwork(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"etc/resolv.conf": "nameserver 192.168.1.1\nnameserver 10.64.0.1\n",
+	})
+	ifaces := []netutil.Interface{
+		{Name: "eth0", Index: 2, Up: true, Addrs: []string{"192.168.1.20/24"}},
+		{Name: "tun0", Index: 5, Up: true, Addrs: []string{"10.64.0.2/24"}, Egress: []string{"ipv4"}},
+	}
+	r := Audit(Options{Root: root, Interfaces: ifaces})
+	if len(r.Findings) != 1 || r.Findings[0].Kind != "resolv_conf" || r.Findings[0].Severity != SeverityCritical {
+		t.Fatalf("findings = %+v", r.Findings)
+	}
+	if len(r.Errors) == 0 {
+		t.Fatal("missing /proc and nsswitch.conf should be reported")
+	}
+}
+
+func TestAudit_InterfacesFromRoot(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"sys/class/net/lo/type":      "772\n",
+		"sys/class/net/lo/flags":     "0x9\n",
+		"sys/class/net/eth0/type":    "1\n",
+		"sys/class/net/eth0/ifindex": "2\n",
+		"sys/class/net/eth0/mtu":     "1500\n",
+		"sys/class/net/eth0/flags":   "0x1003\n",
+		"sys/class/net/wg0/type":     "65534\n",
+		"sys/class/net/wg0/ifindex":  "5\n",
+		"sys/class/net/wg0/mtu":      "1420\n",
+		"sys/class/net/wg0/flags":    "0x91\n",
+		"sys/class/net/tun1/type":    "65534\n",
+		"sys/class/net/tun1/flags":   "0x1090\n",
+		"proc/net/route": "Iface\tDestination\tGateway \tFlags\tRefCnt\tUse\tMetric\tMask\t\tMTU\tWindow\tIRTT\n" +
+			"eth0\t00000000\t0101A8C0\t0003\t0\t0\t100\t00000000\t0\t0\t0\n" +
+			"wg0\t00000000\t00000000\t0001\t0\t0\t50\t00000000\t0\t0\t0\n" +
+			"eth0\t0001A8C0\t00000000\t0001\t0\t0\t100\t00FFFFFF\t0\t0\t0\n",
+		"proc/net/ipv6_route": "00000000000000000000000000000000 00 00000000000000000000000000000000 00 fe800000000000000000000000000001 00000400 00000002 00000000 00000003     eth0\n" +
+			"00000000000000000000000000000000 00 00000000000000000000000000000000 00 00000000000000000000000000000000 ffffffff 00000001 00000000 00200200       lo\n",
+	})
+
+	r := Audit(Options{Root: root})
+	if len(r.Tunnels) != 1 || r.Tunnels[0] != "wg0" {
+		t.Fatalf("tunnels = %v (the live host's must not appear)", r.Tunnels)
+	}
+	ifaces, err := readInterfaces(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]netutil.Interface{}
+	for _, i := range ifaces {
+		got[i.Name] = i
+	}
+	if _, ok := got["lo"]; ok || len(got) != 3 {
+		t.Fatalf("interfaces = %+v", ifaces)
+	}
+	if e := got["eth0"]; !e.Up || e.Index != 2 || e.MTU != 1500 || len(e.Egress) != 1 || e.Egress[0] != "ipv6" {
+		t.Fatalf("eth0 = %+v", e)
+	}
+	if w := got["wg0"]; !w.Up || len(w.Egress) != 1 || w.Egress[0] != "ipv4" {
+		t.Fatalf("wg0 = %+v", w)
+	}
+	if got["tun1"].Up {
+		t.Fatalf("tun1 is down: %+v", got["tun1"])
+	}
+}
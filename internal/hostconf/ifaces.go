@@ -0,0 +1,118 @@
+// File: internal/hostconf/ifaces.go (complete file)
+
+package hostconf
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/baptistax/vpn-leak-identifier/internal/netutil"
+)
+
+const (
+	arphrdLoopback = 772
+	iffUp          = 0x1
+	rtfUp          = 0x1
+)
+
+// readInterfaces lists the interfaces below root/sys/class/net and marks the
+// default-route interface per family from root/proc/net/route and
+// ipv6_route, for auditing a copied tree instead of the live host. Addresses
+// are not available there and stay empty.
+func readInterfaces(root string) ([]netutil.Interface, error) {
+	dir := filepath.Join(root, "sys/class/net")
+	ents, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	egress := map[string][]string{}
+	if name := defaultRoute4(root); name != "" {
+		egress[name] = append(egress[name], "ipv4")
+	}
+	if name := defaultRoute6(root); name != "" {
+		egress[name] = append(egress[name], "ipv6")
+	}
+
+	out := []netutil.Interface{}
+	for _, e := range ents {
+		name := e.Name()
+		if readInt(filepath.Join(dir, name, "type"), 10) == arphrdLoopback {
+			continue
+		}
+		out = append(out, netutil.Interface{
+			Name:   name,
+			Index:  readInt(filepath.Join(dir, name, "ifindex"), 10),
+			MTU:    readInt(filepath.Join(dir, name, "mtu"), 10),
+			Up:     readInt(filepath.Join(dir, name, "flags"), 0)&iffUp != 0,
+			Egress: egress[name],
+		})
+	}
+	return out, nil
+}
+
+// readInt parses a one-number sysfs file; base 0 accepts "0x1003".
+func readInt(path string, base int) int {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return -1
+	}
+	v, err := strconv.ParseInt(strings.TrimSpace(string(b)), base, 64)
+	if err != nil {
+		return -1
+	}
+	return int(v)
+}
+
+// defaultRoute4 returns the interface of the lowest-metric IPv4 default
+// route in the main table.
+func defaultRoute4(root string) string {
+	// Iface Destination Gateway Flags RefCnt Use Metric Mask ...
+	return lowestMetric(filepath.Join(root, "proc/net/route"), func(f []string) (string, string, bool) {
+		if len(f) < 8 || f[0] == "Iface" {
+			return "", "", false
+		}
+		flags, _ := strconv.ParseUint(f[3], 16, 32)
+		return f[0], f[6], f[1] == "00000000" && f[7] == "00000000" && flags&rtfUp != 0
+	}, 10)
+}
+
+// defaultRoute6 returns the interface of the lowest-metric IPv6 default
+// route. The kernel's unreachable default on lo is skipped.
+func defaultRoute6(root string) string {
+	// dest plen src srcplen nexthop metric refcnt use flags iface
+	return lowestMetric(filepath.Join(root, "proc/net/ipv6_route"), func(f []string) (string, string, bool) {
+		if len(f) < 10 {
+			return "", "", false
+		}
+		flags, _ := strconv.ParseUint(f[8], 16, 32)
+		return f[9], f[5], f[0] == strings.Repeat("0", 32) && f[1] == "00" && f[9] != "lo" && flags&rtfUp != 0
+	}, 16)
+}
+
+func lowestMetric(path string, parse func([]string) (iface, metric string, ok bool), base int) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	best, bestMetric := "", uint64(0)
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		iface, metric, ok := parse(strings.Fields(sc.Text()))
+		if !ok {
+			continue
+		}
+		m, err := strconv.ParseUint(metric, base, 32)
+		if err != nil {
+			continue
+		}
+		if best == "" || m < bestMetric {
+			best, bestMetric = iface, m
+		}
+	}
+	return best
+}
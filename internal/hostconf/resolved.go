@@ -0,0 +1,121 @@
+// File: internal/hostconf/resolved.go (complete file)
+
+package hostconf
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/baptistax/vpn-leak-identifier/internal/resolved"
+)
+
+// readResolvedLinks reads the state systemd-resolved keeps per link in
+// /run/systemd/resolve/netif/<ifindex> (KEY=value lines) and the global
+// DNS= and Domains= from /etc/systemd/resolved.conf. names maps ifindex to
+// interface name.
+func readResolvedLinks(root string, names map[int]string) ([]resolved.Link, error) {
+	var out []resolved.Link
+	if g, ok := readResolvedConf(root); ok {
+		out = append(out, g)
+	}
+
+	dir := filepath.Join(root, "run/systemd/resolve/netif")
+	ents, err := os.ReadDir(dir)
+	if err != nil {
+		return out, err
+	}
+	for _, e := range ents {
+		idx, err := strconv.Atoi(e.Name())
+		if err != nil {
+			continue
+		}
+		b, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			continue
+		}
+		l := resolved.Link{Index: idx, Name: names[idx]}
+		if l.Name == "" {
+			l.Name = "#" + e.Name()
+		}
+		for _, line := range strings.Split(string(b), "\n") {
+			key, val, ok := strings.Cut(strings.TrimSpace(line), "=")
+			if !ok {
+				continue
+			}
+			switch key {
+			case "SERVERS":
+				l.Servers = splitServers(val)
+			case "DOMAINS":
+				l.Domains = strings.Fields(val)
+			case "DEFAULT_ROUTE":
+				l.DefaultRoute, l.DefaultRouteSet = parseBool(val)
+			}
+		}
+		l.DefaultRoute = resolved.EffectiveDefaultRoute(l)
+		out = append(out, l)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Index < out[j].Index })
+	return out, nil
+}
+
+// readResolvedConf returns the global scope when resolved.conf sets DNS=.
+func readResolvedConf(root string) (resolved.Link, bool) {
+	b, err := os.ReadFile(filepath.Join(root, "etc/systemd/resolved.conf"))
+	if err != nil {
+		return resolved.Link{}, false
+	}
+	g := resolved.Link{Name: "global"}
+	section := ""
+	for _, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSpace(stripComment(line))
+		if strings.HasPrefix(line, "[") {
+			section = strings.Trim(line, "[]")
+			continue
+		}
+		key, val, ok := strings.Cut(line, "=")
+		if !ok || section != "Resolve" {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "DNS":
+			g.Servers = append(g.Servers, splitServers(val)...)
+		case "Domains":
+			g.Domains = append(g.Domains, strings.Fields(val)...)
+		}
+	}
+	g.DefaultRoute = resolved.EffectiveDefaultRoute(g)
+	return g, len(g.Servers) > 0
+}
+
+// splitServers drops ports, interfaces and SNI names: "1.1.1.1:853#dns" ->
+// "1.1.1.1", "[2606:4700::1111]:53%wg0" -> "2606:4700::1111".
+func splitServers(val string) []string {
+	var out []string
+	for _, f := range strings.Fields(val) {
+		f, _, _ = strings.Cut(f, "#")
+		f, _, _ = strings.Cut(f, "%")
+		if strings.HasPrefix(f, "[") {
+			f = strings.TrimPrefix(f, "[")
+			f, _, _ = strings.Cut(f, "]")
+		} else if strings.Count(f, ":") == 1 {
+			f, _, _ = strings.Cut(f, ":")
+		}
+		if f != "" {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+func parseBool(s string) (value, ok bool) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "1", "yes", "true", "on":
+		return true, true
+	case "0", "no", "false", "off":
+		return false, true
+	}
+	return false, false
+}
@@ -0,0 +1,141 @@
+// File: internal/ipv6/analyze.go (complete file)
+
+package ipv6
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Finding severities.
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// Finding is one IPv6 exposure with an explanation of the path involved.
+type Finding struct {
+	Severity string
+	Kind     string // eui64, teredo, 6to4, nat64, native, privacy
+	Message  string
+}
+
+// Input is what Analyze needs from a probe round.
+type Input struct {
+	Local []Address
+	// ExitV4 and ExitV6 are the public addresses seen by the geo
+	// providers; EgressV4 and EgressV6 name the interfaces carrying each
+	// default route.
+	ExitV4, ExitV6     string
+	EgressV4, EgressV6 string
+	NAT64              NAT64
+}
+
+// Analysis is the classified exit and local addresses with the findings.
+type Analysis struct {
+	Exit     *Address
+	Local    []Address
+	Findings []Finding
+}
+
+// Analyze classifies the IPv6 exit and explains every local IPv6 path that
+// can bypass an IPv4-only VPN or identify the host.
+func Analyze(in Input) Analysis {
+	out := Analysis{Local: in.Local}
+	add := func(sev, kind, format string, args ...any) {
+		out.Findings = append(out.Findings, Finding{Severity: sev, Kind: kind, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if ip := net.ParseIP(in.ExitV6); ip != nil {
+		if a, ok := Classify(ip, in.NAT64.Prefixes...); ok {
+			// A local exit keeps what the kernel says about it (privacy).
+			for _, l := range in.Local {
+				if l.IP == a.IP {
+					a = l
+				}
+			}
+			out.Exit = &a
+		}
+	}
+
+	// IPv6 that does not follow the IPv4 default route is outside a
+	// v4-only tunnel.
+	v6Bypass := in.EgressV4 != "" && in.EgressV6 != "" && in.EgressV6 != in.EgressV4
+	switch {
+	case v6Bypass && in.ExitV6 != "":
+		add(SeverityCritical, "native",
+			"IPv6 leaves through %s while IPv4 uses %s: an IPv4-only VPN does not carry IPv6, so every site reachable over IPv6 sees %s",
+			in.EgressV6, in.EgressV4, in.ExitV6)
+	case v6Bypass:
+		add(SeverityWarning, "native",
+			"the IPv6 default route uses %s while IPv4 uses %s; no IPv6 exit answered, but any IPv6 connection would bypass an IPv4-only VPN",
+			in.EgressV6, in.EgressV4)
+	}
+
+	if e := out.Exit; e != nil {
+		switch {
+		case e.Is(KindEUI64):
+			add(SeverityCritical, "eui64",
+				"the IPv6 exit %s embeds the MAC address %s; every IPv6 destination sees a hardware identity that survives changing networks",
+				e.IP, e.MAC)
+		case e.Is(KindPrivacy):
+			add(SeverityInfo, "privacy", "the IPv6 exit %s is a temporary (privacy) address", e.IP)
+		}
+		if e.Is(KindTeredo) || e.Is(Kind6to4) {
+			add(SeverityWarning, string(e.Kinds[0]),
+				"the IPv6 exit %s is a %s tunnel address: IPv6 is wrapped in IPv4 to a public relay rather than carried by the VPN",
+				e.IP, e.Kinds[0])
+		}
+	}
+
+	for _, a := range in.Local {
+		switch {
+		case a.Is(KindTeredo):
+			sev := SeverityWarning
+			if in.ExitV4 != "" && a.EmbeddedIPv4 != in.ExitV4 {
+				sev = SeverityCritical
+			}
+			add(sev, "teredo",
+				"%s has the Teredo address %s (server %s) revealing the public IPv4 %s:%d; Teredo wraps IPv6 in UDP/3544 to relays, "+
+					"so IPv6 traffic reaches destinations through those relays even when the VPN only routes IPv4",
+				a.Interface, a.IP, a.TeredoServer, a.EmbeddedIPv4, a.TeredoPort)
+		case a.Is(Kind6to4):
+			sev := SeverityWarning
+			if in.ExitV4 != "" && a.EmbeddedIPv4 != in.ExitV4 {
+				sev = SeverityCritical
+			}
+			add(sev, "6to4",
+				"%s has the 6to4 address %s embedding the public IPv4 %s; 6to4 sends IPv6 as IPv4 protocol 41 to anycast relays "+
+					"(192.88.99.1), which a VPN that only tunnels TCP/UDP may not carry",
+				a.Interface, a.IP, a.EmbeddedIPv4)
+		case a.Is(KindEUI64) && a.Is(KindGlobal) && (out.Exit == nil || out.Exit.IP != a.IP):
+			add(SeverityWarning, "eui64",
+				"%s has the EUI-64 address %s embedding the MAC address %s; it becomes the source of any IPv6 connection that leaves outside the tunnel",
+				a.Interface, a.IP, a.MAC)
+		}
+	}
+
+	if len(in.NAT64.Prefixes) > 0 {
+		sev := SeverityWarning
+		if v6Bypass {
+			sev = SeverityCritical
+		}
+		add(sev, "nat64",
+			"the resolver does DNS64 with NAT64 prefix %s (RFC 7050): names with only IPv4 addresses get synthesized AAAA records, "+
+				"so with an IPv4-only VPN connections to them go over IPv6 through the network's NAT64 gateway instead of the tunnel",
+			joinNets(in.NAT64.Prefixes))
+	} else if e := out.Exit; e != nil && e.Is(KindNAT64) {
+		add(SeverityWarning, "nat64", "the IPv6 exit %s is inside a NAT64 prefix", e.IP)
+	}
+	return out
+}
+
+func joinNets(nets []*net.IPNet) string {
+	out := make([]string, 0, len(nets))
+	for _, n := range nets {
+		out = append(out, n.String())
+	}
+	return strings.Join(out, ", ")
+}
@@ -0,0 +1,157 @@
+// File: internal/ipv6/ipv6.go (complete file)
+
+// Package ipv6 classifies IPv6 addresses (EUI-64, privacy, Teredo, 6to4,
+// NAT64) and explains the IPv6 paths that can carry traffic around an
+// IPv4-only VPN.
+package ipv6
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+)
+
+// Kind is one property of an IPv6 address; an address can have several
+// (e.g. global and eui64).
+type Kind string
+
+const (
+	KindGlobal    Kind = "global"     // native global unicast
+	KindEUI64     Kind = "eui64"      // interface ID built from the MAC (RFC 4291 appendix A)
+	KindPrivacy   Kind = "privacy"    // temporary address (RFC 8981)
+	KindTeredo    Kind = "teredo"     // 2001::/32 (RFC 4380)
+	Kind6to4      Kind = "6to4"       // 2002::/16 (RFC 3056)
+	KindNAT64     Kind = "nat64"      // 64:ff9b::/96 or a discovered prefix (RFC 6052)
+	KindLinkLocal Kind = "link-local" // fe80::/10
+	KindULA       Kind = "ula"        // fc00::/7
+)
+
+// Address is a classified IPv6 address.
+type Address struct {
+	IP        string
+	Interface string // empty for addresses that are not local (the exit)
+	Kinds     []Kind
+
+	// MAC is the hardware address embedded in an EUI-64 interface ID.
+	MAC string
+	// EmbeddedIPv4 is the IPv4 address carried in the address: the 6to4
+	// site's public IPv4, the Teredo client's public IPv4 or the NAT64
+	// destination.
+	EmbeddedIPv4 string
+	// TeredoServer and TeredoPort are the Teredo server and the client's
+	// public (NAT-mapped) UDP port.
+	TeredoServer string
+	TeredoPort   int
+}
+
+// Is reports whether a has kind k.
+func (a Address) Is(k Kind) bool {
+	for _, x := range a.Kinds {
+		if x == k {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	teredoNet    = mustCIDR("2001::/32")
+	sixToFourNet = mustCIDR("2002::/16")
+	linkLocalNet = mustCIDR("fe80::/10")
+	ulaNet       = mustCIDR("fc00::/7")
+
+	// WellKnownNAT64 is the RFC 6052 well-known prefix; 64:ff9b:1::/48 is
+	// the RFC 8215 local-use prefix.
+	WellKnownNAT64 = mustCIDR("64:ff9b::/96")
+	LocalUseNAT64  = mustCIDR("64:ff9b:1::/48")
+)
+
+// Classify classifies ip. nat64 lists NAT64 prefixes discovered on the
+// network in addition to the well-known ones. ok is false for IPv4.
+func Classify(ip net.IP, nat64 ...*net.IPNet) (a Address, ok bool) {
+	if ip == nil || ip.To4() != nil || len(ip) != net.IPv6len {
+		return Address{}, false
+	}
+	a.IP = ip.String()
+
+	switch {
+	case linkLocalNet.Contains(ip):
+		a.Kinds = append(a.Kinds, KindLinkLocal)
+	case ulaNet.Contains(ip):
+		a.Kinds = append(a.Kinds, KindULA)
+	case teredoNet.Contains(ip):
+		a.Kinds = append(a.Kinds, KindTeredo)
+		a.TeredoServer = net.IP(ip[4:8]).String()
+		a.TeredoPort = int(^binary.BigEndian.Uint16(ip[10:12]))
+		client := make(net.IP, net.IPv4len)
+		for i := range client {
+			client[i] = ^ip[12+i]
+		}
+		a.EmbeddedIPv4 = client.String()
+		// The Teredo interface ID carries flags and the mapped address,
+		// not a MAC.
+		return a, true
+	case sixToFourNet.Contains(ip):
+		a.Kinds = append(a.Kinds, Kind6to4)
+		a.EmbeddedIPv4 = net.IP(ip[2:6]).String()
+	case ip.IsGlobalUnicast():
+		a.Kinds = append(a.Kinds, KindGlobal)
+	}
+
+	for _, n := range append([]*net.IPNet{WellKnownNAT64, LocalUseNAT64}, nat64...) {
+		if n.Contains(ip) {
+			a.Kinds = append(a.Kinds, KindNAT64)
+			if v4 := embeddedIPv4(ip, n); v4 != nil {
+				a.EmbeddedIPv4 = v4.String()
+			}
+			return a, true
+		}
+	}
+
+	// EUI-64: ff:fe in the middle of the interface ID; flipping the
+	// universal/local bit gives the MAC back.
+	if ip[11] == 0xff && ip[12] == 0xfe {
+		a.Kinds = append(a.Kinds, KindEUI64)
+		a.MAC = net.HardwareAddr{ip[8] ^ 0x02, ip[9], ip[10], ip[13], ip[14], ip[15]}.String()
+	}
+	return a, true
+}
+
+// embeddedIPv4 extracts the IPv4 address from ip under an RFC 6052 prefix of
+// length 32, 40, 48, 56, 64 or 96. Bits 64-71 (the "u" octet) are skipped.
+func embeddedIPv4(ip net.IP, prefix *net.IPNet) net.IP {
+	ones, bits := prefix.Mask.Size()
+	if bits != 128 {
+		return nil
+	}
+	var pos []int
+	switch ones {
+	case 32:
+		pos = []int{4, 5, 6, 7}
+	case 40:
+		pos = []int{5, 6, 7, 9}
+	case 48:
+		pos = []int{6, 7, 9, 10}
+	case 56:
+		pos = []int{7, 9, 10, 11}
+	case 64:
+		pos = []int{9, 10, 11, 12}
+	case 96:
+		pos = []int{12, 13, 14, 15}
+	default:
+		return nil
+	}
+	out := make(net.IP, net.IPv4len)
+	for i, p := range pos {
+		out[i] = ip[p]
+	}
+	return out
+}
+
+func mustCIDR(s string) *net.IPNet {
+	_, n, err := net.ParseCIDR(s)
+	if err != nil {
+		panic(fmt.Sprintf("ipv6: bad CIDR %q", s))
+	}
+	return n
+}
@@ -0,0 +1,99 @@
+// File: internal/ipv6/ipv6_test.go (complete file)
+
+package ipv6
+
+import (
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestClassify_Kinds(t *testing.T) {
+	// RFC 4380 section 4 example.
+	a, ok := Classify(net.ParseIP("2001:0:4136:e378:8000:63bf:3fff:fdd2"))
+	if !ok || !a.Is(KindTeredo) || a.TeredoServer != "65.54.227.120" || a.EmbeddedIPv4 != "192.0.2.45" || a.TeredoPort != 40000 {
+		t.Fatalf("teredo: %+v", a)
+	}
+
+	a, _ = Classify(net.ParseIP("2002:cb00:7107::211:22ff:fe33:4455"))
+	if !a.Is(Kind6to4) || !a.Is(KindEUI64) || a.EmbeddedIPv4 != "203.0.113.7" || a.MAC != "00:11:22:33:44:55" {
+		t.Fatalf("6to4: %+v", a)
+	}
+
+	a, _ = Classify(net.ParseIP("2001:db8:1::a1b2:c3d4:e5f6:789"))
+	if !a.Is(KindGlobal) || a.Is(KindEUI64) {
+		t.Fatalf("random IID: %+v", a)
+	}
+
+	a, _ = Classify(net.ParseIP("64:ff9b::198.51.100.10"))
+	if !a.Is(KindNAT64) || a.EmbeddedIPv4 != "198.51.100.10" {
+		t.Fatalf("nat64: %+v", a)
+	}
+	_, discovered, _ := net.ParseCIDR("2001:db8:64::/64")
+	a, _ = Classify(net.ParseIP("2001:db8:64:0:c6:3364:a00::"), discovered)
+	if !a.Is(KindNAT64) || a.EmbeddedIPv4 != "198.51.100.10" {
+		t.Fatalf("nat64 /64: %+v", a)
+	}
+
+	if _, ok := Classify(net.ParseIP("192.0.2.1")); ok {
+		t.Fatal("IPv4 must not classify")
+	}
+}
+
+func TestNAT64Prefix_RFC6052Lengths(t *testing.T) {
+	for ip, want := range map[string]string{
+		"64:ff9b::c000:aa":           "64:ff9b::/96",
+		"2001:db8:1c0:0:aa::":        "2001:db8:100::/40",
+		"2001:db8:122:c000:0:ab00::": "2001:db8:122::/48",
+		"2001:db8::c0:0:aa00:0":      "2001:db8::/64",
+	} {
+		p := nat64Prefix(net.ParseIP(ip))
+		if p == nil || p.String() != want {
+			t.Errorf("%s: prefix %v, want %s", ip, p, want)
+		}
+	}
+	if p := nat64Prefix(net.ParseIP("2001:db8::1")); p != nil {
+		t.Errorf("unexpected prefix %v", p)
+	}
+}
+
+func TestAnalyze_BypassPaths(t *testing.T) {
+	teredo, _ := Classify(net.ParseIP("2001:0:4136:e378:8000:63bf:3fff:fdd2"))
+	teredo.Interface = "teredo"
+	eui, _ := Classify(net.ParseIP("2001:db8:1::211:22ff:fe33:4455"))
+	eui.Interface = "eth0"
+	_, prefix, _ := net.ParseCIDR("64:ff9b::/96")
+
+	res := Analyze(Input{
+		Local:    []Address{teredo, eui},
+		ExitV4:   "198.51.100.1",
+		ExitV6:   eui.IP,
+		EgressV4: "wg0",
+		EgressV6: "eth0",
+		NAT64:    NAT64{Prefixes: []*net.IPNet{prefix}},
+	})
+	got := map[string]string{}
+	for _, f := range res.Findings {
+		got[f.Kind] = f.Severity
+	}
+	want := map[string]string{"native": SeverityCritical, "eui64": SeverityCritical, "teredo": SeverityCritical, "nat64": SeverityCritical}
+	for k, sev := range want {
+		if got[k] != sev {
+			t.Errorf("%s: severity %q, want %q (findings %+v)", k, got[k], sev, res.Findings)
+		}
+	}
+	if res.Exit == nil || res.Exit.Interface != "eth0" {
+		t.Fatalf("the exit should be matched to the local address: %+v", res.Exit)
+	}
+	for _, f := range res.Findings {
+		if f.Kind == "teredo" && !strings.Contains(f.Message, "192.0.2.45") {
+			t.Errorf("teredo finding should name the embedded IPv4: %s", f.Message)
+		}
+	}
+
+	// A full tunnel carrying both families with a random exit is clean.
+	res = Analyze(Input{ExitV6: "2001:db8::1", EgressV4: "wg0", EgressV6: "wg0"})
+	if len(res.Findings) != 0 {
+		t.Fatalf("unexpected findings: %+v", res.Findings)
+	}
+}
@@ -0,0 +1,43 @@
+// File: internal/ipv6/local.go (complete file)
+
+package ipv6
+
+import "net"
+
+// Local classifies the IPv6 addresses of the up, non-loopback interfaces.
+// Privacy addresses are only recognised where the kernel reports them
+// (Linux); elsewhere they look like any other non-EUI-64 address.
+func Local(nat64 ...*net.IPNet) ([]Address, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	temporary := temporaryAddrs()
+
+	var out []Address
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			n, ok := addr.(*net.IPNet)
+			if !ok {
+				continue
+			}
+			a, ok := Classify(n.IP, nat64...)
+			if !ok {
+				continue
+			}
+			a.Interface = iface.Name
+			if temporary[a.IP] {
+				a.Kinds = append(a.Kinds, KindPrivacy)
+			}
+			out = append(out, a)
+		}
+	}
+	return out, nil
+}
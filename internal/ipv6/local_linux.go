@@ -0,0 +1,53 @@
+// File: internal/ipv6/local_linux.go (complete file)
+
+//go:build linux
+
+package ipv6
+
+import (
+	"bufio"
+	"encoding/hex"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ifaFTemporary is IFA_F_TEMPORARY from linux/if_addr.h.
+const ifaFTemporary = 0x01
+
+// temporaryAddrs reads the address flags from /proc/net/if_inet6:
+//
+//	20010db8000000000000000000000001 02 40 00 01 eth0
+//
+// (address, ifindex, prefix length, scope, flags, name; all hex).
+func temporaryAddrs() map[string]bool {
+	f, err := os.Open("/proc/net/if_inet6")
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	return parseIfInet6(bufio.NewScanner(f))
+}
+
+func parseIfInet6(sc *bufio.Scanner) map[string]bool {
+	out := map[string]bool{}
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) < 6 {
+			continue
+		}
+		raw, err := hex.DecodeString(fields[0])
+		if err != nil || len(raw) != net.IPv6len {
+			continue
+		}
+		flags, err := strconv.ParseUint(fields[4], 16, 32)
+		if err != nil {
+			continue
+		}
+		if flags&ifaFTemporary != 0 {
+			out[net.IP(raw).String()] = true
+		}
+	}
+	return out
+}
@@ -0,0 +1,10 @@
+// File: internal/ipv6/local_other.go (complete file)
+
+//go:build !linux
+
+package ipv6
+
+// temporaryAddrs is not available outside Linux.
+func temporaryAddrs() map[string]bool {
+	return nil
+}
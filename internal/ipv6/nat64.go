@@ -0,0 +1,78 @@
+// File: internal/ipv6/nat64.go (complete file)
+
+package ipv6
+
+import (
+	"context"
+	"errors"
+	"net"
+)
+
+// ipv4onlyAddrs are the well-known IPv4 addresses of ipv4only.arpa; a DNS64
+// resolver synthesizes AAAA records from them (RFC 7050 section 2.2).
+var ipv4onlyAddrs = []net.IP{net.IPv4(192, 0, 0, 170), net.IPv4(192, 0, 0, 171)}
+
+// prefixLengths are the RFC 6052 NAT64 prefix lengths, most common first.
+var prefixLengths = []int{96, 64, 56, 48, 40, 32}
+
+// NAT64 is the outcome of RFC 7050 prefix discovery.
+type NAT64 struct {
+	// Prefixes are the NAT64 prefixes recovered from the synthesized
+	// answers; empty when the resolver does no DNS64.
+	Prefixes []*net.IPNet
+	// Synthesized are the AAAA answers for ipv4only.arpa.
+	Synthesized []string
+}
+
+// DiscoverNAT64 asks the system resolver (or r) for the AAAA records of
+// ipv4only.arpa. The name only has A records, so any AAAA answer was made
+// up by a DNS64 resolver, and the position of 192.0.0.170/171 inside it
+// gives the NAT64 prefix.
+func DiscoverNAT64(ctx context.Context, r *net.Resolver) (NAT64, error) {
+	if r == nil {
+		r = net.DefaultResolver
+	}
+	var out NAT64
+	ips, err := r.LookupIP(ctx, "ip6", "ipv4only.arpa")
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return out, nil
+	}
+	if err != nil {
+		return out, err
+	}
+	for _, ip := range ips {
+		out.Synthesized = append(out.Synthesized, ip.String())
+		if p := nat64Prefix(ip); p != nil && !containsNet(out.Prefixes, p) {
+			out.Prefixes = append(out.Prefixes, p)
+		}
+	}
+	return out, nil
+}
+
+// nat64Prefix returns the prefix under which ip embeds one of the
+// ipv4only.arpa addresses.
+func nat64Prefix(ip net.IP) *net.IPNet {
+	if ip.To4() != nil || len(ip) != net.IPv6len {
+		return nil
+	}
+	for _, ones := range prefixLengths {
+		n := &net.IPNet{IP: ip.Mask(net.CIDRMask(ones, 128)), Mask: net.CIDRMask(ones, 128)}
+		v4 := embeddedIPv4(ip, n)
+		for _, want := range ipv4onlyAddrs {
+			if v4.Equal(want) {
+				return n
+			}
+		}
+	}
+	return nil
+}
+
+func containsNet(list []*net.IPNet, n *net.IPNet) bool {
+	for _, x := range list {
+		if x.String() == n.String() {
+			return true
+		}
+	}
+	return false
+}
@@ -3,33 +3,75 @@
 package leaks
 
 import (
+	"context"
 	"errors"
 	"net"
+	"sync"
+	"time"
 )
 
-func LookupRecursorIPsViaIdentMe() ([]string, error) {
-	// ns.ident.me (and ns4/ns6) returns the public IP of the DNS recursors used by the system.
-	names := []string{"ns.ident.me", "ns4.ident.me", "ns6.ident.me"}
+// RecursorLookup is the answer to one ns.ident.me query: the public IPs of
+// the recursors that asked ident.me's authoritative servers on our behalf.
+type RecursorLookup struct {
+	Name string
+	// Transport is how the recursor had to reach ident.me's servers:
+	// ns4.ident.me is only served over IPv4, ns6.ident.me only over IPv6,
+	// ns.ident.me over either ("any").
+	Transport string
+	// Family is the record type asked for: ipv4 (A) or ipv6 (AAAA).
+	Family  string
+	IPs     []string
+	Elapsed time.Duration
+	Err     error
+}
 
-	var out []string
-	seen := map[string]bool{}
+var identMeNames = []struct{ name, transport string }{
+	{"ns.ident.me", "any"},
+	{"ns4.ident.me", "ipv4"},
+	{"ns6.ident.me", "ipv6"},
+}
 
-	for _, name := range names {
-		ips, err := net.LookupIP(name)
-		if err != nil {
-			continue
-		}
-		for _, ip := range ips {
-			s := ip.String()
-			if !seen[s] {
-				seen[s] = true
-				out = append(out, s)
-			}
+// LookupRecursorsViaIdentMe queries ns.ident.me, ns4.ident.me and
+// ns6.ident.me for both A and AAAA records through the system resolver, so
+// a recursor that only handles one query family or one transport shows up
+// on its own. A name without records of a family is not an error.
+func LookupRecursorsViaIdentMe(ctx context.Context) []RecursorLookup {
+	var out []RecursorLookup
+	for _, n := range identMeNames {
+		for _, family := range []string{"ipv4", "ipv6"} {
+			out = append(out, RecursorLookup{Name: n.name, Transport: n.transport, Family: family})
 		}
 	}
 
-	if len(out) == 0 {
-		return nil, errors.New("no IPs returned from ns.ident.me lookups")
+	var wg sync.WaitGroup
+	for i := range out {
+		wg.Add(1)
+		go func(l *RecursorLookup) {
+			defer wg.Done()
+			network := "ip4"
+			if l.Family == "ipv6" {
+				network = "ip6"
+			}
+			start := time.Now()
+			ips, err := net.DefaultResolver.LookupIP(ctx, network, l.Name)
+			l.Elapsed = time.Since(start)
+			var dnsErr *net.DNSError
+			if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+				return
+			}
+			if err != nil {
+				l.Err = err
+				return
+			}
+			seen := map[string]bool{}
+			for _, ip := range ips {
+				if s := ip.String(); !seen[s] {
+					seen[s] = true
+					l.IPs = append(l.IPs, s)
+				}
+			}
+		}(&out[i])
 	}
-	return out, nil
+	wg.Wait()
+	return out
 }
@@ -0,0 +1,91 @@
+// File: internal/logging/handler.go (complete file)
+
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// field is one flattened attribute; group members are joined with ".".
+type field struct {
+	Key   string
+	Value string
+}
+
+// recordWriter formats and delivers one record (journald or syslog).
+type recordWriter interface {
+	write(t time.Time, level slog.Level, msg string, fields []field) error
+}
+
+// handler flattens slog records into key/value fields for a recordWriter.
+type handler struct {
+	level  slog.Leveler
+	w      recordWriter
+	attrs  []field
+	prefix string
+}
+
+func newHandler(level slog.Leveler, w recordWriter) *handler {
+	return &handler{level: level, w: w}
+}
+
+func (h *handler) Enabled(_ context.Context, l slog.Level) bool {
+	return l >= h.level.Level()
+}
+
+func (h *handler) Handle(_ context.Context, r slog.Record) error {
+	fields := append([]field(nil), h.attrs...)
+	r.Attrs(func(a slog.Attr) bool {
+		fields = appendAttr(fields, h.prefix, a)
+		return true
+	})
+	return h.w.write(r.Time, r.Level, r.Message, fields)
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	n := *h
+	n.attrs = append([]field(nil), h.attrs...)
+	for _, a := range attrs {
+		n.attrs = appendAttr(n.attrs, h.prefix, a)
+	}
+	return &n
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	n := *h
+	n.prefix = h.prefix + name + "."
+	return &n
+}
+
+func appendAttr(fields []field, prefix string, a slog.Attr) []field {
+	v := a.Value.Resolve()
+	if v.Kind() == slog.KindGroup {
+		p := prefix
+		if a.Key != "" {
+			p += a.Key + "."
+		}
+		for _, ga := range v.Group() {
+			fields = appendAttr(fields, p, ga)
+		}
+		return fields
+	}
+	if a.Key == "" {
+		return fields
+	}
+	var s string
+	switch v.Kind() {
+	case slog.KindTime:
+		s = v.Time().UTC().Format(time.RFC3339Nano)
+	case slog.KindAny:
+		s = fmt.Sprint(v.Any())
+	default:
+		s = v.String()
+	}
+	return append(fields, field{Key: prefix + a.Key, Value: s})
+}
@@ -0,0 +1,115 @@
+// File: internal/logging/journald_linux.go (complete file)
+
+//go:build linux
+
+package logging
+
+import (
+	"errors"
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+const journalSocket = "/run/systemd/journal/socket"
+
+// journalWriter speaks the journald native protocol: one datagram of
+// KEY=value lines per entry. Entries too large for a datagram are written to
+// an unlinked temp file whose descriptor is passed instead.
+type journalWriter struct {
+	conn *net.UnixConn
+	addr *net.UnixAddr
+}
+
+func newJournalWriter() (*journalWriter, error) {
+	addr := &net.UnixAddr{Name: journalSocket, Net: "unixgram"}
+	if _, err := os.Stat(journalSocket); err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+	return &journalWriter{conn: conn, addr: addr}, nil
+}
+
+func (w *journalWriter) write(_ time.Time, level slog.Level, msg string, fields []field) error {
+	b := appendJournalField(nil, "MESSAGE", msg)
+	b = appendJournalField(b, "PRIORITY", strconv.Itoa(syslogSeverity(level)))
+	b = appendJournalField(b, "SYSLOG_IDENTIFIER", Identifier)
+	for _, f := range fields {
+		b = appendJournalField(b, journalFieldName(f.Key), f.Value)
+	}
+
+	_, _, err := w.conn.WriteMsgUnix(b, nil, w.addr)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, syscall.EMSGSIZE) && !errors.Is(err, syscall.ENOBUFS) {
+		return err
+	}
+	return w.writeViaFile(b)
+}
+
+func (w *journalWriter) writeViaFile(b []byte) error {
+	f, err := os.CreateTemp("/dev/shm", "vli-journal-")
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_ = os.Remove(f.Name())
+
+	if _, err := f.Write(b); err != nil {
+		return err
+	}
+	_, _, err = w.conn.WriteMsgUnix(nil, syscall.UnixRights(int(f.Fd())), w.addr)
+	return err
+}
+
+// appendJournalField encodes one field; values containing newlines use the
+// binary form: NAME\n, 64-bit little-endian length, value, \n.
+func appendJournalField(b []byte, name, value string) []byte {
+	for i := 0; i < len(value); i++ {
+		if value[i] == '\n' {
+			b = append(b, name...)
+			b = append(b, '\n')
+			n := uint64(len(value))
+			for j := 0; j < 8; j++ {
+				b = append(b, byte(n>>(8*j)))
+			}
+			b = append(b, value...)
+			return append(b, '\n')
+		}
+	}
+	b = append(b, name...)
+	b = append(b, '=')
+	b = append(b, value...)
+	return append(b, '\n')
+}
+
+// journalFieldName upper-cases a key and replaces anything outside [A-Z0-9_];
+// names may not start with '_' (trusted fields) or a digit.
+func journalFieldName(key string) string {
+	out := make([]byte, 0, len(key))
+	for i := 0; i < len(key) && len(out) < 64; i++ {
+		c := key[i]
+		switch {
+		case c >= 'a' && c <= 'z':
+			c -= 'a' - 'A'
+		case c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		default:
+			c = '_'
+		}
+		out = append(out, c)
+	}
+	for len(out) > 0 && (out[0] == '_' || (out[0] >= '0' && out[0] <= '9')) {
+		out = out[1:]
+	}
+	if len(out) == 0 {
+		return "FIELD"
+	}
+	return string(out)
+}
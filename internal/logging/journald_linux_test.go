@@ -0,0 +1,24 @@
+//go:build linux
+
+package logging
+
+import "testing"
+
+func TestJournalFieldEncoding(t *testing.T) {
+	if got := journalFieldName("event_kind"); got != "EVENT_KIND" {
+		t.Fatalf("field name = %q", got)
+	}
+	if got := journalFieldName("_9group.key"); got != "GROUP_KEY" {
+		t.Fatalf("field name = %q", got)
+	}
+
+	b := appendJournalField(nil, "MESSAGE", "one line")
+	if string(b) != "MESSAGE=one line\n" {
+		t.Fatalf("simple field = %q", b)
+	}
+	b = appendJournalField(nil, "MESSAGE", "a\nb")
+	want := "MESSAGE\n\x03\x00\x00\x00\x00\x00\x00\x00a\nb\n"
+	if string(b) != want {
+		t.Fatalf("binary field = %q", b)
+	}
+}
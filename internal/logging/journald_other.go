@@ -0,0 +1,21 @@
+// File: internal/logging/journald_other.go (complete file)
+
+//go:build !linux
+
+package logging
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+)
+
+type journalWriter struct{}
+
+func newJournalWriter() (*journalWriter, error) {
+	return nil, errors.New("journald is only available on Linux")
+}
+
+func (w *journalWriter) write(time.Time, slog.Level, string, []field) error {
+	return nil
+}
@@ -3,11 +3,19 @@
 package logging
 
 import (
+	"fmt"
 	"log/slog"
 	"os"
 	"strings"
 )
 
+// LevelCritical is above slog.LevelError and maps to syslog/journald "crit";
+// monitor leak events use it.
+const LevelCritical = slog.LevelError + 4
+
+// Identifier is the syslog APP-NAME / journald SYSLOG_IDENTIFIER.
+const Identifier = "vpnleakidentifier"
+
 // Setup creates a logger and sets it as the process-wide default.
 // This keeps the CLI usage simple while allowing packages to rely on slog.Default().
 func Setup(level string) {
@@ -15,18 +23,76 @@ func Setup(level string) {
 }
 
 func New(level string) *slog.Logger {
-	lvl := slog.LevelInfo
+	handler := slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: ParseLevel(level)})
+	return slog.New(handler)
+}
+
+// SetupOutput is Setup with a selectable destination:
+//
+//	stderr                  text on stderr (default)
+//	journald                native journal protocol (Linux)
+//	syslog                  RFC 5424 to the local /dev/log
+//	unix:///path            RFC 5424 over a unix socket
+//	udp://host:port         RFC 5424 over UDP
+//	tcp://host:port         RFC 5424 over TCP (octet-counted framing)
+func SetupOutput(level, output string) error {
+	lvl := ParseLevel(level)
+
+	var h slog.Handler
+	switch {
+	case output == "" || output == "stderr":
+		h = slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: lvl})
+	case output == "journald":
+		w, err := newJournalWriter()
+		if err != nil {
+			return fmt.Errorf("journald: %w", err)
+		}
+		h = newHandler(lvl, w)
+	case output == "syslog":
+		w, err := newSyslogWriter("unix", "/dev/log")
+		if err != nil {
+			return fmt.Errorf("syslog: %w", err)
+		}
+		h = newHandler(lvl, w)
+	default:
+		network, addr, ok := strings.Cut(output, "://")
+		if !ok || (network != "unix" && network != "udp" && network != "tcp") {
+			return fmt.Errorf("unknown log output %q", output)
+		}
+		w, err := newSyslogWriter(network, addr)
+		if err != nil {
+			return fmt.Errorf("syslog: %w", err)
+		}
+		h = newHandler(lvl, w)
+	}
+
+	slog.SetDefault(slog.New(h))
+	return nil
+}
+
+func ParseLevel(level string) slog.Level {
 	switch strings.ToLower(level) {
 	case "debug":
-		lvl = slog.LevelDebug
-	case "info":
-		lvl = slog.LevelInfo
+		return slog.LevelDebug
 	case "warn", "warning":
-		lvl = slog.LevelWarn
+		return slog.LevelWarn
 	case "error":
-		lvl = slog.LevelError
+		return slog.LevelError
 	}
+	return slog.LevelInfo
+}
 
-	handler := slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: lvl})
-	return slog.New(handler)
+// syslogSeverity maps a slog level to an RFC 5424 severity.
+func syslogSeverity(l slog.Level) int {
+	switch {
+	case l >= LevelCritical:
+		return 2
+	case l >= slog.LevelError:
+		return 3
+	case l >= slog.LevelWarn:
+		return 4
+	case l >= slog.LevelInfo:
+		return 6
+	}
+	return 7
 }
@@ -0,0 +1,90 @@
+package logging
+
+import (
+	"bufio"
+	"context"
+	"log/slog"
+	"net"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSyslog_RFC5424OverUDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip("udp unavailable:", err)
+	}
+	defer pc.Close()
+
+	w, err := newSyslogWriter("udp", pc.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := slog.New(newHandler(slog.LevelInfo, w))
+	log.Log(context.Background(), LevelCritical, "STUN mismatch", "event_kind", "stun_mismatch", "exit_ip", "198.51.100.7", "note", `a "quoted] value`)
+	log.Debug("filtered")
+
+	_ = pc.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, 2048)
+	n, _, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(buf[:n])
+
+	// daemon.crit = 3*8+2
+	re := regexp.MustCompile(`^<26>1 \S+Z \S+ vpnleakidentifier \d+ stun_mismatch \[vli@32473 event_kind="stun_mismatch" exit_ip="198\.51\.100\.7" note="a \\"quoted\\] value"\] STUN mismatch$`)
+	if !re.MatchString(got) {
+		t.Fatalf("unexpected syslog line: %s", got)
+	}
+}
+
+func TestSyslog_StreamFraming(t *testing.T) {
+	for _, tc := range []struct {
+		network string
+		listen  func() (net.Listener, error)
+		want    *regexp.Regexp
+	}{
+		{"tcp", func() (net.Listener, error) { return net.Listen("tcp", "127.0.0.1:0") },
+			regexp.MustCompile(`^(\d+) <30>1 .* hello$`)},
+		{"unix", func() (net.Listener, error) { return net.Listen("unix", filepath.Join(t.TempDir(), "log")) },
+			regexp.MustCompile(`^<30>1 .* hello\n$`)},
+	} {
+		ln, err := tc.listen()
+		if err != nil {
+			t.Logf("%s unavailable: %v", tc.network, err)
+			continue
+		}
+		defer ln.Close()
+
+		w, err := newSyslogWriter(tc.network, ln.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn, err := ln.Accept()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		if err := w.write(time.Now(), slog.LevelInfo, "hello", nil); err != nil {
+			t.Fatal(err)
+		}
+		w.conn.Close()
+
+		_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		var b strings.Builder
+		_, _ = bufio.NewReader(conn).WriteTo(&b)
+		got := b.String()
+		m := tc.want.FindStringSubmatch(got)
+		if m == nil {
+			t.Fatalf("%s: unexpected framing: %q", tc.network, got)
+		}
+		if tc.network == "tcp" && m[1] != strconv.Itoa(len(got)-len(m[1])-1) {
+			t.Fatalf("tcp: octet count %s does not match %q", m[1], got)
+		}
+	}
+}
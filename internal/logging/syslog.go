@@ -0,0 +1,150 @@
+// File: internal/logging/syslog.go (complete file)
+
+package logging
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// syslogFacility is "daemon" (3).
+	syslogFacility = 3
+	// sdID uses the documentation enterprise number from RFC 5612.
+	sdID = "vli@32473"
+)
+
+// syslogWriter sends RFC 5424 messages. Structured fields become SD-PARAMs
+// and an "event_kind" field becomes the MSGID.
+type syslogWriter struct {
+	network string
+	addr    string
+
+	mu       sync.Mutex
+	conn     net.Conn
+	hostname string
+	pid      int
+}
+
+func newSyslogWriter(network, addr string) (*syslogWriter, error) {
+	host, _ := os.Hostname()
+	if host == "" {
+		host = "-"
+	}
+	w := &syslogWriter{network: network, addr: addr, hostname: host, pid: os.Getpid()}
+	if err := w.connect(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *syslogWriter) connect() error {
+	if w.conn != nil {
+		_ = w.conn.Close()
+		w.conn = nil
+	}
+	if w.network == "unix" {
+		// /dev/log is a datagram socket; some daemons listen on a stream socket.
+		// The network is narrowed to the one that worked.
+		for _, n := range []string{"unixgram", "unix"} {
+			c, err := net.DialTimeout(n, w.addr, 5*time.Second)
+			if err == nil {
+				w.conn = c
+				w.network = n
+				return nil
+			}
+		}
+		return fmt.Errorf("cannot connect to %s", w.addr)
+	}
+	c, err := net.DialTimeout(w.network, w.addr, 5*time.Second)
+	if err != nil {
+		return err
+	}
+	w.conn = c
+	return nil
+}
+
+func (w *syslogWriter) write(t time.Time, level slog.Level, msg string, fields []field) error {
+	line := formatRFC5424(t, level, w.hostname, w.pid, msg, fields)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	switch w.network {
+	case "tcp":
+		// RFC 6587 octet counting.
+		line = fmt.Sprintf("%d %s", len(line), line)
+	case "unix":
+		// Local stream sockets (e.g. rsyslog's imuxsock) split on newlines.
+		line += "\n"
+	}
+
+	// One reconnect attempt covers a restarted syslog daemon.
+	for attempt := 0; attempt < 2; attempt++ {
+		if w.conn == nil {
+			if err := w.connect(); err != nil {
+				return err
+			}
+		}
+		_ = w.conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+		if _, err := w.conn.Write([]byte(line)); err == nil {
+			return nil
+		} else if attempt == 1 {
+			return err
+		}
+		_ = w.conn.Close()
+		w.conn = nil
+	}
+	return nil
+}
+
+// formatRFC5424 renders one syslog message:
+// <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID [SD] MSG
+func formatRFC5424(t time.Time, level slog.Level, hostname string, pid int, msg string, fields []field) string {
+	if t.IsZero() {
+		t = time.Now()
+	}
+	msgID := "-"
+	var sd strings.Builder
+	for _, f := range fields {
+		if f.Key == "event_kind" && f.Value != "" {
+			msgID = sdName(f.Value)
+		}
+		sd.WriteString(" " + sdName(f.Key) + `="` + sdEscape(f.Value) + `"`)
+	}
+	data := "-"
+	if sd.Len() > 0 {
+		data = "[" + sdID + sd.String() + "]"
+	}
+
+	pri := syslogFacility*8 + syslogSeverity(level)
+	return fmt.Sprintf("<%d>1 %s %s %s %d %s %s %s",
+		pri, t.UTC().Format("2006-01-02T15:04:05.000000Z07:00"), hostname, Identifier, pid, msgID, data, msg)
+}
+
+// sdName keeps printable ASCII except '=', ' ', ']' and '"', max 32 chars.
+func sdName(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r <= 32 || r >= 127 || r == '=' || r == ']' || r == '"' {
+			r = '_'
+		}
+		b.WriteRune(r)
+		if b.Len() == 32 {
+			break
+		}
+	}
+	if b.Len() == 0 {
+		return "_"
+	}
+	return b.String()
+}
+
+func sdEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(s)
+}
@@ -0,0 +1,234 @@
+// File: internal/monitor/classify.go (complete file)
+
+package monitor
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/baptistax/vpn-leak-identifier/internal/report"
+)
+
+type EventKind string
+
+const (
+	KindVPNExitChanged       EventKind = "vpn_exit_changed"
+	KindConnectivityLost     EventKind = "connectivity_lost"
+	KindConnectivityRestored EventKind = "connectivity_restored"
+	KindIPv6Appeared         EventKind = "ipv6_appeared"
+	KindDNSRecursorChanged   EventKind = "dns_recursor_changed"
+	KindSTUNMismatch         EventKind = "stun_mismatch"
+	KindLeakToKnownISP       EventKind = "leak_to_known_isp"
+
+	// KindChanged covers differences none of the typed kinds describe
+	// (e.g. dnsleaktest.com results).
+	KindChanged EventKind = "changed"
+)
+
+type Severity string
+
+const (
+	SeverityInfo     Severity = "info"
+	SeverityWarning  Severity = "warning"
+	SeverityCritical Severity = "critical"
+)
+
+// Change is one field that differs between two snapshots.
+type Change struct {
+	Field string `json:"field"`
+	From  string `json:"from,omitempty"`
+	To    string `json:"to,omitempty"`
+}
+
+// classify turns the difference between two snapshots into typed events.
+// lastOnline is the latest online snapshot up to prev; exits and IPv6 are
+// compared against it when prev is offline, so a restore through another
+// exit is still a vpn_exit_changed. knownISP lists IPs/CIDRs of the user's
+// real ISP; any observed address inside them is a leak.
+func classify(prev, cur, lastOnline *report.Snapshot, knownISP []*net.IPNet) []Event {
+	var out []Event
+	add := func(kind EventKind, sev Severity, msg string, changes ...Change) {
+		out = append(out, Event{Kind: kind, Severity: sev, Message: msg, Changes: changes})
+	}
+
+	prevOnline, curOnline := snapshotOnline(prev), snapshotOnline(cur)
+	switch {
+	case prevOnline && !curOnline:
+		add(KindConnectivityLost, SeverityWarning, "connectivity lost", Change{Field: "online", From: "true", To: "false"})
+	case !prevOnline && curOnline:
+		add(KindConnectivityRestored, SeverityInfo, "connectivity restored", Change{Field: "online", From: "false", To: "true"})
+	}
+
+	ref := prev
+	if !prevOnline {
+		ref = lastOnline
+	}
+	if ref != nil {
+		for _, family := range []string{"ipv4", "ipv6", "any"} {
+			a := findPublicIP(ref.PublicIPs, family)
+			b := findPublicIP(cur.PublicIPs, family)
+			if a != "" && b != "" && a != b {
+				add(KindVPNExitChanged, SeverityWarning, fmt.Sprintf("exit %s changed", family),
+					Change{Field: "exit." + family, From: a, To: b})
+			}
+		}
+
+		if findPublicIP(ref.PublicIPs, "ipv6") == "" {
+			if v6 := findPublicIP(cur.PublicIPs, "ipv6"); v6 != "" {
+				add(KindIPv6Appeared, SeverityWarning, "IPv6 connectivity appeared (may bypass an IPv4-only VPN)",
+					Change{Field: "exit.ipv6", To: v6})
+			}
+		}
+	}
+
+	// Empty recursor lists mean the lookup failed, not that the set changed.
+	// Per-lookup changes catch a recursor swap that only affects one query
+	// family or transport and leaves the union as it was.
+	var dnsChanges []Change
+	if len(prev.DNSRecursors) > 0 && len(cur.DNSRecursors) > 0 && !sameStringSet(prev.DNSRecursors, cur.DNSRecursors) {
+		dnsChanges = append(dnsChanges,
+			Change{Field: "dns_recursors", From: joinSorted(prev.DNSRecursors), To: joinSorted(cur.DNSRecursors)})
+	}
+	v6Only := true
+	lookups := report.DiffRecursors(prev.Observation, cur.Observation)
+	for _, d := range lookups {
+		field := "dns_recursors." + d.Family
+		if d.Transport != "" {
+			field += "." + d.Transport
+		}
+		dnsChanges = append(dnsChanges, Change{Field: field, From: joinSorted(d.From), To: joinSorted(d.To)})
+		v6Only = v6Only && d.Family == "ipv6"
+	}
+	switch {
+	case len(lookups) > 0 && v6Only:
+		add(KindDNSRecursorChanged, SeverityWarning, "DNS recursors changed for IPv6 (AAAA) queries", dnsChanges...)
+	case len(dnsChanges) > 0:
+		add(KindDNSRecursorChanged, SeverityWarning, "DNS recursors changed", dnsChanges...)
+	}
+
+	prevMismatch, curMismatch := stunMismatch(prev), stunMismatch(cur)
+	if len(curMismatch) > 0 && !sameStringSet(prevMismatch, curMismatch) {
+		add(KindSTUNMismatch, SeverityCritical, "STUN observed an address that is not the HTTP exit (WebRTC-style leak)",
+			Change{Field: "stun_observed", From: joinSorted(prev.StunObserved), To: joinSorted(cur.StunObserved)})
+	}
+
+	prevLeaks, curLeaks := knownISPHits(prev, knownISP), knownISPHits(cur, knownISP)
+	if len(curLeaks) > 0 && !sameStringSet(prevLeaks, curLeaks) {
+		changes := make([]Change, 0, len(curLeaks))
+		for _, hit := range curLeaks {
+			field, ip, _ := strings.Cut(hit, "=")
+			changes = append(changes, Change{Field: field, To: ip})
+		}
+		add(KindLeakToKnownISP, SeverityCritical, "traffic observed leaving through the known ISP", changes...)
+	}
+
+	if len(out) == 0 && changed(prev, cur) {
+		add(KindChanged, SeverityInfo, "snapshot changed")
+	}
+	return out
+}
+
+func snapshotOnline(s *report.Snapshot) bool {
+	for _, r := range s.PublicIPs {
+		if r.IP != "" && r.Error == "" {
+			return true
+		}
+	}
+	return len(s.StunObserved) > 0
+}
+
+func findPublicIP(list []report.PublicIPResult, family string) string {
+	for _, r := range list {
+		if r.Family == family && r.Error == "" {
+			return r.IP
+		}
+	}
+	return ""
+}
+
+// stunMismatch returns STUN-observed addresses not seen by any HTTP exit probe.
+// Snapshots without a successful HTTP probe cannot be compared.
+func stunMismatch(s *report.Snapshot) []string {
+	exits := map[string]bool{}
+	for _, r := range s.PublicIPs {
+		if r.IP != "" && r.Error == "" {
+			exits[r.IP] = true
+		}
+	}
+	if len(exits) == 0 {
+		return nil
+	}
+	var out []string
+	for _, ip := range s.StunObserved {
+		if !exits[ip] {
+			out = append(out, ip)
+		}
+	}
+	return out
+}
+
+// knownISPHits returns "field=ip" entries for every observed address inside knownISP.
+func knownISPHits(s *report.Snapshot, knownISP []*net.IPNet) []string {
+	if len(knownISP) == 0 {
+		return nil
+	}
+	var out []string
+	check := func(field, ip string) {
+		parsed := net.ParseIP(ip)
+		if parsed == nil {
+			return
+		}
+		for _, n := range knownISP {
+			if n.Contains(parsed) {
+				out = append(out, field+"="+ip)
+				return
+			}
+		}
+	}
+	for _, r := range s.PublicIPs {
+		if r.Error == "" {
+			check("exit."+r.Family, r.IP)
+		}
+	}
+	for _, ip := range s.DNSRecursors {
+		check("dns_recursors", ip)
+	}
+	for _, ip := range s.StunObserved {
+		check("stun_observed", ip)
+	}
+	return out
+}
+
+// ParseNetworks parses a list of IPs or CIDRs (single IPs become host routes).
+func ParseNetworks(list []string) ([]*net.IPNet, error) {
+	var out []*net.IPNet
+	for _, s := range list {
+		if !strings.Contains(s, "/") {
+			ip := net.ParseIP(s)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP or CIDR: %q", s)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 32
+			}
+			out = append(out, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, n)
+	}
+	return out, nil
+}
+
+func joinSorted(list []string) string {
+	cp := append([]string(nil), list...)
+	sort.Strings(cp)
+	return strings.Join(cp, ", ")
+}
@@ -0,0 +1,203 @@
+// File: internal/monitor/detector.go (complete file)
+
+package monitor
+
+import (
+	"net"
+	"time"
+
+	"github.com/baptistax/vpn-leak-identifier/internal/report"
+)
+
+const (
+	// KindUnstable is emitted once when changes start flapping; KindStable
+	// when they settle again. Both carry the number of changes involved.
+	KindUnstable EventKind = "unstable"
+	KindStable   EventKind = "stable"
+)
+
+// detector applies hysteresis and flap suppression on top of classify.
+//
+// A change is only emitted once the same new state has been observed
+// Confirmations times in a row, so one failed probe does not produce a
+// connectivity_lost/connectivity_restored pair. When more than FlapThreshold
+// changes are confirmed within FlapWindow, individual non-critical events are
+// replaced by a single "unstable" event until the state holds for FlapWindow.
+// Critical events (leaks) bypass both: they are classified against the
+// previous snapshot and emitted at once, so a leak seen in one snapshot is
+// reported even when the next one is clean.
+type detector struct {
+	confirmations int
+	flapWindow    time.Duration
+	flapThreshold int
+	knownISP      []*net.IPNet
+
+	confirmed *report.Snapshot
+	candidate *report.Snapshot
+	pending   int
+	// lastOnline is the latest confirmed snapshot that was online.
+	lastOnline *report.Snapshot
+	// last is the previous snapshot, confirmed or not.
+	last *report.Snapshot
+
+	recent     []time.Time
+	unstable   bool
+	suppressed int
+	lastChange time.Time
+}
+
+func newDetector(opt Options) *detector {
+	d := &detector{}
+	d.configure(opt)
+	return d
+}
+
+// configure applies (possibly reloaded) settings without touching the
+// confirmed state.
+func (d *detector) configure(opt Options) {
+	d.confirmations = opt.Confirmations
+	d.flapWindow = opt.FlapWindow
+	d.flapThreshold = opt.FlapThreshold
+	d.knownISP = opt.KnownISP
+	if d.confirmations <= 0 {
+		d.confirmations = 1
+	}
+}
+
+func (d *detector) observe(now time.Time, s *report.Snapshot) []Event {
+	var out []Event
+
+	if d.unstable && now.Sub(d.lastChange) >= d.flapWindow {
+		out = append(out, Event{
+			Kind:     KindStable,
+			Severity: SeverityInfo,
+			Message:  "state stabilized after flapping",
+			Count:    d.suppressed,
+		})
+		d.unstable = false
+		d.suppressed = 0
+		d.recent = nil
+	}
+
+	if d.confirmed == nil {
+		d.confirm(s)
+		d.last = s
+		return out
+	}
+
+	if d.last == nil {
+		// confirmed was preset without a previous snapshot.
+		d.last = d.confirmed
+	}
+	critical, _ := splitCritical(classify(d.last, s, d.lastOnline, d.knownISP))
+	for i := range critical {
+		critical[i].Previous = d.last
+		critical[i].Current = s
+	}
+	out = append(out, critical...)
+	d.last = s
+
+	_, events := splitCritical(classify(d.confirmed, s, d.lastOnline, d.knownISP))
+	if len(events) == 0 {
+		// Back to the confirmed state: drop any pending candidate.
+		d.candidate = nil
+		d.pending = 0
+		d.confirm(s)
+		return out
+	}
+
+	if d.candidate != nil && d.sameState(d.candidate, s) {
+		d.pending++
+	} else {
+		d.candidate = s
+		d.pending = 1
+	}
+	if d.pending < d.confirmations {
+		return out
+	}
+
+	prev := d.confirmed
+	d.confirm(s)
+	d.candidate = nil
+	d.pending = 0
+	for i := range events {
+		events[i].Previous = prev
+		events[i].Current = s
+	}
+
+	return append(out, d.applyFlap(now, events)...)
+}
+
+// splitCritical separates critical events from the ones subject to
+// hysteresis.
+func splitCritical(events []Event) (critical, rest []Event) {
+	for _, ev := range events {
+		if ev.Severity == SeverityCritical {
+			critical = append(critical, ev)
+		} else {
+			rest = append(rest, ev)
+		}
+	}
+	return critical, rest
+}
+
+// sameState reports whether b shows no non-critical change from a.
+func (d *detector) sameState(a, b *report.Snapshot) bool {
+	_, rest := splitCritical(classify(a, b, d.lastOnline, d.knownISP))
+	return len(rest) == 0
+}
+
+// resume seeds change detection with the confirmed state of a previous
+// session, as if s had just been observed. A nil state is ignored.
+func (d *detector) resume(st *State) {
+	if st == nil || st.Confirmed == nil {
+		return
+	}
+	d.confirm(st.Confirmed)
+	d.last = st.Confirmed
+}
+
+// confirm makes s the confirmed state.
+func (d *detector) confirm(s *report.Snapshot) {
+	d.confirmed = s
+	if snapshotOnline(s) {
+		d.lastOnline = s
+	}
+}
+
+func (d *detector) applyFlap(now time.Time, events []Event) []Event {
+	if d.flapThreshold <= 0 || d.flapWindow <= 0 {
+		return events
+	}
+
+	d.lastChange = now
+	cutoff := now.Add(-d.flapWindow)
+	kept := d.recent[:0]
+	for _, t := range d.recent {
+		if t.After(cutoff) {
+			kept = append(kept, t)
+		}
+	}
+	d.recent = append(kept, now)
+
+	var out []Event
+	if !d.unstable && len(d.recent) >= d.flapThreshold {
+		d.unstable = true
+		d.suppressed = len(d.recent)
+		out = append(out, Event{
+			Kind:     KindUnstable,
+			Severity: SeverityWarning,
+			Message:  "state is flapping; suppressing individual change events",
+			Count:    d.suppressed,
+			Previous: events[0].Previous,
+			Current:  events[0].Current,
+		})
+	} else if d.unstable {
+		d.suppressed++
+	}
+
+	if !d.unstable {
+		out = append(out, events...)
+	}
+	return out
+}
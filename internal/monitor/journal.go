@@ -0,0 +1,138 @@
+// File: internal/monitor/journal.go (complete file)
+
+package monitor
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/baptistax/vpn-leak-identifier/internal/report"
+)
+
+// Record is one line of the monitor JSONL log.
+type Record struct {
+	Type     string           `json:"type"` // snapshot|event|summary
+	AtUTC    time.Time        `json:"at_utc"`
+	Snapshot *report.Snapshot `json:"snapshot,omitempty"`
+	Timing   *Timing          `json:"timing,omitempty"`
+	Event    *Event           `json:"event,omitempty"`
+	Summary  *Summary         `json:"summary,omitempty"`
+}
+
+// State is what a restarted monitor needs to continue change detection.
+type State struct {
+	SavedUTC  time.Time        `json:"saved_utc"`
+	Confirmed *report.Snapshot `json:"confirmed"`
+	VPNExits  []string         `json:"vpn_exits,omitempty"`
+}
+
+// Journal appends snapshots and events to a JSONL file and keeps the last
+// confirmed state on disk so a restarted monitor resumes where it stopped.
+type Journal struct {
+	mu        sync.Mutex
+	f         *os.File
+	enc       *json.Encoder
+	statePath string
+	resumed   *State
+}
+
+// OpenJournal opens (appending) the log at logPath. If statePath holds a
+// previous state it is loaded for resumption; an empty statePath disables
+// state persistence.
+func OpenJournal(logPath, statePath string) (*Journal, error) {
+	if err := os.MkdirAll(filepath.Dir(logPath), 0o755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(logPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	j := &Journal{f: f, enc: json.NewEncoder(f), statePath: statePath}
+	if statePath != "" {
+		st, err := LoadState(statePath)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			_ = f.Close()
+			return nil, err
+		}
+		j.resumed = st
+	}
+	return j, nil
+}
+
+// Resumed returns the state loaded at open time, or nil.
+func (j *Journal) Resumed() *State {
+	if j == nil {
+		return nil
+	}
+	return j.resumed
+}
+
+func (j *Journal) Close() error {
+	if j == nil {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.f.Close()
+}
+
+func (j *Journal) append(r Record) {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	_ = j.enc.Encode(r)
+}
+
+func (j *Journal) appendSnapshot(s report.Snapshot, t Timing) {
+	j.append(Record{Type: "snapshot", AtUTC: s.TimestampUTC, Snapshot: &s, Timing: &t})
+}
+
+func (j *Journal) appendEvent(ev Event) {
+	// Snapshots are logged separately; keep event lines small.
+	ev.Previous = nil
+	ev.Current = nil
+	j.append(Record{Type: "event", AtUTC: ev.AtUTC, Event: &ev})
+}
+
+func (j *Journal) appendSummary(s Summary) {
+	j.append(Record{Type: "summary", AtUTC: s.EndedUTC, Summary: &s})
+}
+
+func (j *Journal) saveState(st State) {
+	if j == nil || j.statePath == "" {
+		return
+	}
+	st.SavedUTC = time.Now().UTC()
+	b, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return
+	}
+	// Write-then-rename so a crash never leaves a truncated state file.
+	tmp := j.statePath + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return
+	}
+	_ = os.Rename(tmp, j.statePath)
+}
+
+func LoadState(path string) (*State, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var st State
+	if err := json.Unmarshal(b, &st); err != nil {
+		return nil, err
+	}
+	if st.Confirmed == nil {
+		return nil, errors.New("monitor state has no snapshot")
+	}
+	return &st, nil
+}
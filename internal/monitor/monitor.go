@@ -4,60 +4,200 @@ package monitor
 
 import (
 	"context"
+	"log/slog"
+	"net"
 	"time"
 
 	"github.com/baptistax/vpn-leak-identifier/internal/app"
+	"github.com/baptistax/vpn-leak-identifier/internal/netwatch"
 	"github.com/baptistax/vpn-leak-identifier/internal/report"
 )
 
+// Event is one classified change between consecutive snapshots. A single
+// snapshot can produce several events (e.g. exit changed and IPv6 appeared).
 type Event struct {
-	AtUTC    time.Time
-	Kind     string // "changed"
-	Message  string
-	Previous *report.Snapshot
-	Current  *report.Snapshot
+	AtUTC    time.Time        `json:"at_utc"`
+	Kind     EventKind        `json:"kind"`
+	Severity Severity         `json:"severity"`
+	Message  string           `json:"message"`
+	Changes  []Change         `json:"changes,omitempty"`
+	Count    int              `json:"count,omitempty"` // changes covered by unstable/stable events
+	Previous *report.Snapshot `json:"previous,omitempty"`
+	Current  *report.Snapshot `json:"current,omitempty"`
+
+	// Kernel network events that triggered the snapshot (empty for ticks).
+	Triggers []netwatch.Event `json:"triggers,omitempty"`
+	// How and when the snapshot behind this event ran.
+	Timing Timing `json:"timing"`
 }
 
 type Options struct {
 	Interval time.Duration
 	Timeout  time.Duration
 	Snapshot app.SnapshotOptions
+
+	// FullInterval is how often the expensive probes (STUN, dnsleaktest.com)
+	// run; snapshots in between only refresh exit IPs and DNS recursors and
+	// carry the last expensive results forward. Zero runs every probe each time.
+	FullInterval time.Duration
+
+	// WatchKernel takes an extra snapshot as soon as the kernel reports a
+	// default route, tunnel link or address change (Linux rtnetlink).
+	WatchKernel bool
+	// Settle coalesces a burst of kernel events into one snapshot.
+	Settle time.Duration
+
+	// KnownISP holds the user's real (non-VPN) networks for leak_to_known_isp.
+	KnownISP []*net.IPNet
+
+	// Confirmations is how many consecutive snapshots must agree on a new
+	// state before it is reported (1 reports every change immediately).
+	Confirmations int
+	// FlapThreshold changes within FlapWindow mark the state as unstable.
+	// Zero disables flap detection.
+	FlapThreshold int
+	FlapWindow    time.Duration
+
+	// Journal, when set, receives every snapshot and event and persists the
+	// confirmed state; a state loaded from it seeds change detection.
+	Journal *Journal
+
+	// OnSnapshot, when set, is called with every completed snapshot before
+	// its events are classified (e.g. to update exporter metrics).
+	OnSnapshot func(report.Snapshot, Timing)
+	// OnStatus, when set, is called with the current state after every
+	// snapshot (e.g. for the systemd STATUS line).
+	OnStatus func(Status)
+
+	// Reload delivers new settings while running (e.g. on SIGHUP). Interval,
+	// probe options, KnownISP and the hysteresis/flap settings take effect
+	// for the next snapshot; Journal, callbacks and WatchKernel are kept.
+	Reload <-chan Options
 }
 
-func Run(ctx context.Context, opt Options, onEvent func(Event)) {
-	var prev *report.Snapshot
-
-	ticker := time.NewTicker(opt.Interval)
-	defer ticker.Stop()
-
-	take := func() {
-		snapCtx, cancel := context.WithTimeout(ctx, opt.Timeout)
-		s := app.TakeSnapshot(snapCtx, opt.Snapshot)
-		cancel()
-
-		if prev != nil {
-			if changed(prev, &s) {
-				onEvent(Event{
-					AtUTC:    time.Now().UTC(),
-					Kind:     "changed",
-					Message:  "snapshot changed",
-					Previous: prev,
-					Current:  &s,
-				})
-			}
+// Run snapshots until ctx is done, calling onEvent for every classified
+// change, and returns a summary of the session.
+func Run(ctx context.Context, opt Options, onEvent func(Event)) Summary {
+	det := newDetector(opt)
+	resumed := opt.Journal.Resumed()
+	det.resume(resumed)
+	sum := newSummarizer(opt.KnownISP, resumed)
+
+	var kernel <-chan netwatch.Event
+	if opt.WatchKernel {
+		ch, err := netwatch.Subscribe(ctx)
+		if err != nil {
+			slog.Debug("kernel network events unavailable, polling only", "err", err)
+		} else {
+			kernel = ch
 		}
-		prev = &s
+	}
+	settle := opt.Settle
+	if settle <= 0 {
+		settle = 250 * time.Millisecond
+	}
+
+	sched := newScheduler(ctx, opt)
+	defer sched.stop()
+
+	handle := func(r snapshotResult) {
+		now := time.Now().UTC()
+		opt.Journal.appendSnapshot(r.snapshot, r.timing)
+		if opt.OnSnapshot != nil {
+			opt.OnSnapshot(r.snapshot, r.timing)
+		}
+		sum.observe(&r.snapshot)
+		for _, ev := range det.observe(now, &r.snapshot) {
+			ev.AtUTC = now
+			ev.Triggers = r.triggers
+			ev.Timing = r.timing
+			sum.countEvent(ev)
+			opt.Journal.appendEvent(ev)
+			onEvent(ev)
+		}
+		opt.Journal.saveState(State{Confirmed: det.confirmed, VPNExits: sum.sum.VPNExits})
+		if opt.OnStatus != nil {
+			opt.OnStatus(sum.status())
+		}
+	}
+	reload := func(n Options) {
+		n.Journal = opt.Journal
+		n.OnSnapshot = opt.OnSnapshot
+		n.OnStatus = opt.OnStatus
+		n.Reload = opt.Reload
+		n.WatchKernel = opt.WatchKernel
+		opt = n
+		det.configure(opt)
+		sched.reconfigure(opt)
+		sum.knownISP = opt.KnownISP
+		slog.Info("monitor configuration reloaded", "interval", opt.Interval, "full_interval", opt.FullInterval)
+	}
+	finish := func() Summary {
+		out := sum.finish()
+		opt.Journal.appendSummary(out)
+		return out
 	}
 
 	// First snapshot immediately.
-	take()
+	sched.start(ScheduleInitial, time.Now().UTC(), nil)
 
 	for {
 		select {
 		case <-ctx.Done():
-			return
-		case <-ticker.C:
-			take()
+			return finish()
+		case at := <-sched.ticks():
+			if sched.running {
+				sched.skip()
+				continue
+			}
+			sched.start(ScheduleTick, at.UTC(), nil)
+		case ev, ok := <-kernel:
+			if !ok {
+				kernel = nil
+				continue
+			}
+			if sched.running {
+				// Run once the in-flight snapshot completes.
+				sched.queue(ev)
+				continue
+			}
+			sched.start(ScheduleKernel, time.Now().UTC(), collectBurst(ctx, kernel, ev, settle))
+		case n, ok := <-opt.Reload:
+			if !ok {
+				opt.Reload = nil
+				continue
+			}
+			reload(n)
+		case r := <-sched.results:
+			recheck := sched.complete(&r)
+			handle(r)
+			if pending := sched.takeQueued(); len(pending) > 0 {
+				sched.start(ScheduleKernel, time.Now().UTC(), pending)
+			} else if recheck {
+				sched.start(ScheduleRecheck, time.Now().UTC(), nil)
+			}
+		}
+	}
+}
+
+// collectBurst gathers events arriving within settle of the first one, since a
+// VPN going down typically produces link, address and route events together.
+func collectBurst(ctx context.Context, ch <-chan netwatch.Event, first netwatch.Event, settle time.Duration) []netwatch.Event {
+	events := []netwatch.Event{first}
+	t := time.NewTimer(settle)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return events
+		case <-t.C:
+			return events
+		case ev, ok := <-ch:
+			if !ok {
+				return events
+			}
+			events = append(events, ev)
 		}
 	}
 }
@@ -69,13 +209,20 @@ func changed(a, b *report.Snapshot) bool {
 	if !samePublicIPs(a.PublicIPs, b.PublicIPs) {
 		return true
 	}
-	if !sameStringSet(a.DnsRecursors, b.DnsRecursors) {
+	if !sameStringSet(a.DNSRecursors, b.DNSRecursors) || len(report.DiffRecursors(a.Observation, b.Observation)) > 0 {
 		return true
 	}
 	if !sameStringSet(a.StunObserved, b.StunObserved) {
 		return true
 	}
 
+	// The default route moving to another interface (e.g. off the tunnel).
+	for _, family := range []string{"ipv4", "ipv6"} {
+		if a.EgressInterface(family) != b.EgressInterface(family) {
+			return true
+		}
+	}
+
 	// dnsleaktest.com data can be noisy; compare only observed IP addresses.
 	if !sameDNSLeakIPs(a.DnsLeak, b.DnsLeak) {
 		return true
@@ -89,7 +236,12 @@ func samePublicIPs(a, b []report.PublicIPResult) bool {
 		return false
 	}
 	for i := range a {
-		if a[i].Family != b[i].Family || a[i].IP != b[i].IP || a[i].Error != b[i].Error {
+		if a[i].Family != b[i].Family || a[i].IP != b[i].IP {
+			return false
+		}
+		// A failed probe is an error state, not a value: differing error
+		// texts (timeout vs refused) are the same state.
+		if (a[i].Error == "") != (b[i].Error == "") {
 			return false
 		}
 	}
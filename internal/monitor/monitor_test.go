@@ -3,6 +3,11 @@
 package monitor
 
 import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -34,21 +39,444 @@ func TestChanged_NoChange(t *testing.T) {
 		PublicIPs: []report.PublicIPResult{
 			{Source: "ipify", Family: "ipv4", IP: "1.1.1.1"},
 		},
-		DnsRecursors: []string{"9.9.9.9"},
-		StunObserved: []string{"1.1.1.1"},
-		DnsLeak:      []report.DnsLeakServer{{IPAddress: "9.9.9.9"}},
+		Observation: report.Observation{DNSRecursors: []string{"9.9.9.9"}, StunObserved: []string{"1.1.1.1"}},
+		DnsLeak:     []report.DnsLeakServer{{IPAddress: "9.9.9.9"}},
 	}
 	b := &report.Snapshot{
 		TimestampUTC: time.Now().UTC(),
 		PublicIPs: []report.PublicIPResult{
 			{Source: "ipify", Family: "ipv4", IP: "1.1.1.1"},
 		},
-		DnsRecursors: []string{"9.9.9.9"},
-		StunObserved: []string{"1.1.1.1"},
-		DnsLeak:      []report.DnsLeakServer{{IPAddress: "9.9.9.9"}},
+		Observation: report.Observation{DNSRecursors: []string{"9.9.9.9"}, StunObserved: []string{"1.1.1.1"}},
+		DnsLeak:     []report.DnsLeakServer{{IPAddress: "9.9.9.9"}},
 	}
 
 	if changed(a, b) {
 		t.Fatalf("expected no change")
 	}
 }
+
+func TestClassify_ExitChangeAndKnownISP(t *testing.T) {
+	known, err := ParseNetworks([]string{"203.0.113.0/24"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := &report.Snapshot{
+		PublicIPs: []report.PublicIPResult{
+			{Source: "ipify", Family: "ipv4", IP: "198.51.100.7"},
+			{Source: "ipify", Family: "ipv6", Error: "disabled"},
+		},
+	}
+	b := &report.Snapshot{
+		PublicIPs: []report.PublicIPResult{
+			{Source: "ipify", Family: "ipv4", IP: "203.0.113.9"},
+			{Source: "ipify", Family: "ipv6", Error: "disabled"},
+		},
+	}
+
+	events := classify(a, b, nil, known)
+	kinds := map[EventKind]Severity{}
+	for _, ev := range events {
+		kinds[ev.Kind] = ev.Severity
+	}
+	if kinds[KindVPNExitChanged] != SeverityWarning {
+		t.Fatalf("expected vpn_exit_changed, got %+v", events)
+	}
+	if kinds[KindLeakToKnownISP] != SeverityCritical {
+		t.Fatalf("expected leak_to_known_isp, got %+v", events)
+	}
+}
+
+func TestClassify_STUNMismatchAndConnectivity(t *testing.T) {
+	a := &report.Snapshot{
+		PublicIPs:   []report.PublicIPResult{{Source: "ipify", Family: "ipv4", IP: "198.51.100.7"}},
+		Observation: report.Observation{StunObserved: []string{"198.51.100.7"}},
+	}
+	b := &report.Snapshot{
+		PublicIPs:   []report.PublicIPResult{{Source: "ipify", Family: "ipv4", IP: "198.51.100.7"}},
+		Observation: report.Observation{StunObserved: []string{"198.51.100.7", "192.0.2.44"}},
+	}
+	events := classify(a, b, nil, nil)
+	if len(events) != 1 || events[0].Kind != KindSTUNMismatch {
+		t.Fatalf("expected a single stun_mismatch, got %+v", events)
+	}
+
+	c := &report.Snapshot{
+		PublicIPs: []report.PublicIPResult{{Source: "ipify", Family: "ipv4", Error: "timeout"}},
+	}
+	events = classify(a, c, nil, nil)
+	if len(events) != 1 || events[0].Kind != KindConnectivityLost {
+		t.Fatalf("expected a single connectivity_lost, got %+v", events)
+	}
+}
+
+func TestClassify_IPv6OnlyRecursorChange(t *testing.T) {
+	snap := func(any6, ns6 string) *report.Snapshot {
+		s := &report.Snapshot{PublicIPs: []report.PublicIPResult{{Source: "ipify", Family: "ipv4", IP: "198.51.100.7"}}}
+		s.SetRecursors([]report.RecursorResult{
+			{Query: "ns.ident.me", Family: "ipv4", Transport: "any", IPs: []string{"198.51.100.53"}},
+			{Query: "ns.ident.me", Family: "ipv6", Transport: "any", IPs: []string{any6}},
+			{Query: "ns6.ident.me", Family: "ipv6", Transport: "ipv6", IPs: []string{ns6}},
+		})
+		return s
+	}
+	// The union stays the same; only which recursor answers each AAAA
+	// lookup moves.
+	a := snap("2001:db8::53", "2001:db8:ffff::53")
+	b := snap("2001:db8:ffff::53", "2001:db8::53")
+	if !changed(a, b) {
+		t.Fatal("expected a per-lookup recursor change to count as a change")
+	}
+
+	events := classify(a, b, nil, nil)
+	if len(events) != 1 || events[0].Kind != KindDNSRecursorChanged {
+		t.Fatalf("expected a single dns_recursor_changed, got %+v", events)
+	}
+	if !strings.Contains(events[0].Message, "IPv6") {
+		t.Fatalf("message = %q", events[0].Message)
+	}
+	var fields []string
+	for _, c := range events[0].Changes {
+		fields = append(fields, c.Field)
+	}
+	if got := strings.Join(fields, ","); got != "dns_recursors.ipv6.any,dns_recursors.ipv6.ipv6" {
+		t.Fatalf("fields = %s", got)
+	}
+}
+
+func TestDetector_RestoreThroughNewExit(t *testing.T) {
+	vpn := &report.Snapshot{PublicIPs: []report.PublicIPResult{
+		{Source: "ipify", Family: "ipv4", IP: "198.51.100.7"},
+		{Source: "ipify", Family: "ipv6", IP: "2001:db8::7"},
+	}}
+	down := &report.Snapshot{PublicIPs: []report.PublicIPResult{
+		{Source: "ipify", Family: "ipv4", Error: "timeout"},
+		{Source: "ipify", Family: "ipv6", Error: "timeout"},
+	}}
+	isp := &report.Snapshot{PublicIPs: []report.PublicIPResult{
+		{Source: "ipify", Family: "ipv4", IP: "203.0.113.9"},
+		{Source: "ipify", Family: "ipv6", IP: "2001:db8::7"},
+	}}
+
+	d := newDetector(Options{Confirmations: 1})
+	now := time.Now()
+	d.observe(now, vpn)
+	if evs := d.observe(now.Add(5*time.Second), down); len(evs) != 1 || evs[0].Kind != KindConnectivityLost {
+		t.Fatalf("expected connectivity_lost, got %+v", evs)
+	}
+
+	// The exit is compared with the last online snapshot, not the offline
+	// one; IPv6 was there before the loss, so it did not appear.
+	evs := d.observe(now.Add(10*time.Second), isp)
+	kinds := map[EventKind]Change{}
+	for _, ev := range evs {
+		for _, c := range ev.Changes {
+			kinds[ev.Kind] = c
+		}
+	}
+	if _, ok := kinds[KindConnectivityRestored]; !ok || len(evs) != 2 {
+		t.Fatalf("expected connectivity_restored and vpn_exit_changed, got %+v", evs)
+	}
+	if c := kinds[KindVPNExitChanged]; c.From != "198.51.100.7" || c.To != "203.0.113.9" {
+		t.Fatalf("vpn_exit_changed = %+v", c)
+	}
+}
+
+func TestDetector_ShortLeakIsNotHeldBack(t *testing.T) {
+	clean := &report.Snapshot{
+		PublicIPs:   []report.PublicIPResult{{Source: "ipify", Family: "ipv4", IP: "198.51.100.7"}},
+		Observation: report.Observation{StunObserved: []string{"198.51.100.7"}},
+	}
+	leak := &report.Snapshot{
+		PublicIPs:   []report.PublicIPResult{{Source: "ipify", Family: "ipv4", IP: "198.51.100.7"}},
+		Observation: report.Observation{StunObserved: []string{"198.51.100.7", "192.0.2.44"}},
+	}
+
+	d := newDetector(Options{Confirmations: 2, FlapThreshold: 3, FlapWindow: time.Minute})
+	now := time.Now()
+	var kinds []EventKind
+	for i, s := range []*report.Snapshot{clean, leak, clean, leak, leak, clean} {
+		for _, ev := range d.observe(now.Add(time.Duration(i)*5*time.Second), s) {
+			kinds = append(kinds, ev.Kind)
+		}
+	}
+	// Each leak is reported once, on the snapshot that shows it, whether
+	// it lasts one snapshot or two.
+	if len(kinds) != 2 || kinds[0] != KindSTUNMismatch || kinds[1] != KindSTUNMismatch {
+		t.Fatalf("expected two stun_mismatch events, got %v", kinds)
+	}
+}
+
+func TestDetector_PresetConfirmedState(t *testing.T) {
+	vpn := &report.Snapshot{PublicIPs: []report.PublicIPResult{{Source: "ipify", Family: "ipv4", IP: "198.51.100.7"}}}
+	d := newDetector(Options{})
+	d.confirmed = vpn
+	if evs := d.observe(time.Now(), vpn); len(evs) != 0 {
+		t.Fatalf("expected no events, got %+v", evs)
+	}
+}
+
+func TestDetector_ConfirmationsAndFlap(t *testing.T) {
+	vpn := &report.Snapshot{PublicIPs: []report.PublicIPResult{{Source: "ipify", Family: "ipv4", IP: "198.51.100.7"}}}
+	down := &report.Snapshot{PublicIPs: []report.PublicIPResult{{Source: "ipify", Family: "ipv4", Error: "timeout"}}}
+
+	d := newDetector(Options{Confirmations: 2, FlapThreshold: 3, FlapWindow: time.Minute})
+	now := time.Now()
+	step := func(s *report.Snapshot) []Event {
+		now = now.Add(5 * time.Second)
+		return d.observe(now, s)
+	}
+
+	step(vpn)
+	// A single failed probe is not confirmed.
+	if evs := step(down); len(evs) != 0 {
+		t.Fatalf("expected no events for an unconfirmed change, got %+v", evs)
+	}
+	if evs := step(vpn); len(evs) != 0 {
+		t.Fatalf("expected no events after returning to the confirmed state, got %+v", evs)
+	}
+
+	// Repeated confirmed flips turn into a single unstable event.
+	var kinds []EventKind
+	for i := 0; i < 4; i++ {
+		s := down
+		if i%2 == 1 {
+			s = vpn
+		}
+		for _, ev := range append(step(s), step(s)...) {
+			kinds = append(kinds, ev.Kind)
+		}
+	}
+	want := []EventKind{KindConnectivityLost, KindConnectivityRestored, KindUnstable}
+	if len(kinds) != len(want) {
+		t.Fatalf("expected %v, got %v", want, kinds)
+	}
+	for i := range want {
+		if kinds[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, kinds)
+		}
+	}
+}
+
+func TestScheduler_CarriesSTUNOnlyWhileExitHolds(t *testing.T) {
+	full := report.Snapshot{
+		PublicIPs:   []report.PublicIPResult{{Source: "ipify", Family: "ipv4", IP: "198.51.100.7"}},
+		Observation: report.Observation{StunObserved: []string{"198.51.100.7"}},
+	}
+	at := time.Now().UTC()
+	s := &scheduler{}
+	s.skip()
+	r := snapshotResult{snapshot: full, timing: Timing{Full: true, ScheduledUTC: at}}
+	if s.complete(&r) || r.timing.SkippedTicks != 1 {
+		t.Fatalf("full snapshot: skipped = %d", r.timing.SkippedTicks)
+	}
+
+	// Same exit: STUN is carried from the full snapshot.
+	fast := snapshotResult{snapshot: report.Snapshot{PublicIPs: full.PublicIPs}}
+	if s.complete(&fast) || len(fast.snapshot.StunObserved) != 1 || fast.timing.SkippedTicks != 0 {
+		t.Fatalf("fast snapshot = %+v", fast)
+	}
+
+	// New exit: the old STUN address would look like a leak next to it.
+	moved := snapshotResult{snapshot: report.Snapshot{
+		PublicIPs: []report.PublicIPResult{{Source: "ipify", Family: "ipv4", IP: "198.51.100.99"}},
+	}}
+	if !s.complete(&moved) {
+		t.Fatal("expected a recheck after the exit changed")
+	}
+	if len(moved.snapshot.StunObserved) != 0 {
+		t.Fatalf("stale STUN carried: %v", moved.snapshot.StunObserved)
+	}
+	if evs := classify(&full, &moved.snapshot, nil, nil); len(evs) != 1 || evs[0].Kind != KindVPNExitChanged {
+		t.Fatalf("expected only vpn_exit_changed, got %+v", evs)
+	}
+}
+
+func TestSummarizer_LeakTimeFollowsLeakSignals(t *testing.T) {
+	known, err := ParseNetworks([]string{"203.0.113.0/24"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	snap := func(at time.Time, exit string, stun ...string) *report.Snapshot {
+		s := &report.Snapshot{TimestampUTC: at, Observation: report.Observation{StunObserved: stun}}
+		if exit == "" {
+			s.PublicIPs = []report.PublicIPResult{{Source: "ipify", Family: "ipv4", Error: "timeout"}}
+		} else {
+			s.PublicIPs = []report.PublicIPResult{{Source: "ipify", Family: "ipv4", IP: exit}}
+		}
+		return s
+	}
+
+	start := time.Now().UTC().Add(-time.Hour)
+	at := func(min int) time.Time { return start.Add(time.Duration(min) * time.Minute) }
+	s := newSummarizer(known, nil)
+	s.observe(snap(at(0), "198.51.100.7"))
+	// The VPN changes server: not a leak.
+	s.observe(snap(at(10), "198.51.100.99"))
+	// STUN sees another address than the HTTP exit.
+	s.observe(snap(at(20), "198.51.100.99", "198.51.100.99", "192.0.2.44"))
+	s.observe(snap(at(25), ""))
+	// Traffic leaves through the known ISP.
+	s.observe(snap(at(30), "203.0.113.9"))
+	s.observe(snap(at(40), "198.51.100.99"))
+	// Recursors inside the ISP network, without any exit change.
+	dnsLeak := snap(at(50), "198.51.100.99")
+	dnsLeak.DNS = &report.DNSAssessment{Verdict: report.DNSLeak}
+	s.observe(dnsLeak)
+	s.observe(snap(at(52), "198.51.100.99"))
+	if st := s.status(); !st.Online || st.Leaking || st.Leaks != 3 {
+		t.Fatalf("status = %+v", st)
+	}
+
+	sum := s.finish()
+	if sum.Snapshots != 8 || sum.Leaks != 3 {
+		t.Fatalf("summary = %+v", sum)
+	}
+	if got := sum.LeakedSeconds; got != 17*60 {
+		t.Fatalf("leaked %ds, want %ds", got, 17*60)
+	}
+	if got := sum.OfflineSeconds; got != 5*60 {
+		t.Fatalf("offline %ds, want %ds", got, 5*60)
+	}
+	if sum.OnVPNSeconds < 38*60 || sum.OnVPNSeconds > 39*60 {
+		t.Fatalf("on VPN %ds", sum.OnVPNSeconds)
+	}
+	if strings.Join(sum.VPNExits, ",") != "198.51.100.7,198.51.100.99" {
+		t.Fatalf("vpn exits = %v", sum.VPNExits)
+	}
+}
+
+func TestSummarizer_StartOffVPN(t *testing.T) {
+	// Starting on the bare connection does not make the VPN look like a leak
+	// later on; only a known ISP network can tell the two apart.
+	known, _ := ParseNetworks([]string{"203.0.113.0/24"})
+	start := time.Now().UTC().Add(-time.Hour)
+	s := newSummarizer(known, nil)
+	s.observe(&report.Snapshot{TimestampUTC: start, PublicIPs: []report.PublicIPResult{{Family: "ipv4", IP: "203.0.113.9"}}})
+	vpn := &report.Snapshot{TimestampUTC: start.Add(time.Minute), PublicIPs: []report.PublicIPResult{{Family: "ipv4", IP: "198.51.100.7"}}}
+	vpn.ExitV4 = report.ExitInfo{Family: "ipv4", IP: "198.51.100.7", Geo: report.GeoInfo{ASN: "AS64500", CountryCode: "NL"}}
+	s.observe(vpn)
+	sum := s.finish()
+	if sum.LeakedSeconds != 60 || sum.Leaks != 1 {
+		t.Fatalf("summary = %+v", sum)
+	}
+	if len(sum.VPNExits) != 1 || sum.VPNExits[0] != "198.51.100.7" || sum.ExitGeo["198.51.100.7"].ASN != "AS64500" {
+		t.Fatalf("vpn exits = %v, geo = %v", sum.VPNExits, sum.ExitGeo)
+	}
+}
+
+func TestJournal_AppendAndResume(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "log", "monitor.jsonl")
+	statePath := filepath.Join(dir, "state.json")
+
+	j, err := OpenJournal(logPath, statePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if j.Resumed() != nil {
+		t.Fatal("nothing to resume from yet")
+	}
+	snap := report.Snapshot{
+		TimestampUTC: time.Now().UTC(),
+		PublicIPs:    []report.PublicIPResult{{Source: "ipify", Family: "ipv4", IP: "198.51.100.7"}},
+	}
+	j.appendSnapshot(snap, Timing{Reason: ScheduleTick, Full: true})
+	j.appendEvent(Event{Kind: KindVPNExitChanged, Severity: SeverityWarning, Previous: &snap, Current: &snap})
+	j.appendSummary(Summary{Snapshots: 1})
+	j.saveState(State{Confirmed: &snap, VPNExits: []string{"198.51.100.7"}})
+	if err := j.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var types []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var r Record
+		if err := json.Unmarshal(sc.Bytes(), &r); err != nil {
+			t.Fatal(err)
+		}
+		if r.Type == "event" && (r.Event.Previous != nil || r.Event.Current != nil) {
+			t.Fatalf("event line carries snapshots: %s", sc.Text())
+		}
+		types = append(types, r.Type)
+	}
+	if strings.Join(types, ",") != "snapshot,event,summary" {
+		t.Fatalf("records = %v", types)
+	}
+	if _, err := os.Stat(statePath + ".tmp"); !os.IsNotExist(err) {
+		t.Fatalf("temporary state file left behind: %v", err)
+	}
+
+	// A restarted monitor picks up the confirmed state and the VPN exits.
+	j, err = OpenJournal(logPath, statePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+	st := j.Resumed()
+	if st == nil || findPublicIP(st.Confirmed.PublicIPs, "ipv4") != "198.51.100.7" || st.SavedUTC.IsZero() {
+		t.Fatalf("resumed = %+v", st)
+	}
+	if sum := newSummarizer(nil, st).finish(); len(sum.VPNExits) != 1 || sum.VPNExits[0] != "198.51.100.7" {
+		t.Fatalf("resumed summary exits = %v", sum.VPNExits)
+	}
+}
+
+func TestDetector_ResumesFromJournal(t *testing.T) {
+	dir := t.TempDir()
+	logPath, statePath := filepath.Join(dir, "monitor.jsonl"), filepath.Join(dir, "state.json")
+	vpn := report.Snapshot{PublicIPs: []report.PublicIPResult{{Source: "ipify", Family: "ipv4", IP: "198.51.100.7"}}}
+	j, err := OpenJournal(logPath, statePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j.saveState(State{Confirmed: &vpn})
+	_ = j.Close()
+
+	// A restarted monitor, seeded as Run does.
+	j, err = OpenJournal(logPath, statePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+	d := newDetector(Options{Confirmations: 1})
+	d.resume(j.Resumed())
+
+	down := &report.Snapshot{PublicIPs: []report.PublicIPResult{{Source: "ipify", Family: "ipv4", Error: "timeout"}}}
+	moved := &report.Snapshot{PublicIPs: []report.PublicIPResult{{Source: "ipify", Family: "ipv4", IP: "203.0.113.9"}}}
+	now := time.Now()
+	if evs := d.observe(now, down); len(evs) != 1 || evs[0].Kind != KindConnectivityLost {
+		t.Fatalf("expected connectivity_lost after resuming, got %+v", evs)
+	}
+	// The resumed snapshot is the last online one, so the new exit shows.
+	kinds := map[EventKind]bool{}
+	for _, ev := range d.observe(now.Add(5*time.Second), moved) {
+		kinds[ev.Kind] = true
+	}
+	if len(kinds) != 2 || !kinds[KindConnectivityRestored] || !kinds[KindVPNExitChanged] {
+		t.Fatalf("expected connectivity_restored and vpn_exit_changed, got %v", kinds)
+	}
+}
+
+func TestLoadState_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	empty := filepath.Join(dir, "empty.json")
+	if err := os.WriteFile(empty, []byte(`{"saved_utc":"2024-01-01T00:00:00Z"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadState(empty); err == nil {
+		t.Fatal("expected an error for a state without a snapshot")
+	}
+	if _, err := OpenJournal(filepath.Join(dir, "monitor.jsonl"), empty); err == nil {
+		t.Fatal("expected OpenJournal to reject a bad state file")
+	}
+	if _, err := LoadState(filepath.Join(dir, "missing.json")); !os.IsNotExist(err) {
+		t.Fatalf("missing state: %v", err)
+	}
+}
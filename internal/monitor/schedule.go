@@ -0,0 +1,178 @@
+// File: internal/monitor/schedule.go (complete file)
+
+package monitor
+
+import (
+	"context"
+	"time"
+
+	"github.com/baptistax/vpn-leak-identifier/internal/app"
+	"github.com/baptistax/vpn-leak-identifier/internal/netwatch"
+	"github.com/baptistax/vpn-leak-identifier/internal/report"
+)
+
+type ScheduleReason string
+
+const (
+	ScheduleInitial ScheduleReason = "initial"
+	ScheduleTick    ScheduleReason = "tick"
+	ScheduleKernel  ScheduleReason = "kernel"
+	// ScheduleRecheck is a full snapshot run at once because a fast one saw
+	// the exit change, so STUN and dnsleaktest are not compared stale.
+	ScheduleRecheck ScheduleReason = "recheck"
+)
+
+// Timing describes the snapshot an event was derived from.
+type Timing struct {
+	Reason       ScheduleReason `json:"reason"`
+	ScheduledUTC time.Time      `json:"scheduled_utc"`
+	StartedUTC   time.Time      `json:"started_utc"`
+	DurationMS   int64          `json:"duration_ms"`
+	// Ticks that fired while this snapshot was running.
+	SkippedTicks int  `json:"skipped_ticks"`
+	Full         bool `json:"full"`
+}
+
+type snapshotResult struct {
+	snapshot report.Snapshot
+	timing   Timing
+	triggers []netwatch.Event
+}
+
+// scheduler runs at most one snapshot at a time. It is driven from the Run
+// loop, so its fields need no locking; only the snapshot itself runs in a
+// goroutine and reports back through results.
+type scheduler struct {
+	ctx    context.Context
+	opt    Options
+	ticker *time.Ticker
+
+	results chan snapshotResult
+	running bool
+	skipped int
+	queued  []netwatch.Event
+
+	lastFull     time.Time
+	lastFullSnap *report.Snapshot
+	// audited is set once a snapshot has run the host audit.
+	audited bool
+}
+
+func newScheduler(ctx context.Context, opt Options) *scheduler {
+	return &scheduler{
+		ctx:     ctx,
+		opt:     opt,
+		ticker:  time.NewTicker(opt.Interval),
+		results: make(chan snapshotResult, 1),
+	}
+}
+
+func (s *scheduler) ticks() <-chan time.Time {
+	return s.ticker.C
+}
+
+func (s *scheduler) stop() {
+	s.ticker.Stop()
+}
+
+// reconfigure applies reloaded options; the running snapshot is unaffected.
+func (s *scheduler) reconfigure(opt Options) {
+	s.opt = opt
+	s.ticker.Reset(opt.Interval)
+}
+
+func (s *scheduler) skip() {
+	s.skipped++
+}
+
+func (s *scheduler) queue(ev netwatch.Event) {
+	s.queued = append(s.queued, ev)
+}
+
+func (s *scheduler) takeQueued() []netwatch.Event {
+	q := s.queued
+	s.queued = nil
+	return q
+}
+
+func (s *scheduler) start(reason ScheduleReason, scheduled time.Time, triggers []netwatch.Event) {
+	full := s.opt.FullInterval <= 0 || s.lastFull.IsZero() || scheduled.Sub(s.lastFull) >= s.opt.FullInterval
+
+	snapOpt := s.opt.Snapshot
+	if !full {
+		snapOpt.EnableSTUN = false
+		snapOpt.EnableDNSLeakTest = false
+	}
+	// Host settings rarely change on their own: audit them once per session
+	// and again when the kernel reports a network change.
+	snapOpt.SkipHostAudit = s.audited && reason != ScheduleKernel
+	s.audited = true
+
+	timing := Timing{
+		Reason:       reason,
+		ScheduledUTC: scheduled,
+		Full:         full,
+	}
+	s.skipped = 0
+	s.running = true
+	timeout := s.opt.Timeout
+
+	go func() {
+		timing.StartedUTC = time.Now().UTC()
+		snapCtx, cancel := context.WithTimeout(s.ctx, timeout)
+		snap := app.TakeSnapshot(snapCtx, snapOpt)
+		cancel()
+		timing.DurationMS = time.Since(timing.StartedUTC).Milliseconds()
+
+		// results has room for the only in-flight snapshot, so this never blocks.
+		s.results <- snapshotResult{snapshot: snap, timing: timing, triggers: triggers}
+	}()
+}
+
+// complete records a finished snapshot and fills in carried-forward fields.
+// STUN and dnsleaktest results are only carried while the exits match the
+// last full snapshot; after an exit change they would be compared with the
+// new exit, so they are dropped and complete reports that a full snapshot
+// is due at once.
+func (s *scheduler) complete(r *snapshotResult) (recheck bool) {
+	s.running = false
+	r.timing.SkippedTicks = s.skipped
+	s.skipped = 0
+	if r.timing.Full {
+		s.lastFull = r.timing.ScheduledUTC
+		snap := r.snapshot
+		s.lastFullSnap = &snap
+		return false
+	}
+	if s.lastFullSnap == nil {
+		return false
+	}
+	if !sameExits(s.lastFullSnap, &r.snapshot) {
+		s.lastFull = time.Time{}
+		return true
+	}
+	r.snapshot.StunObserved = s.lastFullSnap.StunObserved
+	r.snapshot.Stun = s.lastFullSnap.Stun
+	r.snapshot.DnsLeak = s.lastFullSnap.DnsLeak
+	// Keep the offline geo of the carried addresses.
+	for ip, g := range s.lastFullSnap.IPGeo {
+		if _, ok := r.snapshot.IPGeo[ip]; ok {
+			continue
+		}
+		if r.snapshot.IPGeo == nil {
+			r.snapshot.IPGeo = map[string]report.GeoInfo{}
+		}
+		r.snapshot.IPGeo[ip] = g
+	}
+	return false
+}
+
+// sameExits reports whether a and b saw the same exit per family.
+func sameExits(a, b *report.Snapshot) bool {
+	for _, family := range []string{"ipv4", "ipv6", "any"} {
+		if findPublicIP(a.PublicIPs, family) != findPublicIP(b.PublicIPs, family) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,202 @@
+// File: internal/monitor/summary.go (complete file)
+
+package monitor
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/baptistax/vpn-leak-identifier/internal/report"
+)
+
+// Summary describes a whole monitor session. Each snapshot's state is assumed
+// to hold until the next snapshot.
+type Summary struct {
+	StartedUTC time.Time         `json:"started_utc"`
+	EndedUTC   time.Time         `json:"ended_utc"`
+	Snapshots  int               `json:"snapshots"`
+	Events     map[EventKind]int `json:"events,omitempty"`
+	VPNExits   []string          `json:"vpn_exits,omitempty"`
+	// ExitGeo is the provider geo/ASN of each VPN exit, when known.
+	ExitGeo        map[string]report.GeoInfo `json:"exit_geo,omitempty"`
+	OnVPNSeconds   int                       `json:"on_vpn_seconds"`
+	OfflineSeconds int                       `json:"offline_seconds"`
+	LeakedSeconds  int                       `json:"leaked_seconds"`
+	Leaks          int                       `json:"leaks"`
+}
+
+// summarizer tracks time on VPN vs leaking. Time counts as leaked only while
+// a snapshot shows a leak: an exit or STUN address in a known ISP network, a
+// STUN address that is not the HTTP exit, or a DNS verdict of LEAK. A new exit
+// by itself is not a leak (the VPN may have changed server, or the monitor may
+// have started off-VPN); every exit seen while not leaking is listed in
+// VPNExits.
+type summarizer struct {
+	knownISP []*net.IPNet
+	sum      Summary
+
+	lastAt    time.Time
+	lastState string // vpn|offline|leak
+	lastExits []string
+	onVPN     time.Duration
+	offline   time.Duration
+	leaked    time.Duration
+}
+
+func newSummarizer(knownISP []*net.IPNet, resumed *State) *summarizer {
+	s := &summarizer{
+		knownISP: knownISP,
+		sum:      Summary{StartedUTC: time.Now().UTC(), Events: map[EventKind]int{}},
+	}
+	if resumed != nil {
+		s.sum.VPNExits = append([]string(nil), resumed.VPNExits...)
+	}
+	return s
+}
+
+func (s *summarizer) observe(snap *report.Snapshot) {
+	at := snap.TimestampUTC
+	s.sum.Snapshots++
+
+	state := "offline"
+	if snapshotOnline(snap) {
+		state = "vpn"
+		if s.leaking(snap) {
+			state = "leak"
+		} else {
+			s.addExits(snap)
+		}
+	}
+
+	s.accrue(at)
+	if state == "leak" && s.lastState != "leak" {
+		s.sum.Leaks++
+	}
+	s.lastState = state
+	s.lastAt = at
+	s.lastExits = exitIPs(snap)
+}
+
+// Status is the monitor's current view, reported after every snapshot.
+type Status struct {
+	Online  bool     `json:"online"`
+	Leaking bool     `json:"leaking"`
+	Exits   []string `json:"exits,omitempty"`
+	Leaks   int      `json:"leaks"`
+}
+
+// String is a one-line form, e.g. for the systemd STATUS field.
+func (st Status) String() string {
+	state := "offline"
+	switch {
+	case st.Leaking:
+		state = "LEAKING"
+	case st.Online:
+		state = "on VPN"
+	}
+	exit := "none"
+	if len(st.Exits) > 0 {
+		exit = strings.Join(st.Exits, ", ")
+	}
+	return fmt.Sprintf("%s | exit %s | leaks %d", state, exit, st.Leaks)
+}
+
+func (s *summarizer) status() Status {
+	return Status{
+		Online:  s.lastState == "vpn" || s.lastState == "leak",
+		Leaking: s.lastState == "leak",
+		Exits:   s.lastExits,
+		Leaks:   s.sum.Leaks,
+	}
+}
+
+func (s *summarizer) leaking(snap *report.Snapshot) bool {
+	if snap.DNS != nil && snap.DNS.Verdict == report.DNSLeak {
+		return true
+	}
+	return len(knownISPHits(snap, s.knownISP)) > 0 || len(stunMismatch(snap)) > 0
+}
+
+func (s *summarizer) addExits(snap *report.Snapshot) {
+	seen := map[string]bool{}
+	for _, ip := range s.sum.VPNExits {
+		seen[ip] = true
+	}
+	for _, ip := range exitIPs(snap) {
+		if !seen[ip] {
+			seen[ip] = true
+			s.sum.VPNExits = append(s.sum.VPNExits, ip)
+		}
+	}
+	for _, family := range []string{"ipv4", "ipv6"} {
+		exit := snap.Exit(family)
+		if exit.IP == "" || exit.Geo == (report.GeoInfo{}) {
+			continue
+		}
+		if _, ok := s.sum.ExitGeo[exit.IP]; !ok {
+			if s.sum.ExitGeo == nil {
+				s.sum.ExitGeo = map[string]report.GeoInfo{}
+			}
+			s.sum.ExitGeo[exit.IP] = exit.Geo
+		}
+	}
+}
+
+func (s *summarizer) accrue(until time.Time) {
+	if s.lastAt.IsZero() || !until.After(s.lastAt) {
+		return
+	}
+	d := until.Sub(s.lastAt)
+	switch s.lastState {
+	case "vpn":
+		s.onVPN += d
+	case "leak":
+		s.leaked += d
+	default:
+		s.offline += d
+	}
+}
+
+func (s *summarizer) countEvent(ev Event) {
+	s.sum.Events[ev.Kind]++
+}
+
+func (s *summarizer) finish() Summary {
+	now := time.Now().UTC()
+	s.accrue(now)
+	s.lastAt = now
+
+	out := s.sum
+	out.EndedUTC = now
+	out.OnVPNSeconds = int(s.onVPN.Seconds())
+	out.OfflineSeconds = int(s.offline.Seconds())
+	out.LeakedSeconds = int(s.leaked.Seconds())
+	return out
+}
+
+func exitIPs(snap *report.Snapshot) []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, r := range snap.PublicIPs {
+		if r.IP != "" && r.Error == "" && !seen[r.IP] {
+			seen[r.IP] = true
+			out = append(out, r.IP)
+		}
+	}
+	return out
+}
+
+// RenderSummaryText formats a session summary for the terminal.
+func RenderSummaryText(s Summary) string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("Monitor session: %s -> %s (%d snapshots)\n",
+		s.StartedUTC.Format("2006-01-02T15:04:05Z"), s.EndedUTC.Format("2006-01-02T15:04:05Z"), s.Snapshots))
+	if len(s.VPNExits) > 0 {
+		b.WriteString("VPN exits: " + strings.Join(s.VPNExits, ", ") + "\n")
+	}
+	b.WriteString(fmt.Sprintf("On VPN: %ds  |  Offline: %ds  |  Leaked: %ds  |  Leaks: %d\n",
+		s.OnVPNSeconds, s.OfflineSeconds, s.LeakedSeconds, s.Leaks))
+	return b.String()
+}
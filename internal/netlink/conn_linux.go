@@ -0,0 +1,123 @@
+// File: internal/netlink/conn_linux.go (complete file)
+
+//go:build linux
+
+package netlink
+
+import (
+	"errors"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+const (
+	FamilyRoute   = syscall.NETLINK_ROUTE
+	FamilyGeneric = syscall.NETLINK_GENERIC
+)
+
+type Conn struct {
+	fd  int
+	pid uint32
+	seq atomic.Uint32
+}
+
+// Dial opens a netlink socket for the given family and joins the multicast
+// groups in the legacy bitmask form (e.g. RTMGRP_LINK). Pass zero for plain
+// request/response use.
+func Dial(family int, groups uint32) (*Conn, error) {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_RAW|syscall.SOCK_CLOEXEC, family)
+	if err != nil {
+		return nil, os.NewSyscallError("socket", err)
+	}
+
+	sa := &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK, Groups: groups}
+	if err := syscall.Bind(fd, sa); err != nil {
+		_ = syscall.Close(fd)
+		return nil, os.NewSyscallError("bind", err)
+	}
+
+	c := &Conn{fd: fd}
+	if local, err := syscall.Getsockname(fd); err == nil {
+		if nl, ok := local.(*syscall.SockaddrNetlink); ok {
+			c.pid = nl.Pid
+		}
+	}
+	c.seq.Store(uint32(time.Now().Unix()))
+	return c, nil
+}
+
+func (c *Conn) Close() error {
+	return syscall.Close(c.fd)
+}
+
+// SetReadTimeout bounds each Receive call. Zero disables the timeout.
+func (c *Conn) SetReadTimeout(d time.Duration) error {
+	tv := syscall.NsecToTimeval(d.Nanoseconds())
+	return syscall.SetsockoptTimeval(c.fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &tv)
+}
+
+// Execute sends a request and collects every reply until the kernel ends the
+// exchange (NLMSG_DONE, an ACK or a single non-multipart reply).
+func (c *Conn) Execute(m Message) ([]Message, error) {
+	m.Header.Seq = c.seq.Add(1)
+	m.Header.Flags |= FlagRequest
+
+	if err := syscall.Sendto(c.fd, m.marshal(), 0, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK}); err != nil {
+		return nil, os.NewSyscallError("sendto", err)
+	}
+
+	var out []Message
+	for {
+		msgs, err := c.Receive()
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range msgs {
+			if r.Header.Seq != m.Header.Seq {
+				continue
+			}
+			switch r.Header.Type {
+			case TypeDone:
+				return out, nil
+			case TypeError:
+				if err := errorFromMessage(r); err != nil {
+					return nil, err
+				}
+				return out, nil
+			case TypeNoop, TypeOverrun:
+				continue
+			}
+			out = append(out, r)
+			if r.Header.Flags&FlagMulti == 0 {
+				return out, nil
+			}
+		}
+	}
+}
+
+// Receive reads one datagram and returns the messages it carries.
+func (c *Conn) Receive() ([]Message, error) {
+	buf := make([]byte, os.Getpagesize()*8)
+	for {
+		n, _, err := syscall.Recvfrom(c.fd, buf, 0)
+		if errors.Is(err, syscall.EINTR) {
+			continue
+		}
+		if err != nil {
+			return nil, os.NewSyscallError("recvfrom", err)
+		}
+		return ParseMessages(append([]byte(nil), buf[:n]...))
+	}
+}
+
+func errnoString(code int) string {
+	return syscall.Errno(code).Error()
+}
+
+// Is lets callers match kernel errors with errors.Is(err, syscall.ENODEV).
+func (e *Error) Is(target error) bool {
+	errno, ok := target.(syscall.Errno)
+	return ok && int(errno) == e.Code
+}
@@ -0,0 +1,33 @@
+// File: internal/netlink/conn_other.go (complete file)
+
+//go:build !linux
+
+package netlink
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	FamilyRoute   = 0
+	FamilyGeneric = 16
+)
+
+type Conn struct{}
+
+func Dial(family int, groups uint32) (*Conn, error) {
+	return nil, ErrNotSupported
+}
+
+func (c *Conn) Close() error { return nil }
+
+func (c *Conn) SetReadTimeout(d time.Duration) error { return ErrNotSupported }
+
+func (c *Conn) Execute(m Message) ([]Message, error) { return nil, ErrNotSupported }
+
+func (c *Conn) Receive() ([]Message, error) { return nil, ErrNotSupported }
+
+func errnoString(code int) string {
+	return fmt.Sprintf("errno %d", code)
+}
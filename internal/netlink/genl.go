@@ -0,0 +1,85 @@
+// File: internal/netlink/genl.go (complete file)
+
+package netlink
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Generic netlink (genetlink) framing: every payload starts with a 4 byte
+// header (command, version, reserved) followed by attributes.
+
+const (
+	genlHeaderLen = 4
+
+	genlIDCtrl         uint16 = 0x10
+	ctrlCmdGetFamily   uint8  = 3
+	ctrlAttrFamilyID   uint16 = 1
+	ctrlAttrFamilyName uint16 = 2
+)
+
+type GenericMessage struct {
+	Command uint8
+	Version uint8
+	Attrs   []Attribute
+}
+
+func (g GenericMessage) marshal() []byte {
+	b := make([]byte, genlHeaderLen)
+	b[0] = g.Command
+	b[1] = g.Version
+	return append(b, EncodeAttributes(g.Attrs)...)
+}
+
+func parseGeneric(m Message) (GenericMessage, error) {
+	if len(m.Data) < genlHeaderLen {
+		return GenericMessage{}, errors.New("netlink: short generic message")
+	}
+	attrs, err := ParseAttributes(m.Data[genlHeaderLen:])
+	if err != nil {
+		return GenericMessage{}, err
+	}
+	return GenericMessage{Command: m.Data[0], Version: m.Data[1], Attrs: attrs}, nil
+}
+
+// ExecuteGeneric sends a generic netlink request to the given family id.
+func (c *Conn) ExecuteGeneric(family uint16, flags uint16, g GenericMessage) ([]GenericMessage, error) {
+	msgs, err := c.Execute(Message{
+		Header: Header{Type: family, Flags: flags},
+		Data:   g.marshal(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]GenericMessage, 0, len(msgs))
+	for _, m := range msgs {
+		gm, err := parseGeneric(m)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, gm)
+	}
+	return out, nil
+}
+
+// ResolveFamily looks up the numeric id of a generic netlink family by name.
+func (c *Conn) ResolveFamily(name string) (uint16, error) {
+	replies, err := c.ExecuteGeneric(genlIDCtrl, 0, GenericMessage{
+		Command: ctrlCmdGetFamily,
+		Version: 1,
+		Attrs:   []Attribute{StringAttribute(ctrlAttrFamilyName, name)},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("resolve genetlink family %q: %w", name, err)
+	}
+	for _, r := range replies {
+		for _, a := range r.Attrs {
+			if a.Type == ctrlAttrFamilyID {
+				return a.Uint16(), nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("genetlink family %q not found", name)
+}
@@ -0,0 +1,205 @@
+// File: internal/netlink/netlink.go (complete file)
+
+package netlink
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// This is a minimal netlink implementation to avoid external dependencies.
+// Only what the probes need is covered: request/dump round trips, multicast
+// subscriptions and attribute (TLV) encoding.
+
+var ErrNotSupported = errors.New("netlink: not supported on this platform")
+
+const (
+	headerLen = 16
+	attrLen   = 4
+
+	// Message types shared by every netlink family.
+	TypeNoop    uint16 = 0x1
+	TypeError   uint16 = 0x2
+	TypeDone    uint16 = 0x3
+	TypeOverrun uint16 = 0x4
+
+	// Header flags.
+	FlagRequest   uint16 = 0x1
+	FlagMulti     uint16 = 0x2
+	FlagAck       uint16 = 0x4
+	FlagDump      uint16 = 0x300
+	FlagNested    uint16 = 0x8000
+	attrTypeMask  uint16 = 0x3fff
+	attrAlignment        = 4
+)
+
+type Header struct {
+	Length uint32
+	Type   uint16
+	Flags  uint16
+	Seq    uint32
+	PID    uint32
+}
+
+type Message struct {
+	Header Header
+	Data   []byte
+}
+
+func (m Message) marshal() []byte {
+	b := make([]byte, headerLen+len(m.Data))
+	binary.NativeEndian.PutUint32(b[0:4], uint32(len(b)))
+	binary.NativeEndian.PutUint16(b[4:6], m.Header.Type)
+	binary.NativeEndian.PutUint16(b[6:8], m.Header.Flags)
+	binary.NativeEndian.PutUint32(b[8:12], m.Header.Seq)
+	binary.NativeEndian.PutUint32(b[12:16], m.Header.PID)
+	copy(b[headerLen:], m.Data)
+	return b
+}
+
+// ParseMessages splits a datagram read from a netlink socket into messages.
+func ParseMessages(b []byte) ([]Message, error) {
+	var out []Message
+	for len(b) >= headerLen {
+		h := Header{
+			Length: binary.NativeEndian.Uint32(b[0:4]),
+			Type:   binary.NativeEndian.Uint16(b[4:6]),
+			Flags:  binary.NativeEndian.Uint16(b[6:8]),
+			Seq:    binary.NativeEndian.Uint32(b[8:12]),
+			PID:    binary.NativeEndian.Uint32(b[12:16]),
+		}
+		l := int(h.Length)
+		if l < headerLen || l > len(b) {
+			return nil, errors.New("netlink: invalid message length")
+		}
+		out = append(out, Message{Header: h, Data: b[headerLen:l]})
+		b = b[align(l):]
+	}
+	return out, nil
+}
+
+// errorFromMessage decodes an NLMSG_ERROR payload. A zero error code is an ACK.
+func errorFromMessage(m Message) error {
+	if len(m.Data) < 4 {
+		return errors.New("netlink: short error message")
+	}
+	code := int32(binary.NativeEndian.Uint32(m.Data[0:4]))
+	if code == 0 {
+		return nil
+	}
+	return &Error{Code: int(-code)}
+}
+
+// Error carries the (positive) errno returned by the kernel.
+type Error struct {
+	Code int
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("netlink: %s", errnoString(e.Code))
+}
+
+type Attribute struct {
+	Type   uint16
+	Nested bool
+	Data   []byte
+}
+
+func (a Attribute) Uint8() uint8 {
+	if len(a.Data) < 1 {
+		return 0
+	}
+	return a.Data[0]
+}
+
+func (a Attribute) Uint16() uint16 {
+	if len(a.Data) < 2 {
+		return 0
+	}
+	return binary.NativeEndian.Uint16(a.Data)
+}
+
+func (a Attribute) Uint32() uint32 {
+	if len(a.Data) < 4 {
+		return 0
+	}
+	return binary.NativeEndian.Uint32(a.Data)
+}
+
+func (a Attribute) Uint64() uint64 {
+	if len(a.Data) < 8 {
+		return 0
+	}
+	return binary.NativeEndian.Uint64(a.Data)
+}
+
+// String returns the attribute payload without the trailing NUL terminator.
+func (a Attribute) String() string {
+	d := a.Data
+	for len(d) > 0 && d[len(d)-1] == 0 {
+		d = d[:len(d)-1]
+	}
+	return string(d)
+}
+
+// ParseAttributes decodes a sequence of netlink attributes.
+func ParseAttributes(b []byte) ([]Attribute, error) {
+	var out []Attribute
+	for len(b) >= attrLen {
+		l := int(binary.NativeEndian.Uint16(b[0:2]))
+		typ := binary.NativeEndian.Uint16(b[2:4])
+		if l < attrLen || l > len(b) {
+			return nil, errors.New("netlink: invalid attribute length")
+		}
+		out = append(out, Attribute{
+			Type:   typ & attrTypeMask,
+			Nested: typ&FlagNested != 0,
+			Data:   b[attrLen:l],
+		})
+		adv := align(l)
+		if adv > len(b) {
+			break
+		}
+		b = b[adv:]
+	}
+	return out, nil
+}
+
+// EncodeAttributes is the inverse of ParseAttributes.
+func EncodeAttributes(attrs []Attribute) []byte {
+	var out []byte
+	for _, a := range attrs {
+		l := attrLen + len(a.Data)
+		buf := make([]byte, align(l))
+		typ := a.Type
+		if a.Nested {
+			typ |= FlagNested
+		}
+		binary.NativeEndian.PutUint16(buf[0:2], uint16(l))
+		binary.NativeEndian.PutUint16(buf[2:4], typ)
+		copy(buf[attrLen:], a.Data)
+		out = append(out, buf...)
+	}
+	return out
+}
+
+func StringAttribute(typ uint16, s string) Attribute {
+	return Attribute{Type: typ, Data: append([]byte(s), 0)}
+}
+
+func Uint16Attribute(typ uint16, v uint16) Attribute {
+	b := make([]byte, 2)
+	binary.NativeEndian.PutUint16(b, v)
+	return Attribute{Type: typ, Data: b}
+}
+
+func Uint32Attribute(typ uint16, v uint32) Attribute {
+	b := make([]byte, 4)
+	binary.NativeEndian.PutUint32(b, v)
+	return Attribute{Type: typ, Data: b}
+}
+
+func align(n int) int {
+	return (n + attrAlignment - 1) &^ (attrAlignment - 1)
+}
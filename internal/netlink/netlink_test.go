@@ -0,0 +1,141 @@
+// File: internal/netlink/netlink_test.go (complete file)
+
+package netlink
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// littleEndian skips tests whose captured bytes are in x86/arm64 host order.
+func littleEndian(t *testing.T) {
+	t.Helper()
+	if binary.NativeEndian.Uint16([]byte{1, 0}) != 1 {
+		t.Skip("captured messages are little-endian")
+	}
+}
+
+func TestParseMessages(t *testing.T) {
+	littleEndian(t)
+	// Two messages in one datagram: a 21-byte reply padded to 24, then an
+	// NLMSG_ERROR carrying -EPERM.
+	b := []byte{
+		21, 0, 0, 0, 0x10, 0, 0x02, 0, 7, 0, 0, 0, 0x39, 0x30, 0, 0,
+		'w', 'g', '0', 0, 0, 0, 0, 0,
+		20, 0, 0, 0, 0x02, 0, 0, 0, 8, 0, 0, 0, 0x39, 0x30, 0, 0,
+		0xff, 0xff, 0xff, 0xff,
+	}
+	msgs, err := ParseMessages(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 2 {
+		t.Fatalf("got %d messages", len(msgs))
+	}
+	h := msgs[0].Header
+	if h.Type != 0x10 || h.Flags != FlagMulti || h.Seq != 7 || h.PID != 12345 || !bytes.Equal(msgs[0].Data, []byte("wg0\x00\x00")) {
+		t.Fatalf("first message = %+v %q", h, msgs[0].Data)
+	}
+	if msgs[1].Header.Type != TypeError {
+		t.Fatalf("second message type = %d", msgs[1].Header.Type)
+	}
+	err = errorFromMessage(msgs[1])
+	if e, ok := err.(*Error); !ok || e.Code != 1 {
+		t.Fatalf("error = %v", err)
+	}
+	if err := errorFromMessage(Message{Data: []byte{0, 0, 0, 0}}); err != nil {
+		t.Fatalf("ack decoded as %v", err)
+	}
+
+	if _, err := ParseMessages([]byte{40, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}); err == nil {
+		t.Fatal("expected an error for a length past the datagram")
+	}
+}
+
+func TestParseAttributes(t *testing.T) {
+	littleEndian(t)
+	cases := []struct {
+		name string
+		in   []byte
+		want []Attribute
+		err  bool
+	}{
+		{
+			name: "string and u16 with padding",
+			in: []byte{
+				8, 0, 2, 0, 'w', 'g', '0', 0,
+				6, 0, 6, 0, 0xca, 0x6c, 0, 0,
+			},
+			want: []Attribute{
+				{Type: 2, Data: []byte("wg0\x00")},
+				{Type: 6, Data: []byte{0xca, 0x6c}},
+			},
+		},
+		{
+			name: "nested flag is split off the type",
+			in:   []byte{8, 0, 0x08, 0x80, 4, 0, 1, 0},
+			want: []Attribute{{Type: 8, Nested: true, Data: []byte{4, 0, 1, 0}}},
+		},
+		{
+			name: "unpadded last attribute",
+			in:   []byte{5, 0, 3, 0, 24},
+			want: []Attribute{{Type: 3, Data: []byte{24}}},
+		},
+		{
+			name: "length past the buffer",
+			in:   []byte{12, 0, 1, 0, 0, 0, 0, 0},
+			err:  true,
+		},
+		{
+			name: "length below the header",
+			in:   []byte{2, 0, 1, 0},
+			err:  true,
+		},
+	}
+	for _, c := range cases {
+		got, err := ParseAttributes(c.in)
+		if c.err {
+			if err == nil {
+				t.Errorf("%s: expected an error", c.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		if len(got) != len(c.want) {
+			t.Errorf("%s: got %+v", c.name, got)
+			continue
+		}
+		for i := range got {
+			if got[i].Type != c.want[i].Type || got[i].Nested != c.want[i].Nested || !bytes.Equal(got[i].Data, c.want[i].Data) {
+				t.Errorf("%s: attr %d = %+v, want %+v", c.name, i, got[i], c.want[i])
+			}
+		}
+	}
+}
+
+func TestEncodeAttributes_RoundTrip(t *testing.T) {
+	in := []Attribute{
+		StringAttribute(2, "wg0"),
+		Uint16Attribute(6, 51820),
+		{Type: 8, Nested: true, Data: EncodeAttributes([]Attribute{Uint32Attribute(1, 7)})},
+	}
+	b := EncodeAttributes(in)
+	if len(b)%attrAlignment != 0 {
+		t.Fatalf("encoded length %d is not aligned", len(b))
+	}
+	out, err := ParseAttributes(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out[0].String() != "wg0" || out[1].Uint16() != 51820 || !out[2].Nested {
+		t.Fatalf("round trip = %+v", out)
+	}
+	nested, err := ParseAttributes(out[2].Data)
+	if err != nil || len(nested) != 1 || nested[0].Uint32() != 7 {
+		t.Fatalf("nested = %+v, %v", nested, err)
+	}
+}
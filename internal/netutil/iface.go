@@ -0,0 +1,79 @@
+// File: internal/netutil/iface.go (complete file)
+
+package netutil
+
+import (
+	"net"
+	"sort"
+)
+
+// Interface is a local interface with its addresses. Egress lists the
+// families ("ipv4", "ipv6") whose default route uses it.
+type Interface struct {
+	Name   string
+	Index  int
+	MTU    int
+	Up     bool
+	Addrs  []string
+	Egress []string
+}
+
+// Interfaces lists non-loopback interfaces and marks the egress interface
+// per family.
+func Interfaces() ([]Interface, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	egress := map[string][]string{}
+	for _, family := range []string{"ipv4", "ipv6"} {
+		if src := EgressSource(family); src != nil {
+			egress[src.String()] = append(egress[src.String()], family)
+		}
+	}
+
+	var out []Interface
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		i := Interface{
+			Name:  iface.Name,
+			Index: iface.Index,
+			MTU:   iface.MTU,
+			Up:    iface.Flags&net.FlagUp != 0,
+		}
+		addrs, _ := iface.Addrs()
+		for _, a := range addrs {
+			ip := addrToIP(a)
+			if ip == nil {
+				continue
+			}
+			i.Addrs = append(i.Addrs, a.String())
+			i.Egress = append(i.Egress, egress[ip.String()]...)
+		}
+		sort.Strings(i.Egress)
+		out = append(out, i)
+	}
+	return out, nil
+}
+
+// EgressSource returns the local address the kernel would use to reach the
+// internet over family, or nil when there is no route. Connecting a UDP
+// socket only selects a route; no packet is sent.
+func EgressSource(family string) net.IP {
+	network, target := "udp4", "192.0.2.1:53" // TEST-NET-1
+	if family == "ipv6" {
+		network, target = "udp6", "[2001:db8::1]:53" // documentation prefix
+	}
+	c, err := net.Dial(network, target)
+	if err != nil {
+		return nil
+	}
+	defer c.Close()
+	if a, ok := c.LocalAddr().(*net.UDPAddr); ok {
+		return a.IP
+	}
+	return nil
+}
@@ -0,0 +1,331 @@
+// File: internal/netwatch/netwatch.go (complete file)
+
+package netwatch
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/baptistax/vpn-leak-identifier/internal/netlink"
+)
+
+// netwatch subscribes to rtnetlink multicast groups and reports the link,
+// address and route changes that can affect where traffic leaves the host.
+
+var ErrNotSupported = netlink.ErrNotSupported
+
+const (
+	// Legacy RTMGRP_* multicast bitmask.
+	groupLink       uint32 = 0x1
+	groupIPv4Addr   uint32 = 0x10
+	groupIPv4Route  uint32 = 0x40
+	groupIPv6Addr   uint32 = 0x100
+	groupIPv6Route  uint32 = 0x400
+	subscribeGroups        = groupLink | groupIPv4Addr | groupIPv4Route | groupIPv6Addr | groupIPv6Route
+
+	rtmNewLink  uint16 = 16
+	rtmDelLink  uint16 = 17
+	rtmNewAddr  uint16 = 20
+	rtmDelAddr  uint16 = 21
+	rtmNewRoute uint16 = 24
+	rtmDelRoute uint16 = 25
+
+	iflaIfname   uint16 = 3
+	iflaLinkinfo uint16 = 18
+	iflaInfoKind uint16 = 1
+
+	ifaAddress uint16 = 1
+	ifaLocal   uint16 = 2
+
+	rtaDst     uint16 = 1
+	rtaOif     uint16 = 4
+	rtaGateway uint16 = 5
+	rtaTable   uint16 = 15
+
+	iffUp           uint32 = 0x1
+	iffLoopback     uint32 = 0x8
+	iffPointToPoint uint32 = 0x10
+
+	rtnUnicast   uint8  = 1
+	rtTableLocal uint32 = 255
+
+	afInet  uint8 = 2
+	afInet6 uint8 = 10
+)
+
+type Event struct {
+	AtUTC     time.Time `json:"at_utc"`
+	Kind      string    `json:"kind"` // link_new|link_del|addr_new|addr_del|route_new|route_del
+	Interface string    `json:"interface,omitempty"`
+	Index     int       `json:"index,omitempty"`
+	Family    string    `json:"family,omitempty"` // ipv4|ipv6
+	Address   string    `json:"address,omitempty"`
+	Gateway   string    `json:"gateway,omitempty"`
+	Table     uint32    `json:"table,omitempty"`
+	LinkKind  string    `json:"link_kind,omitempty"`
+	Up        bool      `json:"up,omitempty"`
+	Tunnel    bool      `json:"tunnel,omitempty"`
+}
+
+func (e Event) String() string {
+	var b strings.Builder
+	b.WriteString(e.Kind)
+	if e.Address != "" {
+		b.WriteString(" " + e.Address)
+	}
+	if e.Gateway != "" {
+		b.WriteString(" via " + e.Gateway)
+	}
+	if e.Interface != "" {
+		b.WriteString(" dev " + e.Interface)
+	}
+	if strings.HasPrefix(e.Kind, "link_") {
+		if e.Up {
+			b.WriteString(" up")
+		} else {
+			b.WriteString(" down")
+		}
+	}
+	if e.Table != 0 && e.Table != 254 {
+		b.WriteString(" table " + strconv.FormatUint(uint64(e.Table), 10))
+	}
+	return b.String()
+}
+
+// Subscribe streams relevant kernel network events until ctx is done:
+// default route changes, tunnel interface changes and global address changes.
+func Subscribe(ctx context.Context) (<-chan Event, error) {
+	c, err := netlink.Dial(netlink.FamilyRoute, subscribeGroups)
+	if err != nil {
+		return nil, err
+	}
+	// Wake up periodically so cancellation is noticed.
+	if err := c.SetReadTimeout(time.Second); err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	out := make(chan Event, 64)
+	go func() {
+		defer close(out)
+		defer c.Close()
+
+		for ctx.Err() == nil {
+			msgs, err := c.Receive()
+			if err != nil {
+				if errors.Is(err, syscall.EAGAIN) || errors.Is(err, syscall.EWOULDBLOCK) {
+					continue
+				}
+				// ENOBUFS means the kernel dropped events; keep listening.
+				if errors.Is(err, syscall.ENOBUFS) {
+					continue
+				}
+				return
+			}
+			for _, m := range msgs {
+				ev, ok := parseEvent(m)
+				if !ok {
+					continue
+				}
+				select {
+				case out <- ev:
+				default:
+					// The consumer only needs to know something changed.
+				}
+			}
+		}
+	}()
+	return out, nil
+}
+
+func parseEvent(m netlink.Message) (Event, bool) {
+	ev := Event{AtUTC: time.Now().UTC()}
+
+	switch m.Header.Type {
+	case rtmNewLink, rtmDelLink:
+		ev.Kind = "link_new"
+		if m.Header.Type == rtmDelLink {
+			ev.Kind = "link_del"
+		}
+		return parseLink(ev, m.Data)
+	case rtmNewAddr, rtmDelAddr:
+		ev.Kind = "addr_new"
+		if m.Header.Type == rtmDelAddr {
+			ev.Kind = "addr_del"
+		}
+		return parseAddr(ev, m.Data)
+	case rtmNewRoute, rtmDelRoute:
+		ev.Kind = "route_new"
+		if m.Header.Type == rtmDelRoute {
+			ev.Kind = "route_del"
+		}
+		return parseRoute(ev, m.Data)
+	}
+	return Event{}, false
+}
+
+func parseLink(ev Event, b []byte) (Event, bool) {
+	// struct ifinfomsg: family, pad, type u16, index i32, flags u32, change u32
+	if len(b) < 16 {
+		return Event{}, false
+	}
+	ev.Index = int(int32(binary.NativeEndian.Uint32(b[4:8])))
+	flags := binary.NativeEndian.Uint32(b[8:12])
+	if flags&iffLoopback != 0 {
+		return Event{}, false
+	}
+	ev.Up = flags&iffUp != 0
+
+	attrs, err := netlink.ParseAttributes(b[16:])
+	if err != nil {
+		return Event{}, false
+	}
+	for _, a := range attrs {
+		switch a.Type {
+		case iflaIfname:
+			ev.Interface = a.String()
+		case iflaLinkinfo:
+			info, _ := netlink.ParseAttributes(a.Data)
+			for _, ia := range info {
+				if ia.Type == iflaInfoKind {
+					ev.LinkKind = ia.String()
+				}
+			}
+		}
+	}
+
+	ev.Tunnel = flags&iffPointToPoint != 0 || IsTunnel(ev.Interface, ev.LinkKind)
+	// Only tunnel links matter; physical link churn shows up as route/address events.
+	if !ev.Tunnel {
+		return Event{}, false
+	}
+	return ev, true
+}
+
+func parseAddr(ev Event, b []byte) (Event, bool) {
+	// struct ifaddrmsg: family u8, prefixlen u8, flags u8, scope u8, index u32
+	if len(b) < 8 {
+		return Event{}, false
+	}
+	family := b[0]
+	prefix := int(b[1])
+	ev.Family = familyName(family)
+	ev.Index = int(binary.NativeEndian.Uint32(b[4:8]))
+
+	attrs, err := netlink.ParseAttributes(b[8:])
+	if err != nil {
+		return Event{}, false
+	}
+	var ip net.IP
+	for _, a := range attrs {
+		switch a.Type {
+		case ifaLocal:
+			ip = net.IP(append([]byte(nil), a.Data...))
+		case ifaAddress:
+			if ip == nil {
+				ip = net.IP(append([]byte(nil), a.Data...))
+			}
+		}
+	}
+	if ip == nil || ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsMulticast() {
+		return Event{}, false
+	}
+
+	ev.Address = ip.String() + "/" + strconv.Itoa(prefix)
+	ev.Interface = interfaceName(ev.Index)
+	ev.Tunnel = IsTunnel(ev.Interface, "")
+	return ev, true
+}
+
+func parseRoute(ev Event, b []byte) (Event, bool) {
+	// struct rtmsg: family, dst_len, src_len, tos, table, protocol, scope, type, flags u32
+	if len(b) < 12 {
+		return Event{}, false
+	}
+	family := b[0]
+	dstLen := int(b[1])
+	table := uint32(b[4])
+	rtype := b[7]
+
+	// Default routes, plus the 0/1 + 128/1 split used by OpenVPN-style clients.
+	if rtype != rtnUnicast || dstLen > 1 {
+		return Event{}, false
+	}
+
+	attrs, err := netlink.ParseAttributes(b[12:])
+	if err != nil {
+		return Event{}, false
+	}
+	dst := net.IP(nil)
+	for _, a := range attrs {
+		switch a.Type {
+		case rtaDst:
+			dst = net.IP(append([]byte(nil), a.Data...))
+		case rtaOif:
+			ev.Index = int(a.Uint32())
+		case rtaGateway:
+			ev.Gateway = net.IP(append([]byte(nil), a.Data...)).String()
+		case rtaTable:
+			table = a.Uint32()
+		}
+	}
+	if table == rtTableLocal {
+		return Event{}, false
+	}
+
+	ev.Family = familyName(family)
+	ev.Table = table
+	if dst == nil {
+		if family == afInet6 {
+			dst = net.IPv6zero
+		} else {
+			dst = net.IPv4zero
+		}
+	}
+	ev.Address = dst.String() + "/" + strconv.Itoa(dstLen)
+	ev.Interface = interfaceName(ev.Index)
+	ev.Tunnel = IsTunnel(ev.Interface, "")
+	return ev, true
+}
+
+// IsTunnel guesses whether an interface is a VPN tunnel from its link kind or name.
+func IsTunnel(name, kind string) bool {
+	switch kind {
+	case "wireguard", "tun", "ipip", "sit", "gre", "ip6tnl", "vti", "vti6", "xfrm":
+		return true
+	}
+	for _, p := range []string{"tun", "tap", "wg", "ppp", "ipsec", "vti", "utun", "nordlynx", "proton", "mullvad"} {
+		if strings.HasPrefix(name, p) {
+			return true
+		}
+	}
+	return false
+}
+
+func interfaceName(index int) string {
+	if index <= 0 {
+		return ""
+	}
+	iface, err := net.InterfaceByIndex(index)
+	if err != nil {
+		return fmt.Sprintf("if%d", index)
+	}
+	return iface.Name
+}
+
+func familyName(f uint8) string {
+	switch f {
+	case afInet:
+		return "ipv4"
+	case afInet6:
+		return "ipv6"
+	}
+	return ""
+}
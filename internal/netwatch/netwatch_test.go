@@ -0,0 +1,117 @@
+// File: internal/netwatch/netwatch_test.go (complete file)
+
+package netwatch
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"testing"
+
+	"github.com/baptistax/vpn-leak-identifier/internal/netlink"
+)
+
+// Raw rtnetlink messages (nlmsghdr included) as read from the socket on a
+// little-endian host. Interface indexes 9001-9004 do not exist, so route
+// and address events name them "ifN".
+var rtnetlinkCases = []struct {
+	name string
+	raw  string
+	want *Event // nil: the message must not trigger a snapshot
+}{
+	{
+		name: "ipv4 default route via gateway",
+		raw:  "3400000018000000000000000000000002000000fe0300010000000008000f00fe00000008000500c0a801010800040029230000",
+		want: &Event{Kind: "route_new", Family: "ipv4", Address: "0.0.0.0/0", Gateway: "192.168.1.1", Table: 254, Index: 9001, Interface: "if9001"},
+	},
+	{
+		name: "openvpn 128.0.0.0/1 split",
+		raw:  "2c00000018000000000000000000000002010000fe030001000000000800010080000000080004002a230000",
+		want: &Event{Kind: "route_new", Family: "ipv4", Address: "128.0.0.0/1", Table: 254, Index: 9002, Interface: "if9002"},
+	},
+	{
+		name: "subnet route is ignored",
+		raw:  "2c00000018000000000000000000000002080000fe03000100000000080001000a0000000800040029230000",
+	},
+	{
+		name: "local table is ignored",
+		raw:  "2c00000018000000000000000000000002000000ff0300010000000008000f00ff0000000800040029230000",
+	},
+	{
+		name: "ipv6 default route removed from a fwmark table",
+		raw:  "400000001900000000000000000000000a000000fe0300010000000014000500fe800000000000000000000000000001080004002923000008000f006cca0000",
+		want: &Event{Kind: "route_del", Family: "ipv6", Address: "::/0", Gateway: "fe80::1", Table: 51820, Index: 9001, Interface: "if9001"},
+	},
+	{
+		name: "global ipv4 address",
+		raw:  "28000000140000000000000000000000021800002923000008000100cb00710508000200cb007105",
+		want: &Event{Kind: "addr_new", Family: "ipv4", Address: "203.0.113.5/24", Index: 9001, Interface: "if9001"},
+	},
+	{
+		name: "link-local address is ignored",
+		raw:  "2c0000001400000000000000000000000a4000002923000014000100fe800000000000000000000000000001",
+	},
+	{
+		name: "wireguard link up",
+		raw:  "3c000000100000000000000000000000000001002b23000041000000000000000800030077673000140012000e000100776972656775617264000000",
+		want: &Event{Kind: "link_new", Interface: "wg0", Index: 9003, LinkKind: "wireguard", Up: true, Tunnel: true},
+	},
+	{
+		name: "physical link is ignored",
+		raw:  "2c00000010000000000000000000000000000100292300000100000000000000090003006574683000000000",
+	},
+	{
+		name: "loopback is ignored",
+		raw:  "2800000010000000000000000000000000000100010000000900000000000000070003006c6f0000",
+	},
+	{
+		name: "point-to-point tun removed",
+		raw:  "2c000000110000000000000000000000000001002c23000010000000000000000900030074756e3000000000",
+		want: &Event{Kind: "link_del", Interface: "tun0", Index: 9004, Tunnel: true},
+	},
+}
+
+func TestParseEvent(t *testing.T) {
+	if binary.NativeEndian.Uint16([]byte{1, 0}) != 1 {
+		t.Skip("captured messages are little-endian")
+	}
+	for _, c := range rtnetlinkCases {
+		b, err := hex.DecodeString(c.raw)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		msgs, err := netlink.ParseMessages(b)
+		if err != nil || len(msgs) != 1 {
+			t.Fatalf("%s: messages = %v, %v", c.name, msgs, err)
+		}
+		ev, ok := parseEvent(msgs[0])
+		if c.want == nil {
+			if ok {
+				t.Errorf("%s: unexpected event %+v", c.name, ev)
+			}
+			continue
+		}
+		if !ok {
+			t.Errorf("%s: no event", c.name)
+			continue
+		}
+		ev.AtUTC = c.want.AtUTC
+		if ev != *c.want {
+			t.Errorf("%s:\n got %+v\nwant %+v", c.name, ev, *c.want)
+		}
+	}
+}
+
+func TestParseEvent_Truncated(t *testing.T) {
+	for _, typ := range []uint16{rtmNewLink, rtmNewAddr, rtmNewRoute, 99} {
+		if ev, ok := parseEvent(netlink.Message{Header: netlink.Header{Type: typ}, Data: []byte{2, 0, 0}}); ok {
+			t.Errorf("type %d: unexpected event %+v", typ, ev)
+		}
+	}
+}
+
+func TestEventString(t *testing.T) {
+	ev := Event{Kind: "route_new", Address: "0.0.0.0/0", Gateway: "192.168.1.1", Interface: "eth0", Table: 51820}
+	if got, want := ev.String(), "route_new 0.0.0.0/0 via 192.168.1.1 dev eth0 table 51820"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,357 @@
+// File: internal/nm/nm.go (complete file)
+
+package nm
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/baptistax/vpn-leak-identifier/internal/dbus"
+)
+
+// NetworkManager D-Bus API (see NetworkManager's introspection XML). Only VPN
+// and WireGuard connection profiles are considered.
+
+const (
+	BusName = "org.freedesktop.NetworkManager"
+
+	RootPath     = dbus.ObjectPath("/org/freedesktop/NetworkManager")
+	SettingsPath = dbus.ObjectPath("/org/freedesktop/NetworkManager/Settings")
+
+	IfaceNetworkManager   = "org.freedesktop.NetworkManager"
+	IfaceSettings         = "org.freedesktop.NetworkManager.Settings"
+	IfaceSettingsConn     = "org.freedesktop.NetworkManager.Settings.Connection"
+	IfaceActiveConnection = "org.freedesktop.NetworkManager.Connection.Active"
+	IfaceVPNConnection    = "org.freedesktop.NetworkManager.VPN.Connection"
+	IfaceDevice           = "org.freedesktop.NetworkManager.Device"
+
+	noObject = dbus.ObjectPath("/")
+
+	connectionTypeVPN       = "vpn"
+	connectionTypeWireGuard = "wireguard"
+)
+
+type Client struct {
+	conn *dbus.Conn
+}
+
+// Connect opens the system bus, where NetworkManager lives.
+func Connect() (*Client, error) {
+	conn, err := dbus.SystemBus()
+	if err != nil {
+		return nil, err
+	}
+	return New(conn), nil
+}
+
+// New wraps an existing bus connection (e.g. a session bus in tests).
+func New(conn *dbus.Conn) *Client {
+	return &Client{conn: conn}
+}
+
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+type Connection struct {
+	ID         string          `json:"id"`
+	UUID       string          `json:"uuid"`
+	Type       string          `json:"type"`
+	Path       dbus.ObjectPath `json:"path"`
+	ActivePath dbus.ObjectPath `json:"active_path,omitempty"`
+	State      string          `json:"state"` // active connection state, or "inactive"
+}
+
+// Connections lists VPN and WireGuard connection profiles with their activation state.
+func (c *Client) Connections(ctx context.Context) ([]Connection, error) {
+	out, err := c.conn.Call(ctx, BusName, SettingsPath, IfaceSettings, "ListConnections", "")
+	if err != nil {
+		return nil, err
+	}
+	paths := objectPaths(out)
+
+	active, err := c.activeByConnection(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var conns []Connection
+	for _, p := range paths {
+		settings, err := c.conn.Call(ctx, BusName, p, IfaceSettingsConn, "GetSettings", "")
+		if err != nil || len(settings) == 0 {
+			continue
+		}
+		section := dictValue(settings[0], "connection")
+		typ := stringValue(dictValue(section, "type"))
+		if typ != connectionTypeVPN && typ != connectionTypeWireGuard {
+			continue
+		}
+
+		conn := Connection{
+			ID:    stringValue(dictValue(section, "id")),
+			UUID:  stringValue(dictValue(section, "uuid")),
+			Type:  typ,
+			Path:  p,
+			State: "inactive",
+		}
+		if a, ok := active[p]; ok {
+			conn.ActivePath = a.path
+			conn.State = ActiveStateName(a.state)
+		}
+		conns = append(conns, conn)
+	}
+
+	sort.Slice(conns, func(i, j int) bool { return conns[i].ID < conns[j].ID })
+	return conns, nil
+}
+
+type activeInfo struct {
+	path  dbus.ObjectPath
+	state uint32
+}
+
+func (c *Client) activeByConnection(ctx context.Context) (map[dbus.ObjectPath]activeInfo, error) {
+	v, err := c.conn.GetProperty(ctx, BusName, RootPath, IfaceNetworkManager, "ActiveConnections")
+	if err != nil {
+		return nil, err
+	}
+
+	out := map[dbus.ObjectPath]activeInfo{}
+	for _, ap := range objectPaths([]any{v}) {
+		props, err := c.conn.GetAllProperties(ctx, BusName, ap, IfaceActiveConnection)
+		if err != nil {
+			continue
+		}
+		conn, _ := props["Connection"].(dbus.ObjectPath)
+		state, _ := props["State"].(uint32)
+		out[conn] = activeInfo{path: ap, state: state}
+	}
+	return out, nil
+}
+
+// Find resolves a connection by id or UUID.
+func (c *Client) Find(ctx context.Context, name string) (Connection, error) {
+	conns, err := c.Connections(ctx)
+	if err != nil {
+		return Connection{}, err
+	}
+	for _, conn := range conns {
+		if conn.ID == name || conn.UUID == name {
+			return conn, nil
+		}
+	}
+	return Connection{}, fmt.Errorf("networkmanager: no VPN or WireGuard connection named %q", name)
+}
+
+// Deactivate brings a named connection down.
+func (c *Client) Deactivate(ctx context.Context, name string) error {
+	conn, err := c.Find(ctx, name)
+	if err != nil {
+		return err
+	}
+	if conn.ActivePath == "" {
+		return fmt.Errorf("networkmanager: connection %q is not active", conn.ID)
+	}
+	_, err = c.conn.Call(ctx, BusName, RootPath, IfaceNetworkManager, "DeactivateConnection", "o", conn.ActivePath)
+	return err
+}
+
+// Activate brings a named connection up and returns its active connection path.
+func (c *Client) Activate(ctx context.Context, name string) (dbus.ObjectPath, error) {
+	conn, err := c.Find(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	out, err := c.conn.Call(ctx, BusName, RootPath, IfaceNetworkManager, "ActivateConnection", "ooo", conn.Path, noObject, noObject)
+	if err != nil {
+		return "", err
+	}
+	if len(out) > 0 {
+		p, _ := out[0].(dbus.ObjectPath)
+		return p, nil
+	}
+	return "", nil
+}
+
+type StateEvent struct {
+	AtUTC  time.Time       `json:"at_utc"`
+	Object dbus.ObjectPath `json:"object"`
+	Kind   string          `json:"kind"` // vpn|active|device
+	Name   string          `json:"name,omitempty"`
+	State  string          `json:"state"`
+	Reason uint32          `json:"reason,omitempty"`
+}
+
+// Watch streams VPN, active connection and device state changes until ctx is done.
+func (c *Client) Watch(ctx context.Context) (<-chan StateEvent, error) {
+	signals, err := c.conn.Subscribe(ctx, "type='signal',sender='"+BusName+"'")
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan StateEvent, 16)
+	go func() {
+		defer close(out)
+		names := map[dbus.ObjectPath]string{}
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case m, ok := <-signals:
+				if !ok {
+					return
+				}
+				ev, ok := stateEventFromSignal(m)
+				if !ok {
+					continue
+				}
+				ev.Name = c.objectName(ctx, names, ev)
+				select {
+				case out <- ev:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out, nil
+}
+
+func stateEventFromSignal(m *dbus.Message) (StateEvent, bool) {
+	ev := StateEvent{AtUTC: time.Now().UTC(), Object: m.Path}
+	u := func(i int) uint32 {
+		if i >= len(m.Body) {
+			return 0
+		}
+		v, _ := m.Body[i].(uint32)
+		return v
+	}
+
+	switch {
+	case m.Interface == IfaceVPNConnection && m.Member == "VpnStateChanged":
+		ev.Kind = "vpn"
+		ev.State = VPNStateName(u(0))
+		ev.Reason = u(1)
+	case m.Interface == IfaceActiveConnection && m.Member == "StateChanged":
+		ev.Kind = "active"
+		ev.State = ActiveStateName(u(0))
+		ev.Reason = u(1)
+	case m.Interface == IfaceDevice && m.Member == "StateChanged":
+		ev.Kind = "device"
+		ev.State = DeviceStateName(u(0))
+		ev.Reason = u(2)
+	default:
+		return StateEvent{}, false
+	}
+	return ev, true
+}
+
+// objectName resolves a human name for the signalling object, caching by path.
+// Deactivated objects disappear quickly, so lookups are best-effort.
+func (c *Client) objectName(ctx context.Context, cache map[dbus.ObjectPath]string, ev StateEvent) string {
+	if n, ok := cache[ev.Object]; ok {
+		return n
+	}
+
+	lctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	var v any
+	var err error
+	if ev.Kind == "device" {
+		v, err = c.conn.GetProperty(lctx, BusName, ev.Object, IfaceDevice, "Interface")
+	} else {
+		v, err = c.conn.GetProperty(lctx, BusName, ev.Object, IfaceActiveConnection, "Id")
+	}
+	if err != nil {
+		return ""
+	}
+	n, _ := v.(string)
+	cache[ev.Object] = n
+	return n
+}
+
+// State names follow NMVpnConnectionState, NMActiveConnectionState and NMDeviceState.
+
+func VPNStateName(s uint32) string {
+	names := []string{"unknown", "prepare", "need-auth", "connect", "ip-config-get", "activated", "failed", "disconnected"}
+	if int(s) < len(names) {
+		return names[s]
+	}
+	return fmt.Sprintf("state-%d", s)
+}
+
+func ActiveStateName(s uint32) string {
+	names := []string{"unknown", "activating", "activated", "deactivating", "deactivated"}
+	if int(s) < len(names) {
+		return names[s]
+	}
+	return fmt.Sprintf("state-%d", s)
+}
+
+func DeviceStateName(s uint32) string {
+	switch s {
+	case 0:
+		return "unknown"
+	case 10:
+		return "unmanaged"
+	case 20:
+		return "unavailable"
+	case 30:
+		return "disconnected"
+	case 40:
+		return "prepare"
+	case 50:
+		return "config"
+	case 60:
+		return "need-auth"
+	case 70:
+		return "ip-config"
+	case 80:
+		return "ip-check"
+	case 90:
+		return "secondaries"
+	case 100:
+		return "activated"
+	case 110:
+		return "deactivating"
+	case 120:
+		return "failed"
+	}
+	return fmt.Sprintf("state-%d", s)
+}
+
+func objectPaths(body []any) []dbus.ObjectPath {
+	if len(body) == 0 {
+		return nil
+	}
+	list, _ := body[0].([]any)
+	out := make([]dbus.ObjectPath, 0, len(list))
+	for _, v := range list {
+		if p, ok := v.(dbus.ObjectPath); ok && p != noObject {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// dictValue reads key from a decoded a{s...} dict, unwrapping variants.
+func dictValue(v any, key string) any {
+	m, ok := v.(map[any]any)
+	if !ok {
+		return nil
+	}
+	val := m[key]
+	if vv, ok := val.(dbus.Variant); ok {
+		return vv.Value
+	}
+	return val
+}
+
+func stringValue(v any) string {
+	s, _ := v.(string)
+	return strings.TrimSpace(s)
+}
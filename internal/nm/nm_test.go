@@ -0,0 +1,189 @@
+// File: internal/nm/nm_test.go (complete file)
+
+package nm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/baptistax/vpn-leak-identifier/internal/dbus"
+	"github.com/baptistax/vpn-leak-identifier/internal/dbus/dbustest"
+)
+
+// mockNM is a stand-in NetworkManager exporting one VPN, one WireGuard and
+// one ethernet profile; only the VPN starts active.
+type mockNM struct {
+	conn *dbus.Conn
+
+	mu     sync.Mutex
+	active map[dbus.ObjectPath]dbus.ObjectPath // active path -> settings path
+}
+
+func newMockNM(t *testing.T, addr string) *mockNM {
+	t.Helper()
+
+	conn, err := dbus.Dial(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	m := &mockNM{
+		conn:   conn,
+		active: map[dbus.ObjectPath]dbus.ObjectPath{"/org/freedesktop/NetworkManager/ActiveConnection/1": "/org/freedesktop/NetworkManager/Settings/1"},
+	}
+
+	profiles := map[dbus.ObjectPath][3]string{
+		"/org/freedesktop/NetworkManager/Settings/1": {"Work VPN", "uuid-vpn", "vpn"},
+		"/org/freedesktop/NetworkManager/Settings/2": {"wg-home", "uuid-wg", "wireguard"},
+		"/org/freedesktop/NetworkManager/Settings/3": {"Wired", "uuid-eth", "802-3-ethernet"},
+	}
+
+	conn.Export(SettingsPath, IfaceSettings, func(member string, args []any) (dbus.Signature, []any, error) {
+		var paths []dbus.ObjectPath
+		for p := range profiles {
+			paths = append(paths, p)
+		}
+		return "ao", []any{paths}, nil
+	})
+	for path, p := range profiles {
+		p := p
+		conn.Export(path, IfaceSettingsConn, func(member string, args []any) (dbus.Signature, []any, error) {
+			settings := map[string]map[string]dbus.Variant{
+				"connection": {
+					"id":   dbus.MakeVariant(p[0]),
+					"uuid": dbus.MakeVariant(p[1]),
+					"type": dbus.MakeVariant(p[2]),
+				},
+			}
+			return "a{sa{sv}}", []any{settings}, nil
+		})
+	}
+
+	conn.Export(RootPath, "org.freedesktop.DBus.Properties", func(member string, args []any) (dbus.Signature, []any, error) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		var paths []dbus.ObjectPath
+		for p := range m.active {
+			paths = append(paths, p)
+		}
+		return "v", []any{dbus.MakeVariant(paths)}, nil
+	})
+	conn.Export(RootPath, IfaceNetworkManager, func(member string, args []any) (dbus.Signature, []any, error) {
+		switch member {
+		case "DeactivateConnection":
+			ap := args[0].(dbus.ObjectPath)
+			m.mu.Lock()
+			delete(m.active, ap)
+			m.mu.Unlock()
+			_ = conn.Emit(ap, IfaceVPNConnection, "VpnStateChanged", "uu", uint32(7), uint32(2))
+			return "", nil, nil
+		case "ActivateConnection":
+			ap := dbus.ObjectPath("/org/freedesktop/NetworkManager/ActiveConnection/2")
+			m.mu.Lock()
+			m.active[ap] = args[0].(dbus.ObjectPath)
+			m.mu.Unlock()
+			m.exportActive(ap)
+			_ = conn.Emit(ap, IfaceVPNConnection, "VpnStateChanged", "uu", uint32(5), uint32(0))
+			return "o", []any{ap}, nil
+		}
+		return "", nil, errors.New("unexpected " + member)
+	})
+	m.exportActive("/org/freedesktop/NetworkManager/ActiveConnection/1")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := conn.RequestName(ctx, BusName); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func (m *mockNM) exportActive(ap dbus.ObjectPath) {
+	m.conn.Export(ap, "org.freedesktop.DBus.Properties", func(member string, args []any) (dbus.Signature, []any, error) {
+		m.mu.Lock()
+		settings, ok := m.active[ap]
+		m.mu.Unlock()
+		if !ok {
+			return "", nil, fmt.Errorf("object %s is gone", ap)
+		}
+		props := map[string]dbus.Variant{
+			"Id":         dbus.MakeVariant("Work VPN"),
+			"Connection": dbus.MakeVariant(settings),
+			"State":      dbus.MakeVariant(uint32(2)),
+		}
+		if member == "Get" {
+			return "v", []any{props[args[1].(string)]}, nil
+		}
+		return "a{sv}", []any{props}, nil
+	})
+}
+
+func TestConnections_ListsVPNAndWireGuard(t *testing.T) {
+	addr := dbustest.StartSessionBus(t)
+	newMockNM(t, addr)
+
+	conn, err := dbus.Dial(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := New(conn)
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conns, err := c.Connections(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conns) != 2 {
+		t.Fatalf("expected 2 connections, got %+v", conns)
+	}
+	if conns[0].ID != "Work VPN" || conns[0].State != "activated" {
+		t.Fatalf("unexpected vpn connection: %+v", conns[0])
+	}
+	if conns[1].ID != "wg-home" || conns[1].State != "inactive" {
+		t.Fatalf("unexpected wireguard connection: %+v", conns[1])
+	}
+}
+
+func TestDeactivateReactivate_EmitsStateEvents(t *testing.T) {
+	addr := dbustest.StartSessionBus(t)
+	newMockNM(t, addr)
+
+	conn, err := dbus.Dial(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := New(conn)
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	events, err := c.Watch(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Deactivate(ctx, "Work VPN"); err != nil {
+		t.Fatal(err)
+	}
+	ev := <-events
+	if ev.Kind != "vpn" || ev.State != "disconnected" {
+		t.Fatalf("unexpected event: %+v", ev)
+	}
+
+	if _, err := c.Activate(ctx, "uuid-vpn"); err != nil {
+		t.Fatal(err)
+	}
+	ev = <-events
+	if ev.Kind != "vpn" || ev.State != "activated" || ev.Name != "Work VPN" {
+		t.Fatalf("unexpected event: %+v", ev)
+	}
+}
@@ -0,0 +1,288 @@
+// File: internal/report/checks.go (complete file)
+
+package report
+
+import (
+	"fmt"
+	"strings"
+)
+
+type CheckStatus string
+
+const (
+	CheckPass CheckStatus = "pass"
+	CheckFail CheckStatus = "fail"
+	CheckSkip CheckStatus = "skip"
+)
+
+// Check is one pass/fail/skip result derived from a report, e.g. a test case
+// in JUnit or TAP output. Evidence lists the observations behind a failure.
+type Check struct {
+	Name     string      `json:"name"`
+	Status   CheckStatus `json:"status"`
+	Message  string      `json:"message,omitempty"`
+	Evidence []string    `json:"evidence,omitempty"`
+}
+
+// RunChecks derives the individual checks of a test run.
+func RunChecks(r RunReport) []Check {
+	var out []Check
+
+	if r.Baseline.Online {
+		out = append(out, Check{Name: "baseline_connectivity", Status: CheckPass, Message: "baseline reached the internet"})
+	} else {
+		out = append(out, Check{Name: "baseline_connectivity", Status: CheckFail, Message: "no connectivity during the baseline window",
+			Evidence: []string{"ipv4: " + exitEvidence(r.Baseline.ExitV4), "ipv6: " + exitEvidence(r.Baseline.ExitV6)}})
+	}
+
+	out = append(out, exitLeakCheck(r, "ipv4", r.Baseline.ExitV4))
+	out = append(out, exitLeakCheck(r, "ipv6", r.Baseline.ExitV6))
+
+	switch {
+	case r.DNS != nil && r.DNS.Verdict == DNSLeak:
+		out = append(out, dnsCheck(r.DNS))
+	case r.DNSDelta != nil:
+		c := Check{Name: "dns_leak", Status: CheckFail,
+			Message: fmt.Sprintf("DNS recursors changed at T+%ds", r.DNSDelta.AtSec),
+			Evidence: []string{
+				"before: " + strings.Join(r.DNSDelta.From, ", "),
+				fmt.Sprintf("T+%ds: %s", r.DNSDelta.AtSec, strings.Join(r.DNSDelta.To, ", ")),
+			}}
+		for _, d := range r.DNSDelta.Lookups {
+			c.Evidence = append(c.Evidence, fmt.Sprintf("%s: %s -> %s", d.Label(), strings.Join(d.From, ", "), strings.Join(d.To, ", ")))
+		}
+		out = append(out, c)
+	case len(r.Baseline.DNSRecursors) == 0:
+		out = append(out, Check{Name: "dns_leak", Status: CheckSkip, Message: "no DNS recursors observed"})
+	case r.DNS != nil && r.DNS.Verdict == DNSPass:
+		out = append(out, dnsCheck(r.DNS))
+	default:
+		out = append(out, Check{Name: "dns_leak", Status: CheckPass, Message: "DNS recursors stayed the same"})
+	}
+
+	out = append(out, stunCheck(r))
+	if r.IPv6 != nil {
+		out = append(out, ipv6Check(r.IPv6))
+	}
+	if r.Host != nil {
+		out = append(out, hostCheck(r.Host))
+	}
+
+	switch {
+	case r.Mode != RunModeKillSwitch:
+		out = append(out, Check{Name: "kill_switch", Status: CheckSkip, Message: "not tested (vpn-only mode)"})
+	case r.Verdict.KillSwitch == "PASS":
+		out = append(out, Check{Name: "kill_switch", Status: CheckPass, Message: r.Verdict.Reason})
+	case r.Verdict.KillSwitch == "FAIL":
+		c := Check{Name: "kill_switch", Status: CheckFail, Message: "traffic continued outside the VPN"}
+		for _, d := range r.ExitDeltas {
+			c.Evidence = append(c.Evidence, fmt.Sprintf("T+%ds %s exit %s -> %s", d.AtSec, d.Family, exitEvidence(d.From), exitEvidence(d.To)))
+		}
+		out = append(out, c)
+	default:
+		out = append(out, Check{Name: "kill_switch", Status: CheckSkip, Message: r.Verdict.Reason})
+	}
+
+	if r.TunnelBypass != nil {
+		out = append(out, Check{Name: "wireguard_tunnel", Status: CheckFail,
+			Message:  fmt.Sprintf("%s: %s at T+%ds", r.TunnelBypass.Device, r.TunnelBypass.Reason, r.TunnelBypass.AtSec),
+			Evidence: []string{"peer: " + r.TunnelBypass.Peer}})
+	} else if len(r.Baseline.WireGuard) > 0 {
+		out = append(out, Check{Name: "wireguard_tunnel", Status: CheckPass, Message: "WireGuard tunnel kept carrying traffic"})
+	}
+
+	// Failures carry the verdict reason so a CI log explains itself.
+	if reason := strings.TrimSpace(r.Verdict.Reason); reason != "" {
+		for i := range out {
+			if out[i].Status == CheckFail {
+				out[i].Evidence = append(out[i].Evidence, "verdict: "+r.Verdict.Overall+" - "+reason)
+			}
+		}
+	}
+	return out
+}
+
+func exitEvidence(e ExitInfo) string {
+	if e.Error != "" {
+		return "error: " + e.Error
+	}
+	if strings.TrimSpace(e.IP) == "" {
+		return "unavailable"
+	}
+	s := e.IP + formatGeoSuffix(e.Geo)
+	if n := geoNetwork(e.Geo); n != "" {
+		s += " " + n
+	}
+	if c := e.Geo.Consensus; c != nil {
+		s += " " + consensusText(c)
+	}
+	return s
+}
+
+// consensusText summarizes a geo consensus, e.g. "[agreed 3/4]" or
+// "[disputed 2/4; ip: ident.me=198.51.100.1, ipinfo.io=203.0.113.7]".
+func consensusText(c *GeoConsensus) string {
+	s := fmt.Sprintf("[%s %d/%d", c.Status, len(c.Agreeing), len(c.Providers))
+	for _, d := range c.Disagreements {
+		var vals []string
+		for _, a := range c.Providers {
+			if v, ok := d.Values[a.Provider]; ok {
+				vals = append(vals, a.Provider+"="+v)
+			}
+		}
+		s += "; " + d.Field + ": " + strings.Join(vals, ", ")
+	}
+	return s + "]"
+}
+
+func exitLeakCheck(r RunReport, family string, base ExitInfo) Check {
+	name := family + "_leak"
+	if d := findExitDelta(r, family); d != nil {
+		return Check{Name: name, Status: CheckFail,
+			Message: fmt.Sprintf("exit changed %s -> %s at T+%ds", d.From.IP, d.To.IP, d.AtSec),
+			Evidence: []string{
+				"baseline: " + exitEvidence(d.From),
+				fmt.Sprintf("T+%ds: %s", d.AtSec, exitEvidence(d.To)),
+			}}
+	}
+	if base.IP == "" {
+		// A family that was unavailable at baseline but shows up later
+		// bypasses a VPN that only covers the other family.
+		if e, at, ok := r.appearedExit(family); ok {
+			return Check{Name: name, Status: CheckFail,
+				Message: fmt.Sprintf("%s exit %s appeared at T+%ds", family, e.IP, at),
+				Evidence: []string{
+					"baseline: " + exitEvidence(base),
+					fmt.Sprintf("T+%ds: %s", at, exitEvidence(e)),
+				}}
+		}
+		return Check{Name: name, Status: CheckPass, Message: family + " unavailable for the whole run"}
+	}
+	for _, p := range r.Probes {
+		e := p.Exit(family)
+		if e.IP != "" && !e.Geo.Trusted() {
+			return Check{Name: name, Status: CheckSkip,
+				Message:  fmt.Sprintf("geo providers %s on the exit at T+%ds", e.Geo.Consensus.Status, p.AtSec),
+				Evidence: []string{fmt.Sprintf("T+%ds: %s", p.AtSec, exitEvidence(e))}}
+		}
+	}
+	return Check{Name: name, Status: CheckPass, Message: "exit stayed " + base.IP}
+}
+
+func stunCheck(r RunReport) Check {
+	probes := r.Probes
+	if len(probes) == 0 {
+		probes = []ProbeSet{r.Baseline, r.End}
+	}
+	seen := false
+	for _, p := range probes {
+		if len(p.StunObserved) == 0 {
+			continue
+		}
+		seen = true
+		if bad := stunOutsideExits(p); len(bad) > 0 {
+			return Check{Name: "stun_mismatch", Status: CheckFail,
+				Message: fmt.Sprintf("STUN observed %s outside the HTTP exit at T+%ds", strings.Join(bad, ", "), p.AtSec),
+				Evidence: []string{
+					fmt.Sprintf("T+%ds STUN: %s", p.AtSec, strings.Join(p.StunObserved, ", ")),
+					fmt.Sprintf("T+%ds HTTP exits: %s, %s", p.AtSec, exitEvidence(p.ExitV4), exitEvidence(p.ExitV6)),
+				}}
+		}
+	}
+	if !seen {
+		return Check{Name: "stun_mismatch", Status: CheckSkip, Message: "no STUN observations"}
+	}
+	return Check{Name: "stun_mismatch", Status: CheckPass, Message: "STUN addresses matched the HTTP exit"}
+}
+
+// stunOutsideExits returns STUN addresses that are not one of the probe's
+// HTTP exits; probes without an HTTP exit cannot be compared.
+func stunOutsideExits(p ProbeSet) []string {
+	exits := map[string]bool{}
+	for _, e := range []ExitInfo{p.ExitV4, p.ExitV6} {
+		if e.IP != "" && e.Error == "" {
+			exits[e.IP] = true
+		}
+	}
+	if len(exits) == 0 {
+		return nil
+	}
+	var out []string
+	for _, ip := range p.StunObserved {
+		if !exits[ip] {
+			out = append(out, ip)
+		}
+	}
+	return out
+}
+
+// SnapshotChecks derives checks from a single snapshot.
+func SnapshotChecks(s Snapshot) []Check {
+	var out []Check
+	online := exitKey(s.ExitV4) != "" || exitKey(s.ExitV6) != ""
+	for _, r := range s.PublicIPs {
+		if r.IP != "" && r.Error == "" {
+			online = true
+		}
+	}
+	if online {
+		out = append(out, Check{Name: "connectivity", Status: CheckPass, Message: "exit probes succeeded"})
+	} else {
+		out = append(out, Check{Name: "connectivity", Status: CheckFail, Message: "no exit probe succeeded"})
+	}
+
+	// Compare STUN with every HTTP exit the snapshot saw: ident.me and ipify.
+	ps := ProbeSet{Observation: s.Observation}
+	for _, r := range s.PublicIPs {
+		if r.Error != "" || ps.Exit(r.Family).IP != "" {
+			continue
+		}
+		switch r.Family {
+		case "ipv4":
+			ps.ExitV4 = ExitInfo{Family: "ipv4", IP: r.IP}
+		case "ipv6":
+			ps.ExitV6 = ExitInfo{Family: "ipv6", IP: r.IP}
+		}
+	}
+	if s.DNS != nil {
+		out = append(out, dnsCheck(s.DNS))
+	}
+	if s.IPv6 != nil {
+		out = append(out, ipv6Check(s.IPv6))
+	}
+	if s.Host != nil {
+		out = append(out, hostCheck(s.Host))
+	}
+
+	switch bad := stunOutsideExits(ps); {
+	case len(s.StunObserved) == 0:
+		out = append(out, Check{Name: "stun_mismatch", Status: CheckSkip, Message: "no STUN observations"})
+	case len(bad) > 0:
+		out = append(out, Check{Name: "stun_mismatch", Status: CheckFail, Message: "STUN observed " + strings.Join(bad, ", ") + " outside the HTTP exit"})
+	default:
+		out = append(out, Check{Name: "stun_mismatch", Status: CheckPass, Message: "STUN addresses matched the HTTP exit"})
+	}
+	return out
+}
+
+// dnsCheck turns a recursor classification into the dns_leak check; every
+// recursor is listed as evidence.
+func dnsCheck(a *DNSAssessment) Check {
+	c := Check{Name: "dns_leak", Status: CheckSkip, Message: a.Reason}
+	switch a.Verdict {
+	case DNSLeak:
+		c.Status = CheckFail
+	case DNSPass:
+		c.Status = CheckPass
+	}
+	for _, r := range a.Recursors {
+		c.Evidence = append(c.Evidence, recursorText(r))
+	}
+	return c
+}
+
+// recursorText renders e.g. "192.0.2.53: isp AS64501 Example ISP - AS64501
+// differs from the exit AS64500".
+func recursorText(r RecursorAssessment) string {
+	return r.IP + ": " + strings.Join(nonEmpty(r.Class, r.ASN, r.Org), " ") + " - " + r.Reason
+}
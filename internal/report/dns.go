@@ -0,0 +1,223 @@
+// File: internal/report/dns.go (complete file)
+
+package report
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+)
+
+// DNS verdicts.
+const (
+	DNSPass    = "PASS"
+	DNSLeak    = "LEAK"
+	DNSUnknown = "UNKNOWN"
+)
+
+// Recursor classes.
+const (
+	RecursorVPN        = "vpn"
+	RecursorPublic     = "public"
+	RecursorISP        = "isp"
+	RecursorThirdParty = "third-party"
+	RecursorUnknown    = "unknown"
+)
+
+// DNSAssessment says who operates the recursors a probe round saw.
+type DNSAssessment struct {
+	Verdict   string               `json:"verdict"` // PASS|LEAK|UNKNOWN
+	Reason    string               `json:"reason,omitempty"`
+	Recursors []RecursorAssessment `json:"recursors,omitempty"`
+}
+
+// RecursorAssessment is the class of one recursor and why.
+type RecursorAssessment struct {
+	IP     string `json:"ip"`
+	Class  string `json:"class"` // vpn|public|isp|third-party|unknown
+	ASN    string `json:"asn,omitempty"`
+	Org    string `json:"org,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// DNSProfile is what the user knows about their networks: the VPN
+// provider's resolvers (IPs, CIDRs) and ASNs, and their real ISP.
+type DNSProfile struct {
+	VPNNetworks []*net.IPNet
+	VPNASNs     []string
+	ISPNetworks []*net.IPNet
+}
+
+// publicResolver is a large public resolver operator. Recursors are matched
+// by ASN since ns.ident.me sees their unicast egress addresses, not the
+// anycast service address.
+type publicResolver struct {
+	name string
+	asns []string
+}
+
+var publicResolvers = []publicResolver{
+	{"Google Public DNS", []string{"AS15169", "AS396982"}},
+	{"Cloudflare", []string{"AS13335"}},
+	{"Quad9", []string{"AS19281"}},
+	{"OpenDNS", []string{"AS36692"}},
+}
+
+// ClassifyDNS classifies the recursors in o against the VPN exits in ref.
+// Recursor and exit ASNs come from the exits' geo and from o.IPGeo (MMDB or
+// origin lookups); without them most recursors stay unknown.
+func ClassifyDNS(o, ref Observation, p DNSProfile) *DNSAssessment {
+	a := &DNSAssessment{}
+	for _, ip := range o.DNSRecursors {
+		a.Recursors = append(a.Recursors, classifyRecursor(ip, o.IPGeo[ip], ref, p))
+	}
+
+	var isp, thirdParty, unknown, public []string
+	for _, r := range a.Recursors {
+		switch r.Class {
+		case RecursorISP:
+			isp = append(isp, r.IP)
+		case RecursorThirdParty:
+			thirdParty = append(thirdParty, r.IP)
+		case RecursorUnknown:
+			unknown = append(unknown, r.IP)
+		case RecursorPublic:
+			public = append(public, r.IP)
+		}
+	}
+	switch {
+	case len(a.Recursors) == 0:
+		a.Verdict, a.Reason = DNSUnknown, "no DNS recursors observed"
+	case len(isp) > 0:
+		a.Verdict, a.Reason = DNSLeak, "recursors in your ISP network: "+strings.Join(isp, ", ")
+	case len(thirdParty) > 0:
+		a.Verdict, a.Reason = DNSUnknown, "recursors run by neither the VPN provider nor a public resolver: "+
+			strings.Join(thirdParty, ", ")+" (pass --known-isp to tell your ISP's apart)"
+	case len(unknown) > 0:
+		a.Verdict, a.Reason = DNSUnknown, "no ownership data for "+strings.Join(unknown, ", ")
+	case len(public) > 0:
+		a.Verdict, a.Reason = DNSPass, "recursors belong to the VPN provider or public resolvers ("+strings.Join(public, ", ")+")"
+	default:
+		a.Verdict, a.Reason = DNSPass, "all recursors belong to the VPN provider"
+	}
+	return a
+}
+
+func classifyRecursor(ip string, g GeoInfo, ref Observation, p DNSProfile) RecursorAssessment {
+	r := RecursorAssessment{IP: ip, ASN: g.ASN, Org: g.ISP, Class: RecursorUnknown}
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		r.Reason = "not an IP address"
+		return r
+	}
+
+	if n := matchNetwork(parsed, p.VPNNetworks); n != "" {
+		r.Class, r.Reason = RecursorVPN, "listed in the VPN profile ("+n+")"
+		return r
+	}
+	if r.ASN != "" && containsFold(p.VPNASNs, r.ASN) {
+		r.Class, r.Reason = RecursorVPN, r.ASN+" is listed in the VPN profile"
+		return r
+	}
+	if n := matchNetwork(parsed, p.ISPNetworks); n != "" {
+		r.Class, r.Reason = RecursorISP, "inside your ISP network "+n
+		return r
+	}
+
+	for _, family := range []string{"ipv4", "ipv6"} {
+		e := ref.Exit(family)
+		exitIP := net.ParseIP(e.IP)
+		if exitIP == nil || e.Error != "" {
+			continue
+		}
+		eg := ref.IPGeo[e.IP]
+		if _, n, err := net.ParseCIDR(g.Prefix); err == nil && n.Contains(exitIP) {
+			r.Class, r.Reason = RecursorVPN, "same announced prefix "+g.Prefix+" as the "+family+" exit"
+			return r
+		}
+		if _, n, err := net.ParseCIDR(eg.Prefix); err == nil && n.Contains(parsed) {
+			r.Class, r.Reason = RecursorVPN, "inside the "+family+" exit prefix "+eg.Prefix
+			return r
+		}
+		if sameSubnet(parsed, exitIP) {
+			r.Class, r.Reason = RecursorVPN, "same subnet as the "+family+" exit "+e.IP
+			return r
+		}
+		if exitASN := firstNonEmpty(eg.ASN, e.Geo.ASN); r.ASN != "" && strings.EqualFold(r.ASN, exitASN) {
+			r.Class, r.Reason = RecursorVPN, "same "+r.ASN+" as the "+family+" exit"
+			return r
+		}
+	}
+
+	if r.ASN == "" {
+		r.Reason = "no ASN data for this recursor"
+		return r
+	}
+	for _, pr := range publicResolvers {
+		if containsFold(pr.asns, r.ASN) {
+			r.Class, r.Reason = RecursorPublic, pr.name+" ("+r.ASN+")"
+			return r
+		}
+	}
+	exitASNs := exitASNs(ref)
+	if len(exitASNs) == 0 {
+		r.Reason = "no ASN data for the exit"
+		return r
+	}
+	// Another operator; only the user's own networks say it is their ISP.
+	r.Class = RecursorThirdParty
+	r.Reason = fmt.Sprintf("%s differs from the exit %s", r.ASN, strings.Join(exitASNs, ", "))
+	return r
+}
+
+func exitASNs(ref Observation) []string {
+	var out []string
+	for _, e := range []ExitInfo{ref.ExitV4, ref.ExitV6} {
+		if e.IP == "" || e.Error != "" {
+			continue
+		}
+		if asn := firstNonEmpty(ref.IPGeo[e.IP].ASN, e.Geo.ASN); asn != "" && !containsFold(out, asn) {
+			out = append(out, asn)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// sameSubnet treats addresses in the same /24 (IPv4) or /48 (IPv6) as one
+// operator's; VPN servers often run their resolver next to the exit.
+func sameSubnet(a, b net.IP) bool {
+	if a4, b4 := a.To4(), b.To4(); a4 != nil || b4 != nil {
+		return a4 != nil && b4 != nil && a4.Mask(net.CIDRMask(24, 32)).Equal(b4.Mask(net.CIDRMask(24, 32)))
+	}
+	m := net.CIDRMask(48, 128)
+	return a.Mask(m).Equal(b.Mask(m))
+}
+
+func matchNetwork(ip net.IP, nets []*net.IPNet) string {
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return n.String()
+		}
+	}
+	return ""
+}
+
+func containsFold(list []string, s string) bool {
+	for _, x := range list {
+		if strings.EqualFold(x, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func firstNonEmpty(vals ...string) string {
+	for _, v := range vals {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,159 @@
+// File: internal/report/host.go (complete file)
+
+package report
+
+import (
+	"fmt"
+	"strings"
+)
+
+// HostConfig is the audited host configuration: the sysctls and resolver
+// settings that decide whether DNS and IPv6 follow the VPN.
+type HostConfig struct {
+	Tunnels          []string       `json:"tunnels,omitempty"`
+	Sysctls          []HostSysctl   `json:"sysctls,omitempty"`
+	Nameservers      []string       `json:"nameservers,omitempty"`
+	ResolvConfTarget string         `json:"resolv_conf_target,omitempty"`
+	NSSwitchHosts    []string       `json:"nsswitch_hosts,omitempty"`
+	ResolvedLinks    []ResolvedLink `json:"resolved_links,omitempty"`
+	DNSRoutes        []DNSRoute     `json:"dns_routes,omitempty"`
+	Findings         []HostFinding  `json:"findings,omitempty"`
+	Errors           []string       `json:"errors,omitempty"`
+}
+
+// HostSysctl is one sysctl, e.g. "net.ipv6.conf.eth0.disable_ipv6" = 0.
+type HostSysctl struct {
+	Name  string `json:"name"`
+	Value int    `json:"value"`
+}
+
+// ResolvedLink is systemd-resolved's DNS configuration of one link; index 0
+// is the global scope.
+type ResolvedLink struct {
+	Index        int      `json:"index"`
+	Name         string   `json:"name"`
+	Servers      []string `json:"servers,omitempty"`
+	Domains      []string `json:"domains,omitempty"`
+	DefaultRoute bool     `json:"default_route"`
+}
+
+// HostFinding is one setting that lets DNS or IPv6 bypass the VPN.
+type HostFinding struct {
+	Severity string `json:"severity"` // info|warning|critical
+	Kind     string `json:"kind"`
+	Message  string `json:"message"`
+}
+
+// Critical returns the critical findings.
+func (h *HostConfig) Critical() []HostFinding {
+	if h == nil {
+		return nil
+	}
+	var out []HostFinding
+	for _, f := range h.Findings {
+		if f.Severity == "critical" {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// RenderHostConfigText renders the audit as printed by the audit command.
+func RenderHostConfigText(h HostConfig) string {
+	var b strings.Builder
+	writeHostConfig(&b, &h, true)
+	return b.String()
+}
+
+// writeHostConfig prints the "Host configuration" section; full adds the
+// sysctls, which reports leave to the JSON.
+func writeHostConfig(b *strings.Builder, h *HostConfig, full bool) {
+	if h == nil {
+		return
+	}
+	b.WriteString("\nHost configuration:\n")
+	tunnels := "none"
+	if len(h.Tunnels) > 0 {
+		tunnels = strings.Join(h.Tunnels, ", ")
+	}
+	b.WriteString("  Tunnels: " + tunnels + "\n")
+	if len(h.Nameservers) > 0 {
+		line := "  resolv.conf: " + strings.Join(h.Nameservers, ", ")
+		if h.ResolvConfTarget != "" {
+			line += " (-> " + h.ResolvConfTarget + ")"
+		}
+		b.WriteString(line + "\n")
+	}
+	if len(h.NSSwitchHosts) > 0 {
+		b.WriteString("  nsswitch hosts: " + strings.Join(h.NSSwitchHosts, " ") + "\n")
+	}
+	for _, l := range h.ResolvedLinks {
+		b.WriteString("  resolved " + resolvedLinkText(l) + "\n")
+	}
+	for _, r := range h.DNSRoutes {
+		b.WriteString("  route " + r.Text() + "\n")
+	}
+	if full {
+		for _, s := range h.Sysctls {
+			b.WriteString(fmt.Sprintf("  %s = %d\n", s.Name, s.Value))
+		}
+	}
+	for _, f := range h.Findings {
+		b.WriteString(fmt.Sprintf("  %s [%s]: %s\n", strings.ToUpper(f.Severity), f.Kind, f.Message))
+	}
+	switch {
+	case len(h.Findings) > 0:
+	case len(h.Tunnels) == 0:
+		b.WriteString("  No VPN tunnel is up; bring the VPN up to audit DNS and IPv6 routing.\n")
+	default:
+		b.WriteString("  No leak-prone settings found.\n")
+	}
+	for _, e := range h.Errors {
+		b.WriteString("  unreadable: " + e + "\n")
+	}
+}
+
+// resolvedLinkText renders e.g. "wg0: 10.64.0.1 domains ~. (default route)".
+func resolvedLinkText(l ResolvedLink) string {
+	s := l.Name + ": " + strings.Join(l.Servers, ", ")
+	if len(l.Servers) == 0 {
+		s += "no servers"
+	}
+	if len(l.Domains) > 0 {
+		s += " domains " + strings.Join(l.Domains, " ")
+	}
+	if l.DefaultRoute {
+		s += " (default route)"
+	}
+	return s
+}
+
+func writeHostConfigMarkdown(b *strings.Builder, h *HostConfig) {
+	if h == nil {
+		return
+	}
+	b.WriteString("\n## Host configuration\n\n")
+	if len(h.Findings) == 0 {
+		b.WriteString("No leak-prone settings found.\n")
+		return
+	}
+	b.WriteString("| Severity | Kind | Finding |\n|---|---|---|\n")
+	for _, f := range h.Findings {
+		b.WriteString(fmt.Sprintf("| %s | %s | %s |\n", f.Severity, f.Kind, mdCell(f.Message)))
+	}
+}
+
+// hostCheck fails on critical host configuration findings.
+func hostCheck(h *HostConfig) Check {
+	c := Check{Name: "host_config", Status: CheckPass, Message: "no leak-prone host settings found"}
+	for _, f := range h.Findings {
+		c.Evidence = append(c.Evidence, f.Severity+": "+f.Message)
+	}
+	if crit := h.Critical(); len(crit) > 0 {
+		c.Status = CheckFail
+		c.Message = fmt.Sprintf("%d critical host setting(s): %s", len(crit), crit[0].Kind)
+	} else if len(h.Findings) > 0 {
+		c.Message = "host settings below the critical level"
+	}
+	return c
+}
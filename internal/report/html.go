@@ -0,0 +1,396 @@
+// File: internal/report/html.go (complete file)
+
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+func WriteRunHTML(path string, r RunReport) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	out, err := RenderRunHTML(r)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(out), 0o644)
+}
+
+// RenderRunHTML renders a single self-contained HTML page (inline CSS and
+// SVG, no external assets) with the per-second probe timeline of a run.
+func RenderRunHTML(r RunReport) (string, error) {
+	v := runView{
+		R:        r,
+		Timeline: template.HTML(timelineSVG(r)),
+		Checks:   RunChecks(r),
+		Notes:    runNotes(r),
+	}
+	for _, e := range []ExitInfo{r.Baseline.ExitV4, r.Baseline.ExitV6} {
+		v.Exits = append(v.Exits, exitRow{Family: e.Family, Text: exitText(e), Network: geoNetwork(e.Geo)})
+	}
+	v.ExitChanges = exitChanges(r)
+	v.DNSChanges = dnsChanges(r)
+	v.Stun = stunRows(r)
+
+	var buf bytes.Buffer
+	err := runHTML.Execute(&buf, v)
+	return buf.String(), err
+}
+
+type runView struct {
+	R           RunReport
+	Timeline    template.HTML
+	Exits       []exitRow
+	ExitChanges []exitChange
+	DNSChanges  []dnsChange
+	Stun        []stunRow
+	Checks      []Check
+	Notes       []string
+}
+
+type exitRow struct{ Family, Text, Network string }
+
+type exitChange struct {
+	AtSec     int
+	Family    string
+	From, To  string
+	FromGeo   string
+	ToGeo     string
+	ToNetwork string
+}
+
+type dnsChange struct {
+	AtSec    int
+	From, To []string
+}
+
+type stunRow struct {
+	AtSec    int
+	Observed []string
+	Mismatch []string
+}
+
+// exitChanges lists every exit transition in the probe timeline (not only
+// the first one kept in ExitDeltas), including going offline and back.
+func exitChanges(r RunReport) []exitChange {
+	if len(r.Probes) == 0 {
+		var out []exitChange
+		for _, d := range r.ExitDeltas {
+			out = append(out, exitChange{AtSec: d.AtSec, Family: d.Family, From: d.From.IP, To: d.To.IP,
+				FromGeo: formatLocation(d.From.Geo), ToGeo: formatLocation(d.To.Geo), ToNetwork: geoNetwork(d.To.Geo)})
+		}
+		return out
+	}
+	var out []exitChange
+	for _, family := range []string{"ipv4", "ipv6"} {
+		prev := probeExit(r.Probes[0], family)
+		for _, p := range r.Probes[1:] {
+			cur := probeExit(p, family)
+			if exitKey(cur) == exitKey(prev) {
+				continue
+			}
+			out = append(out, exitChange{AtSec: p.AtSec, Family: family, From: exitLabel(prev), To: exitLabel(cur),
+				FromGeo: formatLocation(prev.Geo), ToGeo: formatLocation(cur.Geo), ToNetwork: geoNetwork(cur.Geo)})
+			prev = cur
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].AtSec < out[j].AtSec })
+	return out
+}
+
+func dnsChanges(r RunReport) []dnsChange {
+	var out []dnsChange
+	var prev []string
+	started := false
+	for _, p := range r.Probes {
+		if len(p.DNSRecursors) == 0 {
+			continue
+		}
+		if started && !equalStringSets(prev, p.DNSRecursors) {
+			out = append(out, dnsChange{AtSec: p.AtSec, From: prev, To: p.DNSRecursors})
+		}
+		prev, started = p.DNSRecursors, true
+	}
+	if len(out) == 0 && r.DNSDelta != nil {
+		out = append(out, dnsChange{AtSec: r.DNSDelta.AtSec, From: r.DNSDelta.From, To: r.DNSDelta.To})
+	}
+	return out
+}
+
+// stunRows keeps the STUN observations that differ from the previous one.
+func stunRows(r RunReport) []stunRow {
+	var out []stunRow
+	last := ""
+	for _, p := range r.Probes {
+		if len(p.StunObserved) == 0 {
+			continue
+		}
+		key := strings.Join(p.StunObserved, ",")
+		bad := stunOutsideExits(p)
+		if key == last && len(bad) == 0 {
+			continue
+		}
+		last = key
+		out = append(out, stunRow{AtSec: p.AtSec, Observed: p.StunObserved, Mismatch: bad})
+	}
+	return out
+}
+
+func probeExit(p ProbeSet, family string) ExitInfo {
+	if family == "ipv6" {
+		return p.ExitV6
+	}
+	return p.ExitV4
+}
+
+func exitKey(e ExitInfo) string {
+	if e.Error != "" || strings.TrimSpace(e.IP) == "" {
+		return ""
+	}
+	return e.IP
+}
+
+func exitLabel(e ExitInfo) string {
+	if k := exitKey(e); k != "" {
+		return k
+	}
+	return "offline"
+}
+
+func geoNetwork(g GeoInfo) string {
+	return strings.TrimSpace(strings.Join(nonEmpty(g.ASN, g.ISP), " "))
+}
+
+func nonEmpty(in ...string) []string {
+	var out []string
+	for _, s := range in {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// Timeline geometry.
+const (
+	tlWidth  = 960
+	tlLeft   = 70
+	tlRight  = 20
+	tlRowH   = 22
+	tlRowGap = 8
+	tlTop    = 10
+)
+
+var dnsPalette = []string{"#0969da", "#8250df", "#bf8700", "#1b7c83", "#bc4c00", "#6e7781"}
+
+func timelineSVG(r RunReport) string {
+	probes := r.Probes
+	if len(probes) == 0 {
+		return `<p class="meta">No per-second probes recorded.</p>`
+	}
+
+	span := probes[len(probes)-1].AtSec + stepSec(r)
+	if d := int(r.Duration.Duration().Seconds()); d > span {
+		span = d
+	}
+	if span <= 0 {
+		span = 1
+	}
+	plotW := float64(tlWidth - tlLeft - tlRight)
+	x := func(sec int) float64 { return tlLeft + plotW*float64(sec)/float64(span) }
+
+	rows := []string{"IPv4", "IPv6", "DNS", "STUN"}
+	height := tlTop + len(rows)*(tlRowH+tlRowGap) + 30
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" class="timeline">`, tlWidth, height, tlWidth, height)
+	for i, name := range rows {
+		fmt.Fprintf(&b, `<text x="%d" y="%d" class="label">%s</text>`, tlLeft-8, rowY(i)+tlRowH-6, name)
+		fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%.1f" height="%d" class="track"/>`, tlLeft, rowY(i), plotW, tlRowH)
+	}
+
+	// Online/offline bands per family; a segment lasts until the next probe.
+	for i, family := range []string{"ipv4", "ipv6"} {
+		base := exitKey(probeExit(r.Baseline, family))
+		segments(probes, r, func(p ProbeSet) string { return exitKey(probeExit(p, family)) }, func(from, to int, key string, p ProbeSet) {
+			class := "offline"
+			switch {
+			case key == "":
+			case key == base:
+				class = "online"
+			default:
+				class = "changed"
+			}
+			e := probeExit(p, family)
+			tip := fmt.Sprintf("T+%ds..T+%ds %s %s%s", from, to, family, exitLabel(e), formatGeoSuffix(e.Geo))
+			svgRect(&b, x(from), rowY(i), x(to)-x(from), class, "", tip)
+		})
+	}
+
+	// DNS recursor sets, one color per distinct set.
+	colors := map[string]string{}
+	segments(probes, r, func(p ProbeSet) string { return strings.Join(p.DNSRecursors, ", ") }, func(from, to int, key string, _ ProbeSet) {
+		if key == "" {
+			return
+		}
+		c, ok := colors[key]
+		if !ok {
+			c = dnsPalette[len(colors)%len(dnsPalette)]
+			colors[key] = c
+		}
+		svgRect(&b, x(from), rowY(2), x(to)-x(from), "dns", c, fmt.Sprintf("T+%ds..T+%ds DNS %s", from, to, key))
+	})
+
+	// STUN observations as dots; red when they differ from the HTTP exit.
+	for _, p := range probes {
+		if len(p.StunObserved) == 0 {
+			continue
+		}
+		class := "stun"
+		if len(stunOutsideExits(p)) > 0 {
+			class = "stun mismatch"
+		}
+		fmt.Fprintf(&b, `<circle cx="%.1f" cy="%d" r="4" class="%s"><title>%s</title></circle>`,
+			x(p.AtSec)+2, rowY(3)+tlRowH/2, class, template.HTMLEscapeString(fmt.Sprintf("T+%ds STUN %s", p.AtSec, strings.Join(p.StunObserved, ", "))))
+	}
+
+	// Event markers across all rows.
+	bottom := rowY(len(rows)) - tlRowGap
+	for _, d := range r.ExitDeltas {
+		svgMarker(&b, x(d.AtSec), bottom, "marker exit", fmt.Sprintf("T+%ds %s exit %s -> %s%s", d.AtSec, d.Family, d.From.IP, d.To.IP, formatGeoSuffix(d.To.Geo)))
+	}
+	if d := r.DNSDelta; d != nil {
+		svgMarker(&b, x(d.AtSec), bottom, "marker dns", fmt.Sprintf("T+%ds DNS %s -> %s", d.AtSec, strings.Join(d.From, ", "), strings.Join(d.To, ", ")))
+	}
+	if r.OfflineAtSec != nil {
+		svgMarker(&b, x(*r.OfflineAtSec), bottom, "marker off", fmt.Sprintf("T+%ds offline", *r.OfflineAtSec))
+	}
+	for _, e := range r.ConnectionEvents {
+		svgMarker(&b, x(e.AtSec), bottom, "marker conn", fmt.Sprintf("T+%ds %s %s %s", e.AtSec, e.Kind, e.State, e.Connection))
+	}
+
+	// Time axis.
+	tick := tickSec(span)
+	for s := 0; s <= span; s += tick {
+		fmt.Fprintf(&b, `<line x1="%.1f" y1="%d" x2="%.1f" y2="%d" class="tick"/>`, x(s), bottom, x(s), bottom+5)
+		fmt.Fprintf(&b, `<text x="%.1f" y="%d" class="axis">T+%ds</text>`, x(s), bottom+18, s)
+	}
+	b.WriteString(`</svg>`)
+	return b.String()
+}
+
+func rowY(i int) int { return tlTop + i*(tlRowH+tlRowGap) }
+
+func stepSec(r RunReport) int {
+	if s := int(r.Interval.Duration().Seconds()); s > 0 {
+		return s
+	}
+	return 1
+}
+
+// segments merges consecutive probes with the same key and calls emit with
+// the covered [from, to) interval and the first probe of the segment.
+func segments(probes []ProbeSet, r RunReport, key func(ProbeSet) string, emit func(from, to int, key string, first ProbeSet)) {
+	start := 0
+	for i := 1; i <= len(probes); i++ {
+		if i < len(probes) && key(probes[i]) == key(probes[start]) {
+			continue
+		}
+		to := probes[len(probes)-1].AtSec + stepSec(r)
+		if i < len(probes) {
+			to = probes[i].AtSec
+		}
+		emit(probes[start].AtSec, to, key(probes[start]), probes[start])
+		start = i
+	}
+}
+
+func svgRect(b *strings.Builder, x float64, y int, w float64, class, fill, tip string) {
+	style := ""
+	if fill != "" {
+		style = ` style="fill:` + fill + `"`
+	}
+	fmt.Fprintf(b, `<rect x="%.1f" y="%d" width="%.1f" height="%d" class="%s"%s><title>%s</title></rect>`,
+		x, y, w, tlRowH, class, style, template.HTMLEscapeString(tip))
+}
+
+func svgMarker(b *strings.Builder, x float64, bottom int, class, tip string) {
+	fmt.Fprintf(b, `<line x1="%.1f" y1="%d" x2="%.1f" y2="%d" class="%s"><title>%s</title></line>`,
+		x, tlTop-4, x, bottom, class, template.HTMLEscapeString(tip))
+}
+
+func tickSec(span int) int {
+	for _, t := range []int{1, 2, 5, 10, 15, 30, 60, 120, 300, 600} {
+		if span/t <= 12 {
+			return t
+		}
+	}
+	return span/12 + 1
+}
+
+var runHTML = template.Must(template.New("run").Funcs(template.FuncMap{
+	"join": strings.Join,
+}).Parse(`<!DOCTYPE html>
+<html lang="en"><head><meta charset="utf-8"><title>vpnleakID run {{.R.RunID}}</title>
+<style>` + reportCSS + `
+.verdict{display:inline-block;padding:.2em .7em;border-radius:4px;color:#fff;background:#6e7781}
+.verdict.PASS,.verdict.OK{background:#1a7f37}.verdict.FAIL{background:#cf222e}.verdict.INCONCLUSIVE{background:#9a6700}
+svg.timeline{max-width:100%;height:auto;font-size:11px}
+.label{text-anchor:end;fill:#444}.axis{text-anchor:middle;fill:#666}.tick{stroke:#999}
+.track{fill:#f6f8fa}.online{fill:#2da44e}.changed{fill:#cf222e}.offline{fill:#d0d7de}
+.stun{fill:#0969da}.stun.mismatch{fill:#cf222e}
+.marker{stroke-width:1.5;stroke-dasharray:4 3}.marker.exit{stroke:#cf222e}.marker.dns{stroke:#8250df}.marker.off{stroke:#24292f}.marker.conn{stroke:#bf8700}
+.legend span{display:inline-block;width:.9em;height:.9em;margin:0 .3em 0 1em;vertical-align:middle}
+</style></head><body>
+<h1>vpnleakID run {{.R.RunID}}</h1>
+<p class="meta">Started {{.R.StartedUTC.Format "2006-01-02T15:04:05Z"}} &middot; mode {{.R.Mode}} &middot; duration {{.R.Duration}} &middot; interval {{.R.Interval}}</p>
+
+<h2>Verdict <span class="verdict {{.R.Verdict.Overall}}">{{.R.Verdict.Overall}}</span></h2>
+{{if .R.Verdict.KillSwitch}}<p>Kill-switch: <b>{{.R.Verdict.KillSwitch}}</b>{{if .R.OfflineAtSec}} &middot; offline at T+{{.R.OfflineAtSec}}s{{end}}</p>{{end}}
+{{if .R.Verdict.Reason}}<p>{{.R.Verdict.Reason}}</p>{{end}}
+
+<h2>Timeline</h2>
+{{.Timeline}}
+<p class="legend meta"><span style="background:#2da44e"></span>baseline exit<span style="background:#cf222e"></span>different exit / STUN mismatch<span style="background:#d0d7de"></span>offline<span style="background:#0969da"></span>STUN observation &middot; dashed lines: exit change, DNS change, offline, connection event</p>
+
+<h2>Baseline exits</h2>
+<table><tr><th>Family</th><th>Exit</th><th>Network</th></tr>
+{{range .Exits}}<tr><td>{{.Family}}</td><td>{{.Text}}</td><td>{{.Network}}</td></tr>
+{{end}}</table>
+
+{{if .ExitChanges}}<h2>Exit changes</h2>
+<table><tr><th>At</th><th>Family</th><th>From</th><th>To</th><th>Network</th></tr>
+{{range .ExitChanges}}<tr><td>T+{{.AtSec}}s</td><td>{{.Family}}</td><td>{{.From}}{{if .FromGeo}} ({{.FromGeo}}){{end}}</td><td>{{.To}}{{if .ToGeo}} ({{.ToGeo}}){{end}}</td><td>{{.ToNetwork}}</td></tr>
+{{end}}</table>{{end}}
+
+<h2>DNS recursors</h2>
+<p>Baseline: {{if .R.Baseline.DNSRecursors}}{{join .R.Baseline.DNSRecursors ", "}}{{else}}none observed{{end}}</p>
+{{if .DNSChanges}}<table><tr><th>At</th><th>From</th><th>To</th></tr>
+{{range .DNSChanges}}<tr><td>T+{{.AtSec}}s</td><td>{{join .From ", "}}</td><td>{{join .To ", "}}</td></tr>
+{{end}}</table>{{end}}
+
+{{if .Stun}}<h2>STUN observations</h2>
+<table><tr><th>At</th><th>Observed</th><th>Outside HTTP exit</th></tr>
+{{range .Stun}}<tr{{if .Mismatch}} class="fail"{{end}}><td>T+{{.AtSec}}s</td><td>{{join .Observed ", "}}</td><td>{{join .Mismatch ", "}}</td></tr>
+{{end}}</table>{{end}}
+
+{{if .R.ConnectionEvents}}<h2>Connection events</h2>
+<table><tr><th>At</th><th>Kind</th><th>State</th><th>Connection</th><th>Error</th></tr>
+{{range .R.ConnectionEvents}}<tr><td>T+{{.AtSec}}s</td><td>{{.Kind}}</td><td>{{.State}}</td><td>{{.Connection}}</td><td>{{.Error}}</td></tr>
+{{end}}</table>{{end}}
+
+<h2>Checks</h2>
+<table><tr><th>Check</th><th>Status</th><th>Detail</th></tr>
+{{range .Checks}}<tr class="{{.Status}}"><td>{{.Name}}</td><td>{{.Status}}</td><td>{{.Message}}</td></tr>
+{{end}}</table>
+
+{{if .Notes}}<h2>Notes</h2><ul>{{range .Notes}}<li>{{.}}</li>{{end}}</ul>{{end}}
+</body></html>
+`))
@@ -0,0 +1,118 @@
+// File: internal/report/ipv6.go (complete file)
+
+package report
+
+import (
+	"fmt"
+	"strings"
+)
+
+// IPv6Analysis classifies the IPv6 exit and local addresses and lists the
+// IPv6 paths that can bypass an IPv4-only VPN or identify the host.
+type IPv6Analysis struct {
+	Exit          *IPv6Address  `json:"exit,omitempty"`
+	Local         []IPv6Address `json:"local,omitempty"`
+	NAT64Prefixes []string      `json:"nat64_prefixes,omitempty"`
+	Findings      []IPv6Finding `json:"findings,omitempty"`
+}
+
+// IPv6Address is a classified IPv6 address. Kinds holds global, eui64,
+// privacy, teredo, 6to4, nat64, link-local or ula.
+type IPv6Address struct {
+	IP           string   `json:"ip"`
+	Interface    string   `json:"interface,omitempty"`
+	Kinds        []string `json:"kinds,omitempty"`
+	MAC          string   `json:"mac,omitempty"`
+	EmbeddedIPv4 string   `json:"embedded_ipv4,omitempty"`
+	TeredoServer string   `json:"teredo_server,omitempty"`
+	TeredoPort   int      `json:"teredo_port,omitempty"`
+}
+
+// IPv6Finding is one exposure with an explanation of the path involved.
+type IPv6Finding struct {
+	Severity string `json:"severity"` // info|warning|critical
+	Kind     string `json:"kind"`     // eui64|privacy|teredo|6to4|nat64|native
+	Message  string `json:"message"`
+}
+
+// Critical returns the critical findings.
+func (a *IPv6Analysis) Critical() []IPv6Finding {
+	if a == nil {
+		return nil
+	}
+	var out []IPv6Finding
+	for _, f := range a.Findings {
+		if f.Severity == "critical" {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// writeIPv6Analysis prints the classified addresses worth mentioning and
+// every finding.
+func writeIPv6Analysis(b *strings.Builder, a *IPv6Analysis) {
+	if a == nil {
+		return
+	}
+	if a.Exit != nil {
+		b.WriteString("IPv6 exit: " + ipv6AddressText(*a.Exit) + "\n")
+	}
+	// Link-local and ULA addresses never leave the site; plain global ones
+	// are already listed with the interfaces.
+	for _, l := range a.Local {
+		if containsFold(l.Kinds, "link-local") || containsFold(l.Kinds, "ula") || len(l.Kinds) == 1 && l.Kinds[0] == "global" {
+			continue
+		}
+		b.WriteString(fmt.Sprintf("IPv6 [%s]: %s\n", l.Interface, ipv6AddressText(l)))
+	}
+	if len(a.NAT64Prefixes) > 0 {
+		b.WriteString("NAT64 prefix (RFC 7050): " + strings.Join(a.NAT64Prefixes, ", ") + "\n")
+	}
+	for _, f := range a.Findings {
+		b.WriteString(fmt.Sprintf("IPv6 %s: %s\n", f.Severity, f.Message))
+	}
+}
+
+// ipv6AddressText renders e.g. "2001:db8::211:22ff:fe33:4455 (global,
+// eui64; MAC 00:11:22:33:44:55)".
+func ipv6AddressText(a IPv6Address) string {
+	var extra []string
+	if a.MAC != "" {
+		extra = append(extra, "MAC "+a.MAC)
+	}
+	if a.EmbeddedIPv4 != "" {
+		extra = append(extra, "IPv4 "+a.EmbeddedIPv4)
+	}
+	if a.TeredoServer != "" {
+		extra = append(extra, fmt.Sprintf("server %s, port %d", a.TeredoServer, a.TeredoPort))
+	}
+	info := strings.Join(a.Kinds, ", ")
+	if len(extra) > 0 {
+		info += "; " + strings.Join(extra, "; ")
+	}
+	if info == "" {
+		return a.IP
+	}
+	return a.IP + " (" + info + ")"
+}
+
+// ipv6Check fails on critical findings (native IPv6 around the tunnel, an
+// EUI-64 exit, tunnel addresses revealing another IPv4).
+func ipv6Check(a *IPv6Analysis) Check {
+	c := Check{Name: "ipv6_exposure", Status: CheckPass, Message: "no IPv6 path around the VPN found"}
+	if a == nil {
+		c.Status, c.Message = CheckSkip, "not analysed"
+		return c
+	}
+	for _, f := range a.Findings {
+		c.Evidence = append(c.Evidence, f.Severity+": "+f.Message)
+	}
+	if crit := a.Critical(); len(crit) > 0 {
+		c.Status = CheckFail
+		c.Message = fmt.Sprintf("%d critical IPv6 finding(s): %s", len(crit), crit[0].Kind)
+	} else if len(a.Findings) > 0 {
+		c.Message = "IPv6 findings below the critical level"
+	}
+	return c
+}
@@ -0,0 +1,72 @@
+// File: internal/report/junit.go (complete file)
+
+package report
+
+import (
+	"encoding/xml"
+	"strings"
+	"time"
+)
+
+type junitSuites struct {
+	XMLName xml.Name     `xml:"testsuites"`
+	Suites  []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name      string      `xml:"name,attr"`
+	Tests     int         `xml:"tests,attr"`
+	Failures  int         `xml:"failures,attr"`
+	Skipped   int         `xml:"skipped,attr"`
+	Timestamp string      `xml:"timestamp,attr,omitempty"`
+	Time      float64     `xml:"time,attr"`
+	Cases     []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr,omitempty"`
+	Body    string `xml:",cdata"`
+}
+
+// RenderJUnit renders checks as a JUnit XML test suite, one test case per
+// check.
+func RenderJUnit(suite string, started time.Time, duration time.Duration, checks []Check) (string, error) {
+	s := junitSuite{Name: suite, Tests: len(checks), Time: duration.Seconds()}
+	if !started.IsZero() {
+		s.Timestamp = started.UTC().Format("2006-01-02T15:04:05")
+	}
+	for _, c := range checks {
+		tc := junitCase{Name: c.Name, ClassName: suite}
+		switch c.Status {
+		case CheckFail:
+			s.Failures++
+			tc.Failure = &junitMessage{Message: c.Message, Type: c.Name, Body: checkDetail(c)}
+		case CheckSkip:
+			s.Skipped++
+			tc.Skipped = &junitMessage{Message: c.Message}
+		default:
+			tc.SystemOut = c.Message
+		}
+		s.Cases = append(s.Cases, tc)
+	}
+	b, err := xml.MarshalIndent(junitSuites{Suites: []junitSuite{s}}, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return xml.Header + string(b) + "\n", nil
+}
+
+// checkDetail is the message followed by one evidence item per line.
+func checkDetail(c Check) string {
+	lines := append([]string{c.Message}, c.Evidence...)
+	return strings.Join(lines, "\n")
+}
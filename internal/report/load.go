@@ -0,0 +1,192 @@
+// File: internal/report/load.go (complete file)
+
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+type DocumentKind string
+
+const (
+	KindRun      DocumentKind = "run"
+	KindSnapshot DocumentKind = "snapshot"
+)
+
+// Document is a saved run.json or snapshot.json, decoded and validated.
+type Document struct {
+	Kind     DocumentKind
+	Run      *RunReport
+	Snapshot *Snapshot
+	// Warnings lists recoverable problems (e.g. fields filled in for
+	// reports written by older versions).
+	Warnings []string
+}
+
+// Load reads and validates a run or snapshot report.
+func Load(path string) (*Document, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	d, err := Decode(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return d, nil
+}
+
+// Decode detects the report kind from its fields, migrates older schema
+// versions to SchemaVersion and validates the result.
+func Decode(b []byte) (*Document, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var raw map[string]any
+	if err := dec.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("not a JSON object: %w", err)
+	}
+
+	// A monitor log line wraps its snapshot.
+	if inner, ok := raw["snapshot"].(map[string]any); ok && raw["type"] != nil {
+		raw = inner
+	}
+
+	d := &Document{}
+	switch {
+	case raw["verdict"] != nil || raw["baseline"] != nil:
+		d.Kind = KindRun
+	case raw["public_ips"] != nil || raw["timestamp_utc"] != nil:
+		d.Kind = KindSnapshot
+	default:
+		return nil, errors.New("neither a run report nor a snapshot")
+	}
+
+	warnings, err := migrate(d.Kind, raw)
+	if err != nil {
+		return nil, err
+	}
+	d.Warnings = warnings
+	migrated, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	if d.Kind == KindRun {
+		var r RunReport
+		if err := decodeReport(migrated, &r); err != nil {
+			return nil, err
+		}
+		d.Warnings = append(d.Warnings, upgradeRun(&r)...)
+		if err := ValidateRun(r); err != nil {
+			return nil, err
+		}
+		d.Run = &r
+		return d, nil
+	}
+
+	var s Snapshot
+	if err := decodeReport(migrated, &s); err != nil {
+		return nil, err
+	}
+	if err := ValidateSnapshot(s); err != nil {
+		return nil, err
+	}
+	d.Snapshot = &s
+	return d, nil
+}
+
+// decodeReport reports type mismatches; unknown fields from newer versions
+// are ignored.
+func decodeReport(b []byte, v any) error {
+	if err := json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("invalid report: %w", err)
+	}
+	return nil
+}
+
+// upgradeRun fills fields that older versions did not write.
+func upgradeRun(r *RunReport) []string {
+	var warnings []string
+	if r.Mode == "" {
+		r.Mode = RunModeVPNOnly
+		if r.Verdict.KillSwitch != "" {
+			r.Mode = RunModeKillSwitch
+		}
+		warnings = append(warnings, "report has no mode; assumed "+string(r.Mode))
+	}
+	if r.RunID == "" && !r.StartedUTC.IsZero() {
+		r.RunID = r.StartedUTC.UTC().Format("20060102_150405")
+	}
+	return warnings
+}
+
+var (
+	validOverall    = []string{"PASS", "FAIL", "INCONCLUSIVE", "OK", "NOT TESTED"}
+	validKillSwitch = []string{"", "PASS", "FAIL", "INCONCLUSIVE", "NOT TESTED"}
+	validDNS        = []string{"", DNSPass, DNSLeak, DNSUnknown}
+	validFamilies   = []string{"ipv4", "ipv6", "any"}
+)
+
+// ValidateRun checks that a run report is internally consistent.
+func ValidateRun(r RunReport) error {
+	var errs []string
+	if r.StartedUTC.IsZero() {
+		errs = append(errs, "started_utc is missing")
+	}
+	if r.Mode != RunModeKillSwitch && r.Mode != RunModeVPNOnly {
+		errs = append(errs, fmt.Sprintf("unknown mode %q", r.Mode))
+	}
+	if !oneOf(r.Verdict.Overall, validOverall) {
+		errs = append(errs, fmt.Sprintf("unknown verdict %q", r.Verdict.Overall))
+	}
+	if !oneOf(r.Verdict.KillSwitch, validKillSwitch) {
+		errs = append(errs, fmt.Sprintf("unknown kill-switch verdict %q", r.Verdict.KillSwitch))
+	}
+	if !oneOf(r.Verdict.DNS, validDNS) {
+		errs = append(errs, fmt.Sprintf("unknown DNS verdict %q", r.Verdict.DNS))
+	}
+	for i, p := range r.Probes {
+		if i > 0 && p.AtSec < r.Probes[i-1].AtSec {
+			errs = append(errs, fmt.Sprintf("probes out of order at index %d", i))
+			break
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New("invalid run report: " + strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// ValidateSnapshot checks that a snapshot is internally consistent.
+func ValidateSnapshot(s Snapshot) error {
+	var errs []string
+	if s.TimestampUTC.IsZero() {
+		errs = append(errs, "timestamp_utc is missing")
+	}
+	for i, r := range s.PublicIPs {
+		if !oneOf(r.Family, validFamilies) {
+			errs = append(errs, fmt.Sprintf("public_ips[%d]: unknown family %q", i, r.Family))
+		}
+		if r.IP == "" && r.Error == "" {
+			errs = append(errs, fmt.Sprintf("public_ips[%d]: neither ip nor error", i))
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New("invalid snapshot: " + strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func oneOf(s string, list []string) bool {
+	for _, x := range list {
+		if s == x {
+			return true
+		}
+	}
+	return false
+}
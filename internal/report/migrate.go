@@ -0,0 +1,109 @@
+// File: internal/report/migrate.go (complete file)
+
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// migration upgrades a decoded report of version to-1 to version to, in
+// place. Migrations work on the generic JSON so old layouts never need Go
+// types of their own.
+type migration struct {
+	to    int
+	apply func(kind DocumentKind, raw map[string]any) error
+}
+
+var migrations = []migration{
+	{to: 2, apply: migrateDurationsToSeconds},
+	{to: 3, apply: migrateSnapshotExits},
+}
+
+// migrate brings raw up to SchemaVersion. Reports without schema_version are
+// version 1. Reports from a newer version are read as-is with a warning.
+func migrate(kind DocumentKind, raw map[string]any) ([]string, error) {
+	version := 1
+	if v, ok := raw["schema_version"]; ok {
+		n, err := strconv.Atoi(fmt.Sprint(v))
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid schema_version %v", v)
+		}
+		version = n
+	}
+	if version > SchemaVersion {
+		return []string{fmt.Sprintf("report has schema_version %d, newer than %d; unknown fields are ignored", version, SchemaVersion)}, nil
+	}
+
+	var warnings []string
+	if version < SchemaVersion {
+		warnings = append(warnings, fmt.Sprintf("migrated report from schema_version %d to %d", version, SchemaVersion))
+	}
+	for _, m := range migrations {
+		if m.to <= version {
+			continue
+		}
+		if err := m.apply(kind, raw); err != nil {
+			return nil, fmt.Errorf("migrating to schema_version %d: %w", m.to, err)
+		}
+		version = m.to
+		raw["schema_version"] = version
+	}
+	return warnings, nil
+}
+
+// migrateDurationsToSeconds replaces the nanosecond durations of version 1
+// run reports ("duration": 30000000000) with seconds ("duration_seconds": 30).
+func migrateDurationsToSeconds(kind DocumentKind, raw map[string]any) error {
+	if kind != KindRun {
+		return nil
+	}
+	for _, key := range []string{"duration", "interval", "baseline_window"} {
+		v, ok := raw[key]
+		if !ok {
+			continue
+		}
+		num, ok := v.(json.Number)
+		if !ok {
+			return fmt.Errorf("%s: expected nanoseconds, got %v", key, v)
+		}
+		ns, err := num.Int64()
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		delete(raw, key)
+		raw[key+"_seconds"] = time.Duration(ns).Seconds()
+	}
+	return nil
+}
+
+// migrateSnapshotExits fills the exit_v4/exit_v6 of snapshots taken before
+// the shared observation model from their public_ips (no geo data).
+func migrateSnapshotExits(kind DocumentKind, raw map[string]any) error {
+	if kind != KindSnapshot {
+		return nil
+	}
+	list, _ := raw["public_ips"].([]any)
+	for _, family := range []string{"ipv4", "ipv6"} {
+		key := "exit_v4"
+		if family == "ipv6" {
+			key = "exit_v6"
+		}
+		if _, ok := raw[key]; ok {
+			continue
+		}
+		exit := map[string]any{"family": family, "error": "not probed"}
+		for _, item := range list {
+			r, ok := item.(map[string]any)
+			if !ok || r["family"] != family {
+				continue
+			}
+			exit = map[string]any{"family": family, "source": r["source"], "ip": r["ip"], "error": r["error"]}
+			break
+		}
+		raw[key] = exit
+	}
+	return nil
+}
@@ -19,11 +19,23 @@ type DnsLeakServer struct {
 	Country   string `json:"country"`
 }
 
+// ProbeTiming records how long one probe of a snapshot took.
+type ProbeTiming struct {
+	Prober     string `json:"prober"`   // ipify|ns.ident.me|dnsleaktest|stun
+	Endpoint   string `json:"endpoint"` // URL, hostname or host:port
+	DurationMS int64  `json:"duration_ms"`
+	OK         bool   `json:"ok"`
+}
+
 type Snapshot struct {
-	TimestampUTC time.Time        `json:"timestamp_utc"`
-	PublicIPs    []PublicIPResult `json:"public_ips"`
-	DnsRecursors []string         `json:"dns_recursors,omitempty"`
-	DnsLeak      []DnsLeakServer  `json:"dnsleaktest,omitempty"`
-	StunObserved []string         `json:"stun_observed,omitempty"`
-	Notes        []string         `json:"notes,omitempty"`
+	SchemaVersion int       `json:"schema_version"`
+	TimestampUTC  time.Time `json:"timestamp_utc"`
+	Observation
+	PublicIPs []PublicIPResult `json:"public_ips"`
+	DnsLeak   []DnsLeakServer  `json:"dnsleaktest,omitempty"`
+	DNS       *DNSAssessment   `json:"dns,omitempty"`
+	IPv6      *IPv6Analysis    `json:"ipv6,omitempty"`
+	Host      *HostConfig      `json:"host,omitempty"`
+	Notes     []string         `json:"notes,omitempty"`
+	Probes    []ProbeTiming    `json:"probes,omitempty"`
 }
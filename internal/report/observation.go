@@ -0,0 +1,222 @@
+// File: internal/report/observation.go (complete file)
+
+package report
+
+import "strings"
+
+// Observation is what one probe round saw. Snapshots, test probes and
+// monitor snapshots all embed it, so every command reports the same fields
+// under the same JSON names.
+type Observation struct {
+	ExitV4 ExitInfo `json:"exit_v4"`
+	ExitV6 ExitInfo `json:"exit_v6"`
+
+	// DNSRecursors is the union of Recursors, kept for consumers of the flat
+	// list.
+	DNSRecursors []string         `json:"dns_recursors,omitempty"`
+	Recursors    []RecursorResult `json:"recursors,omitempty"`
+	// DNSRoute is the link systemd-resolved sends the recursor lookups to,
+	// when resolved manages DNS.
+	DNSRoute *DNSRoute `json:"dns_route,omitempty"`
+
+	// StunObserved is the union of Stun.
+	StunObserved []string     `json:"stun_observed,omitempty"`
+	Stun         []StunResult `json:"stun,omitempty"`
+
+	Interfaces []InterfaceInfo `json:"interfaces,omitempty"`
+
+	// IPGeo holds offline geo/ASN data for every observed IP (exits,
+	// recursors, STUN-mapped addresses), keyed by IP.
+	IPGeo map[string]GeoInfo `json:"ip_geo,omitempty"`
+}
+
+// RecursorResult lists the recursors that answered one ns.ident.me query.
+// Family is the record type asked for (A: ipv4, AAAA: ipv6) and Transport
+// how the recursor reached ident.me's servers (ns4: ipv4, ns6: ipv6, ns:
+// any). Reports written before the split carry only Family and IPs.
+type RecursorResult struct {
+	Query     string   `json:"query,omitempty"`
+	Family    string   `json:"family"`              // ipv4|ipv6
+	Transport string   `json:"transport,omitempty"` // any|ipv4|ipv6
+	IPs       []string `json:"ips,omitempty"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// Label names the lookup, e.g. "ipv6 query, ipv4 transport".
+func (r RecursorResult) Label() string {
+	if r.Transport == "" {
+		return r.Family
+	}
+	return r.Family + " query, " + r.Transport + " transport"
+}
+
+// RecursorDelta is a change in the recursors answering one lookup.
+type RecursorDelta struct {
+	Query     string   `json:"query,omitempty"`
+	Family    string   `json:"family"`
+	Transport string   `json:"transport,omitempty"`
+	From      []string `json:"from"`
+	To        []string `json:"to"`
+}
+
+// Label names the lookup like RecursorResult.Label.
+func (d RecursorDelta) Label() string {
+	return RecursorResult{Family: d.Family, Transport: d.Transport}.Label()
+}
+
+// DNSRoute is systemd-resolved's predicted route for a name: the links it
+// sends the query to and why.
+type DNSRoute struct {
+	Name  string         `json:"name"`
+	Links []DNSRouteLink `json:"links,omitempty"`
+	// Domain is the domain that claimed the name; empty when it fell
+	// through to the default-route links.
+	Domain string `json:"domain,omitempty"`
+	Reason string `json:"reason"`
+	// Tunnels are the VPN interfaces that were up, whether or not the
+	// name is sent to them.
+	Tunnels []string `json:"tunnels,omitempty"`
+}
+
+// DNSRouteLink is one link a name is sent to.
+type DNSRouteLink struct {
+	Name    string   `json:"name"`
+	Servers []string `json:"servers,omitempty"`
+	Tunnel  bool     `json:"tunnel"`
+}
+
+// Outside returns the predicted links that are not VPN tunnels.
+func (r DNSRoute) Outside() []string {
+	var out []string
+	for _, l := range r.Links {
+		if !l.Tunnel {
+			out = append(out, l.Name)
+		}
+	}
+	return out
+}
+
+// Exposed returns the outside links while a VPN is up: the name goes to
+// them next to the tunnel or, when no tunnel link gets it, instead of it.
+// Reports without Tunnels only know a VPN was up when a link is a tunnel.
+func (r DNSRoute) Exposed() []string {
+	out := r.Outside()
+	if len(r.Tunnels) == 0 && len(out) == len(r.Links) {
+		return nil
+	}
+	return out
+}
+
+// Text renders e.g. "ns.ident.me -> wg0 (10.64.0.1); domain ~. matches".
+func (r DNSRoute) Text() string {
+	var links []string
+	for _, l := range r.Links {
+		s := l.Name
+		if len(l.Servers) > 0 {
+			s += " (" + strings.Join(l.Servers, ", ") + ")"
+		}
+		links = append(links, s)
+	}
+	if len(links) == 0 {
+		links = []string{"nowhere"}
+	}
+	s := r.Name + " -> " + strings.Join(links, ", ") + "; " + r.Reason
+	if out := r.Exposed(); len(out) > 0 {
+		s += "; " + strings.Join(out, ", ") + " is outside the tunnel"
+	}
+	return s
+}
+
+// StunResult is the answer of one STUN server.
+type StunResult struct {
+	Server   string   `json:"server"`
+	Observed []string `json:"observed,omitempty"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// InterfaceInfo is a local network interface at probe time. Egress lists the
+// families whose default route leaves through it.
+type InterfaceInfo struct {
+	Name   string   `json:"name"`
+	Index  int      `json:"index"`
+	MTU    int      `json:"mtu,omitempty"`
+	Up     bool     `json:"up"`
+	Addrs  []string `json:"addrs,omitempty"`
+	Egress []string `json:"egress,omitempty"`
+}
+
+// Exit returns the exit of a family (ipv4|ipv6).
+func (o Observation) Exit(family string) ExitInfo {
+	if family == "ipv6" {
+		return o.ExitV6
+	}
+	return o.ExitV4
+}
+
+// SetRecursors stores the per-lookup results and their union.
+func (o *Observation) SetRecursors(rs []RecursorResult) {
+	o.Recursors = rs
+	o.DNSRecursors = nil
+	for _, r := range rs {
+		for _, ip := range r.IPs {
+			if !containsFold(o.DNSRecursors, ip) {
+				o.DNSRecursors = append(o.DNSRecursors, ip)
+			}
+		}
+	}
+}
+
+// DiffRecursors returns the lookups whose recursors differ between a and b.
+// Lookups that failed or came back empty on either side are skipped, as
+// are reports without per-lookup results.
+func DiffRecursors(a, b Observation) []RecursorDelta {
+	var out []RecursorDelta
+	for _, ra := range a.Recursors {
+		for _, rb := range b.Recursors {
+			if ra.Query != rb.Query || ra.Family != rb.Family || ra.Transport != rb.Transport {
+				continue
+			}
+			if len(ra.IPs) > 0 && len(rb.IPs) > 0 && !equalStringSets(ra.IPs, rb.IPs) {
+				out = append(out, RecursorDelta{Query: rb.Query, Family: rb.Family, Transport: rb.Transport, From: ra.IPs, To: rb.IPs})
+			}
+		}
+	}
+	return out
+}
+
+// Annotate appends what IPGeo knows to each IP, e.g.
+// "192.0.2.53 (NL, Amsterdam; AS64500 Example ISP; 192.0.2.0/24)".
+func (o Observation) Annotate(ips []string) []string {
+	out := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		g, ok := o.IPGeo[ip]
+		if !ok {
+			out = append(out, ip)
+			continue
+		}
+		if info := strings.Join(nonEmpty(formatLocation(g), geoNetwork(g), g.Prefix), "; "); info != "" {
+			ip += " (" + info + ")"
+		}
+		out = append(out, ip)
+	}
+	return out
+}
+
+// EgressInterface names the interface carrying the default route of family.
+func (o Observation) EgressInterface(family string) string {
+	for _, i := range o.Interfaces {
+		for _, f := range i.Egress {
+			if f == family {
+				return i.Name
+			}
+		}
+	}
+	return ""
+}
+
+func ipFamily(ip string) string {
+	if strings.Contains(ip, ":") {
+		return "ipv6"
+	}
+	return "ipv4"
+}
@@ -0,0 +1,204 @@
+// File: internal/report/render.go (complete file)
+
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"strings"
+)
+
+// RenderRunMarkdown renders a run report for tickets and pull requests.
+func RenderRunMarkdown(r RunReport) string {
+	var b strings.Builder
+	b.WriteString("# vpnleakID run " + r.RunID + "\n\n")
+	b.WriteString(fmt.Sprintf("- **Started (UTC):** %s\n", r.StartedUTC.Format("2006-01-02T15:04:05Z")))
+	b.WriteString(fmt.Sprintf("- **Mode:** %s\n", r.Mode))
+	b.WriteString(fmt.Sprintf("- **Duration:** %s\n", durShort(r.Duration.Duration())))
+	b.WriteString(fmt.Sprintf("- **Verdict:** %s\n", r.Verdict.Overall))
+	if r.Mode == RunModeKillSwitch {
+		b.WriteString(fmt.Sprintf("- **Kill-switch:** %s\n", r.Verdict.KillSwitch))
+	}
+	if reason := strings.TrimSpace(r.Verdict.Reason); reason != "" {
+		b.WriteString("- **Reason:** " + reason + "\n")
+	}
+
+	b.WriteString("\n## Exits\n\n| Family | Baseline | Change |\n|---|---|---|\n")
+	for _, e := range []ExitInfo{r.Baseline.ExitV4, r.Baseline.ExitV6} {
+		change := "-"
+		if d := findExitDelta(r, e.Family); d != nil {
+			change = fmt.Sprintf("%s%s at T+%ds", d.To.IP, formatGeoSuffix(d.To.Geo), d.AtSec)
+		}
+		b.WriteString(fmt.Sprintf("| %s | %s | %s |\n", e.Family, mdCell(exitText(e)), mdCell(change)))
+	}
+
+	if len(r.Baseline.DNSRecursors) > 0 {
+		b.WriteString("\n## DNS\n\n- Baseline recursors: " + strings.Join(r.Baseline.Annotate(r.Baseline.DNSRecursors), ", ") + "\n")
+		if d := r.DNSDelta; d != nil {
+			b.WriteString(fmt.Sprintf("- Changed at T+%ds to: %s\n", d.AtSec, strings.Join(d.To, ", ")))
+			for _, l := range d.Lookups {
+				b.WriteString(fmt.Sprintf("  - %s: %s -> %s\n", l.Label(), strings.Join(l.From, ", "), strings.Join(l.To, ", ")))
+			}
+		}
+		if rt := r.Baseline.DNSRoute; rt != nil {
+			b.WriteString("- Route (systemd-resolved): " + rt.Text() + "\n")
+		}
+	}
+
+	writeHostConfigMarkdown(&b, r.Host)
+	writeChecksMarkdown(&b, RunChecks(r))
+
+	if notes := runNotes(r); len(notes) > 0 {
+		b.WriteString("\n## Notes\n\n")
+		for _, n := range notes {
+			b.WriteString("- " + n + "\n")
+		}
+	}
+	return b.String()
+}
+
+// RenderSnapshotMarkdown renders a single snapshot.
+func RenderSnapshotMarkdown(s Snapshot) string {
+	var b strings.Builder
+	b.WriteString("# vpnleakID snapshot\n\n")
+	b.WriteString("- **Timestamp (UTC):** " + s.TimestampUTC.Format("2006-01-02T15:04:05Z") + "\n")
+	if s.ExitV4.Family != "" || s.ExitV6.Family != "" {
+		b.WriteString("\n## Exits\n\n| Family | Exit | Network |\n|---|---|---|\n")
+		for _, e := range []ExitInfo{s.ExitV4, s.ExitV6} {
+			b.WriteString(fmt.Sprintf("| %s | %s | %s |\n", e.Family, mdCell(exitText(e)), mdCell(geoNetwork(e.Geo))))
+		}
+	}
+	b.WriteString("\n## Public IPs\n\n| Source | Family | Result |\n|---|---|---|\n")
+	for _, r := range s.PublicIPs {
+		res := r.IP
+		if r.Error != "" {
+			res = "error: " + r.Error
+		}
+		b.WriteString(fmt.Sprintf("| %s | %s | %s |\n", mdCell(r.Source), r.Family, mdCell(res)))
+	}
+	if len(s.Recursors) > 0 {
+		b.WriteString("\n## DNS\n\n")
+		for _, r := range s.Recursors {
+			if len(r.IPs) > 0 {
+				b.WriteString(fmt.Sprintf("- Recursors (%s): %s\n", r.Label(), strings.Join(s.Annotate(r.IPs), ", ")))
+			}
+		}
+	} else if len(s.DNSRecursors) > 0 {
+		b.WriteString("\n## DNS\n\n- Recursors: " + strings.Join(s.Annotate(s.DNSRecursors), ", ") + "\n")
+	}
+	if s.DNSRoute != nil && len(s.DNSRecursors) > 0 {
+		b.WriteString("- Route (systemd-resolved): " + s.DNSRoute.Text() + "\n")
+	}
+	for _, d := range s.DnsLeak {
+		b.WriteString(fmt.Sprintf("- %s (%s) - %s - %s, %s\n", d.IPAddress, d.Hostname, d.ISP, d.City, d.Country))
+	}
+	if len(s.Stun) > 0 {
+		b.WriteString("\n## STUN\n\n")
+		for _, r := range s.Stun {
+			res := strings.Join(s.Annotate(r.Observed), ", ")
+			if r.Error != "" {
+				res = "error: " + r.Error
+			}
+			b.WriteString(fmt.Sprintf("- %s: %s\n", r.Server, res))
+		}
+	} else if len(s.StunObserved) > 0 {
+		b.WriteString("\n## STUN\n\n- Observed: " + strings.Join(s.Annotate(s.StunObserved), ", ") + "\n")
+	}
+	if len(s.Interfaces) > 0 {
+		b.WriteString("\n## Interfaces\n\n| Name | Up | Addresses | Egress |\n|---|---|---|---|\n")
+		for _, i := range s.Interfaces {
+			b.WriteString(fmt.Sprintf("| %s | %t | %s | %s |\n", mdCell(i.Name), i.Up, strings.Join(i.Addrs, ", "), strings.Join(i.Egress, ", ")))
+		}
+	}
+	writeHostConfigMarkdown(&b, s.Host)
+	writeChecksMarkdown(&b, SnapshotChecks(s))
+	if len(s.Notes) > 0 {
+		b.WriteString("\n## Notes\n\n")
+		for _, n := range uniqStrings(s.Notes) {
+			b.WriteString("- " + n + "\n")
+		}
+	}
+	return b.String()
+}
+
+func writeChecksMarkdown(b *strings.Builder, checks []Check) {
+	b.WriteString("\n## Checks\n\n| Check | Status | Detail |\n|---|---|---|\n")
+	for _, c := range checks {
+		b.WriteString(fmt.Sprintf("| %s | %s | %s |\n", c.Name, strings.ToUpper(string(c.Status)), mdCell(c.Message)))
+	}
+}
+
+func mdCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.ReplaceAll(s, "\n", " ")
+}
+
+func exitText(e ExitInfo) string {
+	switch {
+	case strings.EqualFold(strings.TrimSpace(e.Error), "disabled"):
+		return "disabled"
+	case e.Error != "" || strings.TrimSpace(e.IP) == "":
+		return "unavailable"
+	}
+	return e.IP + formatGeoSuffix(e.Geo) + disputeSuffix(e.Geo)
+}
+
+func runNotes(r RunReport) []string {
+	notes := []string{}
+	notes = append(notes, r.Notes...)
+	notes = append(notes, r.Baseline.Notes...)
+	notes = append(notes, r.End.Notes...)
+	return uniqStrings(notes)
+}
+
+var snapshotHTML = template.Must(template.New("snapshot").Parse(`<!DOCTYPE html>
+<html lang="en"><head><meta charset="utf-8"><title>vpnleakID snapshot</title>
+<style>` + reportCSS + `</style></head><body>
+<h1>vpnleakID snapshot</h1>
+<p class="meta">{{.S.TimestampUTC.Format "2006-01-02T15:04:05Z"}}</p>
+{{if or .S.ExitV4.Family .S.ExitV6.Family}}<h2>Exits</h2>
+<table><tr><th>Family</th><th>Exit</th><th>Network</th></tr>
+{{range .Exits}}<tr><td>{{.Family}}</td><td>{{.Text}}</td><td>{{.Network}}</td></tr>
+{{end}}</table>{{end}}
+<h2>Public IPs</h2>
+<table><tr><th>Source</th><th>Family</th><th>Result</th></tr>
+{{range .S.PublicIPs}}<tr><td>{{.Source}}</td><td>{{.Family}}</td><td>{{if .Error}}error: {{.Error}}{{else}}{{.IP}}{{end}}</td></tr>
+{{end}}</table>
+{{if .S.DNSRecursors}}<h2>DNS recursors</h2><ul>{{range .S.Annotate .S.DNSRecursors}}<li>{{.}}</li>{{end}}</ul>{{with .S.DNSRoute}}<p>Route (systemd-resolved): {{.Text}}</p>{{end}}{{end}}
+{{if .S.Stun}}<h2>STUN</h2><table><tr><th>Server</th><th>Observed</th></tr>
+{{range .S.Stun}}<tr><td>{{.Server}}</td><td>{{if .Error}}error: {{.Error}}{{else}}{{range $i, $ip := $.S.Annotate .Observed}}{{if $i}}, {{end}}{{$ip}}{{end}}{{end}}</td></tr>
+{{end}}</table>{{else if .S.StunObserved}}<h2>STUN</h2><ul>{{range .S.StunObserved}}<li>{{.}}</li>{{end}}</ul>{{end}}
+{{if .S.Interfaces}}<h2>Interfaces</h2><table><tr><th>Name</th><th>Up</th><th>Addresses</th><th>Egress</th></tr>
+{{range .S.Interfaces}}<tr><td>{{.Name}}</td><td>{{.Up}}</td><td>{{range $i, $a := .Addrs}}{{if $i}}, {{end}}{{$a}}{{end}}</td><td>{{range .Egress}}{{.}} {{end}}</td></tr>
+{{end}}</table>{{end}}
+<h2>Checks</h2>
+<table><tr><th>Check</th><th>Status</th><th>Detail</th></tr>
+{{range .Checks}}<tr class="{{.Status}}"><td>{{.Name}}</td><td>{{.Status}}</td><td>{{.Message}}</td></tr>
+{{end}}</table>
+{{if .S.Notes}}<h2>Notes</h2><ul>{{range .S.Notes}}<li>{{.}}</li>{{end}}</ul>{{end}}
+</body></html>
+`))
+
+const reportCSS = `body{font-family:system-ui,sans-serif;margin:2em;color:#222}
+table{border-collapse:collapse;margin:.5em 0}
+td,th{border:1px solid #ccc;padding:.3em .6em;text-align:left}
+.meta{color:#666}
+tr.pass td:nth-child(2){color:#1a7f37}
+tr.fail td:nth-child(2){color:#cf222e;font-weight:bold}
+tr.skip td:nth-child(2){color:#888}`
+
+// RenderSnapshotHTML renders a self-contained HTML page for a snapshot.
+func RenderSnapshotHTML(s Snapshot) (string, error) {
+	var buf bytes.Buffer
+	var exits []exitRow
+	for _, e := range []ExitInfo{s.ExitV4, s.ExitV6} {
+		exits = append(exits, exitRow{Family: e.Family, Text: exitText(e), Network: geoNetwork(e.Geo)})
+	}
+	err := snapshotHTML.Execute(&buf, struct {
+		S      Snapshot
+		Exits  []exitRow
+		Checks []Check
+	}{s, exits, SnapshotChecks(s)})
+	return buf.String(), err
+}
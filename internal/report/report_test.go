@@ -0,0 +1,360 @@
+package report
+
+import (
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDecode_LegacyRunAndChecks(t *testing.T) {
+	legacy := `{"started_utc":"2025-01-01T00:00:00Z","duration":30000000000,
+		"baseline":{"at_sec":0,"exit_v4":{"family":"ipv4","ip":"198.51.100.1"},"exit_v6":{"family":"ipv6","error":"unavailable"},"online":true},
+		"exit_deltas":[{"family":"ipv4","from":{"family":"ipv4","ip":"198.51.100.1"},"to":{"family":"ipv4","ip":"203.0.113.7"},"at_sec":12}],
+		"verdict":{"overall":"FAIL","kill_switch":"FAIL","reason":"exit changed"}}`
+
+	d, err := Decode([]byte(legacy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Kind != KindRun || d.Run.Mode != RunModeKillSwitch || len(d.Warnings) != 2 {
+		t.Fatalf("legacy run not upgraded: %+v", d)
+	}
+	if d.Run.SchemaVersion != SchemaVersion || d.Run.Duration.Duration() != 30*time.Second {
+		t.Fatalf("legacy durations not migrated: version %d, duration %s", d.Run.SchemaVersion, d.Run.Duration)
+	}
+	if d.Run.RunID != "20250101_000000" {
+		t.Fatalf("run id not derived: %q", d.Run.RunID)
+	}
+
+	status := map[string]CheckStatus{}
+	for _, c := range RunChecks(*d.Run) {
+		status[c.Name] = c.Status
+	}
+	if status["ipv4_leak"] != CheckFail || status["ipv6_leak"] != CheckPass || status["kill_switch"] != CheckFail || status["stun_mismatch"] != CheckSkip {
+		t.Fatalf("unexpected checks: %v", status)
+	}
+
+	out, err := RenderJUnit("vli", d.Run.StartedUTC, d.Run.Duration.Duration(), RunChecks(*d.Run))
+	if err != nil || !strings.Contains(out, `failures="2"`) || !strings.Contains(out, "verdict: FAIL - exit changed") {
+		t.Fatalf("junit: %v\n%s", err, out)
+	}
+
+	tap := RenderTAP(RunChecks(*d.Run))
+	for _, want := range []string{"1..6\n", "not ok 2 - ipv4_leak\n", `    - "T+12s: 203.0.113.7"`, "ok 5 - stun_mismatch # SKIP"} {
+		if !strings.Contains(tap, want) {
+			t.Fatalf("tap output missing %q:\n%s", want, tap)
+		}
+	}
+}
+
+func TestDecode_SnapshotExitsFromPublicIPs(t *testing.T) {
+	v2 := `{"schema_version":2,"timestamp_utc":"2025-01-01T00:00:00Z","public_ips":[
+		{"source":"api.ipify.org","family":"ipv4","ip":"198.51.100.1"},
+		{"source":"api6.ipify.org","family":"ipv6","error":"timeout"}]}`
+
+	d, err := Decode([]byte(v2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := d.Snapshot
+	if s.SchemaVersion != SchemaVersion || s.ExitV4.IP != "198.51.100.1" || s.ExitV4.Source != "api.ipify.org" || s.ExitV6.Error != "timeout" {
+		t.Fatalf("exits not migrated: %+v", s.Observation)
+	}
+}
+
+func TestFinish_UntrustedExitIsInconclusive(t *testing.T) {
+	vpn := ExitInfo{Family: "ipv4", IP: "198.51.100.1", Geo: GeoInfo{Consensus: &GeoConsensus{Status: "agreed", Quorum: 2, Agreeing: []string{"a", "b"}}}}
+	stale := vpn
+	stale.Geo.Consensus = &GeoConsensus{Status: "insufficient", Quorum: 2, Agreeing: []string{"a"},
+		Providers: []GeoAnswer{{Provider: "a", IP: vpn.IP}, {Provider: "b", Error: "timeout"}}}
+
+	offline := 3
+	r := RunReport{Mode: RunModeKillSwitch, OfflineAtSec: &offline}
+	r.Baseline = ProbeSet{Online: true, Observation: Observation{ExitV4: vpn}}
+	r.Probes = []ProbeSet{r.Baseline, {AtSec: 3}, {AtSec: 5, Online: true, Observation: Observation{ExitV4: stale}}}
+	r.Finish()
+	if r.Verdict.Overall != "INCONCLUSIVE" || r.Verdict.KillSwitch != "INCONCLUSIVE" {
+		t.Fatalf("a single-provider answer must not pass: %+v", r.Verdict)
+	}
+	for _, c := range RunChecks(r) {
+		if c.Name == "ipv4_leak" && (c.Status != CheckSkip || !strings.Contains(c.Evidence[0], "[insufficient 1/2]")) {
+			t.Fatalf("unexpected ipv4 check: %+v", c)
+		}
+	}
+}
+
+func TestFinish_DNSLeakFails(t *testing.T) {
+	vpn := ExitInfo{Family: "ipv4", IP: "198.51.100.1"}
+	r := RunReport{Mode: RunModeKillSwitch}
+	r.Baseline = ProbeSet{Online: true, Observation: Observation{ExitV4: vpn}}
+	r.Probes = []ProbeSet{r.Baseline}
+	r.DNS = &DNSAssessment{Verdict: DNSLeak, Reason: "recursors in your ISP network: 203.0.113.53",
+		Recursors: []RecursorAssessment{{IP: "203.0.113.53", Class: RecursorISP}}}
+	r.Finish()
+	if r.Verdict.Overall != "FAIL" || r.Verdict.DNS != DNSLeak || r.Verdict.KillSwitch != "NOT TESTED" {
+		t.Fatalf("a DNS leak must fail the run: %+v", r.Verdict)
+	}
+	if !strings.Contains(r.Verdict.Reason, "203.0.113.53") {
+		t.Fatalf("reason = %q", r.Verdict.Reason)
+	}
+
+	// An exit change keeps its reason and gains the DNS one.
+	r.ExitDeltas = []ExitDelta{{Family: "ipv4", From: vpn, To: ExitInfo{Family: "ipv4", IP: "203.0.113.9"}}}
+	r.Finish()
+	if r.Verdict.Overall != "FAIL" || !strings.HasPrefix(r.Verdict.Reason, "Exit IP changed") || !strings.Contains(r.Verdict.Reason, "DNS leak") {
+		t.Fatalf("combined verdict = %+v", r.Verdict)
+	}
+
+	r.ExitDeltas = nil
+	r.DNS.Verdict = DNSUnknown
+	r.Finish()
+	if r.Verdict.Overall != "NOT TESTED" {
+		t.Fatalf("an unknown DNS verdict must not fail the run: %+v", r.Verdict)
+	}
+}
+
+func TestFinish_IPv6AppearingMidRunFails(t *testing.T) {
+	vpn := ExitInfo{Family: "ipv4", IP: "198.51.100.1"}
+	r := RunReport{Mode: RunModeVPNOnly}
+	r.Baseline = ProbeSet{Online: true, Observation: Observation{ExitV4: vpn, ExitV6: ExitInfo{Family: "ipv6", Error: "unavailable"}}}
+	r.Probes = []ProbeSet{r.Baseline, {AtSec: 4, Online: true, Observation: Observation{ExitV4: vpn, ExitV6: ExitInfo{Family: "ipv6", IP: "2001:db8::9"}}}}
+	r.Finish()
+	if r.Verdict.Overall != "FAIL" || !strings.Contains(r.Verdict.Reason, "2001:db8::9 appeared at T+4s") {
+		t.Fatalf("a mid-run IPv6 exit must fail the run: %+v", r.Verdict)
+	}
+	for _, c := range RunChecks(r) {
+		if c.Name == "ipv6_leak" && c.Status != CheckFail {
+			t.Fatalf("unexpected ipv6 check: %+v", c)
+		}
+	}
+
+	r.Probes = r.Probes[:1]
+	r.Finish()
+	if r.Verdict.Overall != "OK" {
+		t.Fatalf("an IPv6 exit unavailable for the whole run must not fail: %+v", r.Verdict)
+	}
+}
+
+func TestMaybeRecordExitDelta_DisputedExit(t *testing.T) {
+	agreed := &GeoConsensus{Status: "agreed", Quorum: 2, Agreeing: []string{"a", "b"}}
+	vpn := ExitInfo{Family: "ipv4", IP: "198.51.100.1", Geo: GeoInfo{Consensus: agreed}}
+	// A 1:1 tie won by a stale answer.
+	tie := ExitInfo{Family: "ipv4", IP: "203.0.113.9", Geo: GeoInfo{Consensus: &GeoConsensus{Status: "disputed", Quorum: 2, Agreeing: []string{"a"}}}}
+	moved := ExitInfo{Family: "ipv4", IP: "203.0.113.9", Geo: GeoInfo{Consensus: agreed}}
+
+	r := RunReport{Mode: RunModeVPNOnly}
+	r.Baseline = ProbeSet{Online: true, Observation: Observation{ExitV4: vpn}}
+	r.MaybeRecordExitDelta(r.Baseline, ProbeSet{AtSec: 5, Online: true, Observation: Observation{ExitV4: tie}})
+	if len(r.ExitDeltas) != 0 {
+		t.Fatalf("disputed exit recorded as a delta: %+v", r.ExitDeltas)
+	}
+	r.MaybeRecordExitDelta(r.Baseline, ProbeSet{AtSec: 10, Online: true, Observation: Observation{ExitV4: moved}})
+	if len(r.ExitDeltas) != 1 || r.ExitDeltas[0].AtSec != 10 {
+		t.Fatalf("agreed change not recorded: %+v", r.ExitDeltas)
+	}
+}
+
+func TestDNSDelta_PerLookupAndEveryAddress(t *testing.T) {
+	probe := func(at int, aaaa string) ProbeSet {
+		ps := ProbeSet{AtSec: at, Online: true}
+		ps.SetRecursors([]RecursorResult{
+			{Query: "ns4.ident.me", Family: "ipv4", Transport: "ipv4", IPs: []string{"198.51.100.53", "198.51.100.54"}},
+			{Query: "ns.ident.me", Family: "ipv6", Transport: "any", IPs: []string{aaaa}},
+		})
+		return ps
+	}
+	var r RunReport
+	r.Baseline = probe(0, "2001:db8::53")
+	r.MaybeRecordDNSDelta(r.Baseline, probe(4, "2001:db8::53"))
+	if r.DNSDelta != nil {
+		t.Fatalf("unexpected delta: %+v", r.DNSDelta)
+	}
+	r.MaybeRecordDNSDelta(r.Baseline, probe(6, "2001:db8:ffff::53"))
+	if r.DNSDelta == nil || len(r.DNSDelta.Lookups) != 1 || r.DNSDelta.Lookups[0].Label() != "ipv6 query, any transport" {
+		t.Fatalf("expected an ipv6 lookup delta, got %+v", r.DNSDelta)
+	}
+
+	r.Baseline.DNSRoute = &DNSRoute{
+		Name:   "ns.ident.me",
+		Reason: "no domain matches; sent to every default-route link",
+		Links: []DNSRouteLink{
+			{Name: "eth0", Servers: []string{"192.168.1.1"}},
+			{Name: "wg0", Servers: []string{"10.64.0.1"}, Tunnel: true},
+		},
+	}
+
+	text := RenderRunText(r)
+	for _, want := range []string{
+		"DNS: 198.51.100.53, 198.51.100.54, 2001:db8::53  ->  198.51.100.53, 198.51.100.54, 2001:db8:ffff::53  [T+6s]",
+		"ipv6 query, any transport: 2001:db8::53  ->  2001:db8:ffff::53",
+		"DNS route (systemd-resolved): ns.ident.me -> eth0 (192.168.1.1), wg0 (10.64.0.1); no domain matches; sent to every default-route link; eth0 is outside the tunnel",
+	} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("missing %q in:\n%s", want, text)
+		}
+	}
+}
+
+func TestDNSRoute_AllLinksOutside(t *testing.T) {
+	rt := DNSRoute{
+		Name:   "ns.ident.me",
+		Reason: "no domain matches; sent to every default-route link",
+		Links:  []DNSRouteLink{{Name: "eth0", Servers: []string{"192.168.1.1"}}},
+	}
+	// No VPN up: the physical link is simply the resolver.
+	if out := rt.Exposed(); len(out) != 0 || strings.Contains(rt.Text(), "outside the tunnel") {
+		t.Fatalf("flagged without a tunnel: %v %q", out, rt.Text())
+	}
+	// wg0 is up but has no DNS in systemd-resolved: every query skips it.
+	rt.Tunnels = []string{"wg0"}
+	if out := rt.Exposed(); len(out) != 1 || out[0] != "eth0" {
+		t.Fatalf("exposed = %v", out)
+	}
+	if !strings.HasSuffix(rt.Text(), "; eth0 is outside the tunnel") {
+		t.Fatalf("text = %q", rt.Text())
+	}
+}
+
+func TestClassifyDNS(t *testing.T) {
+	exit := Observation{
+		ExitV4: ExitInfo{Family: "ipv4", IP: "198.51.100.7", Geo: GeoInfo{ASN: "AS64500"}},
+		IPGeo:  map[string]GeoInfo{"198.51.100.7": {ASN: "AS64500", Prefix: "198.51.100.0/24"}},
+	}
+	_, profileNet, _ := net.ParseCIDR("192.0.2.0/24")
+	p := DNSProfile{VPNNetworks: []*net.IPNet{profileNet}}
+
+	o := exit
+	o.DNSRecursors = []string{"198.51.100.53", "203.0.113.9", "192.0.2.1", "74.125.0.1", "2001:db8::53"}
+	o.IPGeo = map[string]GeoInfo{
+		"198.51.100.7": exit.IPGeo["198.51.100.7"],
+		"203.0.113.9":  {ASN: "AS64500"},
+		"74.125.0.1":   {ASN: "AS15169", ISP: "Google LLC"},
+		"2001:db8::53": {ASN: "AS64501", ISP: "Example ISP"},
+	}
+	a := ClassifyDNS(o, exit, p)
+	want := []string{RecursorVPN, RecursorVPN, RecursorVPN, RecursorPublic, RecursorThirdParty}
+	for i, r := range a.Recursors {
+		if r.Class != want[i] {
+			t.Errorf("%s: got %s (%s), want %s", r.IP, r.Class, r.Reason, want[i])
+		}
+	}
+	// Another ASN alone does not make it the user's ISP.
+	if a.Verdict != DNSUnknown || !strings.Contains(a.Reason, "2001:db8::53") {
+		t.Fatalf("expected an unknown verdict for a third-party recursor: %+v", a)
+	}
+
+	_, ispNet, _ := net.ParseCIDR("2001:db8::/48")
+	withISP := p
+	withISP.ISPNetworks = []*net.IPNet{ispNet}
+	a = ClassifyDNS(o, exit, withISP)
+	if a.Recursors[4].Class != RecursorISP || a.Verdict != DNSLeak || !strings.Contains(a.Reason, "2001:db8::53") {
+		t.Fatalf("expected a leak to the known ISP: %+v", a)
+	}
+
+	o.DNSRecursors = o.DNSRecursors[:4]
+	if a := ClassifyDNS(o, exit, p); a.Verdict != DNSPass {
+		t.Fatalf("expected pass: %+v", a)
+	}
+	o.DNSRecursors = []string{"203.0.113.200"}
+	delete(o.IPGeo, "203.0.113.200")
+	if a := ClassifyDNS(o, exit, p); a.Verdict != DNSUnknown {
+		t.Fatalf("a recursor without ASN data should be unknown: %+v", a)
+	}
+}
+
+func TestDecode_Invalid(t *testing.T) {
+	for _, in := range []string{
+		`[]`,
+		`{"foo":1}`,
+		`{"started_utc":"2025-01-01T00:00:00Z","mode":"kill-switch","verdict":{"overall":"MAYBE"}}`,
+		`{"timestamp_utc":"2025-01-01T00:00:00Z","public_ips":[{"source":"ipify","family":"ipv5","ip":"1.2.3.4"}]}`,
+	} {
+		if _, err := Decode([]byte(in)); err == nil {
+			t.Errorf("expected an error for %s", in)
+		}
+	}
+}
+
+func TestSchema_MatchesWrittenReport(t *testing.T) {
+	r := NewRunReport(RunModeKillSwitch, 30*time.Second, time.Second, 5*time.Second)
+	r.Verdict = Verdict{Overall: "PASS", KillSwitch: "PASS"}
+	b, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var written map[string]any
+	_ = json.Unmarshal(b, &written)
+	if written["duration_seconds"] != 30.0 || written["schema_version"] != float64(SchemaVersion) {
+		t.Fatalf("unexpected layout: %s", b)
+	}
+
+	sb, err := JSONSchema(KindRun)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema struct {
+		Properties map[string]any `json:"properties"`
+		Required   []string       `json:"required"`
+		Defs       map[string]any `json:"$defs"`
+	}
+	if err := json.Unmarshal(sb, &schema); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range schema.Required {
+		if _, ok := written[key]; !ok {
+			t.Errorf("schema requires %q but the report does not write it", key)
+		}
+	}
+	for key := range written {
+		if _, ok := schema.Properties[key]; !ok {
+			t.Errorf("report writes %q but the schema does not describe it", key)
+		}
+	}
+	if schema.Defs["ProbeSet"] == nil || schema.Defs["Verdict"] == nil {
+		t.Fatalf("missing $defs: %v", schema.Defs)
+	}
+}
+
+func TestMaybeRecordTunnelBypass(t *testing.T) {
+	t0 := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	probe := func(at int, online bool, handshake time.Time, rx, tx uint64, allowed ...string) ProbeSet {
+		hs := handshake
+		return ProbeSet{
+			AtUTC:  t0.Add(time.Duration(at) * time.Second),
+			AtSec:  at,
+			Online: online,
+			WireGuard: []WireGuardDevice{{Name: "wg0", Peers: []WireGuardPeer{{
+				PublicKey: "peer", LastHandshakeUTC: &hs, RxBytes: rx, TxBytes: tx, AllowedIPs: allowed,
+			}}}},
+		}
+	}
+	fresh := t0.Add(-10 * time.Second)
+	stale := t0.Add(-200 * time.Second)
+	def := []string{"0.0.0.0/0", "::/0"}
+
+	cases := []struct {
+		name                    string
+		baseline, prev, current ProbeSet
+		want                    string // substring of the reason; empty for no bypass
+	}{
+		{"counters moving", probe(0, true, fresh, 1, 1, def...), probe(4, true, fresh, 10, 10, def...), probe(5, true, fresh, 20, 20, def...), ""},
+		{"counters frozen", probe(0, true, fresh, 1, 1, def...), probe(4, true, fresh, 10, 10, def...), probe(5, true, fresh, 10, 10, def...), "counters did not move"},
+		{"frozen after offline probe", probe(0, true, fresh, 1, 1, def...), probe(4, false, fresh, 10, 10, def...), probe(5, true, fresh, 10, 10, def...), ""},
+		{"stale handshake", probe(0, true, fresh, 1, 1, def...), probe(4, true, fresh, 10, 10, def...), probe(200, true, fresh, 20, 20, def...), "handshake is stale"},
+		{"offline current", probe(0, true, fresh, 1, 1, def...), probe(4, true, fresh, 10, 10, def...), probe(200, false, fresh, 10, 10, def...), ""},
+		{"split tunnel peer", probe(0, true, fresh, 1, 1, "10.0.0.0/8"), probe(4, true, fresh, 10, 10, "10.0.0.0/8"), probe(5, true, fresh, 10, 10, "10.0.0.0/8"), ""},
+		{"stale at baseline", probe(0, true, stale, 1, 1, def...), probe(4, true, stale, 10, 10, def...), probe(5, true, stale, 10, 10, def...), ""},
+	}
+	for _, c := range cases {
+		var r RunReport
+		r.MaybeRecordTunnelBypass(c.baseline, c.prev, c.current)
+		switch {
+		case c.want == "" && r.TunnelBypass != nil:
+			t.Errorf("%s: unexpected bypass %+v", c.name, r.TunnelBypass)
+		case c.want != "" && (r.TunnelBypass == nil || !strings.Contains(r.TunnelBypass.Reason, c.want)):
+			t.Errorf("%s: bypass = %+v, want %q", c.name, r.TunnelBypass, c.want)
+		}
+	}
+}
@@ -2,7 +2,10 @@
 
 package report
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 type RunMode string
 
@@ -19,6 +22,52 @@ type GeoInfo struct {
 	ISP         string `json:"isp,omitempty"`
 	ASN         string `json:"asn,omitempty"`
 	Timezone    string `json:"timezone,omitempty"`
+
+	// Prefix and Registry are the announced route and the allocating RIR
+	// from an origin lookup.
+	Prefix   string `json:"prefix,omitempty"`
+	Registry string `json:"registry,omitempty"`
+
+	// Source names where the fields came from when it is not the exit's
+	// own provider, e.g. "mmdb:GeoLite2-City.mmdb,cymru".
+	Source string `json:"source,omitempty"`
+
+	// Consensus is how the geo providers agreed on this exit (nil for
+	// reports from a single provider).
+	Consensus *GeoConsensus `json:"consensus,omitempty"`
+}
+
+// GeoConsensus records a quorum lookup over several geo providers.
+type GeoConsensus struct {
+	Status        string            `json:"status"` // agreed, disputed, insufficient, failed
+	Quorum        int               `json:"quorum"`
+	Agreeing      []string          `json:"agreeing,omitempty"`
+	Disagreements []GeoDisagreement `json:"disagreements,omitempty"`
+	Providers     []GeoAnswer       `json:"providers"`
+}
+
+// GeoDisagreement lists the values providers reported for a field they
+// did not agree on (ip, asn, country_code).
+type GeoDisagreement struct {
+	Field  string            `json:"field"`
+	Values map[string]string `json:"values"` // provider -> value
+}
+
+// GeoAnswer is one provider's answer.
+type GeoAnswer struct {
+	Provider    string `json:"provider"`
+	IP          string `json:"ip,omitempty"`
+	CountryCode string `json:"country_code,omitempty"`
+	City        string `json:"city,omitempty"`
+	ASN         string `json:"asn,omitempty"`
+	ISP         string `json:"isp,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// Trusted reports whether the exit IP can be relied on: it is from a single
+// provider (older reports) or at least a quorum of providers agreed on it.
+func (g GeoInfo) Trusted() bool {
+	return g.Consensus == nil || g.Consensus.Status == "agreed"
 }
 
 type ExitInfo struct {
@@ -29,15 +78,29 @@ type ExitInfo struct {
 	Error  string  `json:"error,omitempty"`
 }
 
+type WireGuardPeer struct {
+	PublicKey        string     `json:"public_key"`
+	Endpoint         string     `json:"endpoint,omitempty"`
+	LastHandshakeUTC *time.Time `json:"last_handshake_utc,omitempty"`
+	RxBytes          uint64     `json:"rx_bytes"`
+	TxBytes          uint64     `json:"tx_bytes"`
+	AllowedIPs       []string   `json:"allowed_ips,omitempty"`
+}
+
+type WireGuardDevice struct {
+	Name       string          `json:"name"`
+	PublicKey  string          `json:"public_key,omitempty"`
+	ListenPort int             `json:"listen_port,omitempty"`
+	Peers      []WireGuardPeer `json:"peers,omitempty"`
+}
+
 type ProbeSet struct {
-	AtUTC        time.Time `json:"at_utc"`
-	AtSec        int       `json:"at_sec"`
-	ExitV4       ExitInfo  `json:"exit_v4"`
-	ExitV6       ExitInfo  `json:"exit_v6"`
-	DNSRecursors []string  `json:"dns_recursors,omitempty"`
-	StunObserved []string  `json:"stun_observed,omitempty"`
-	Online       bool      `json:"online"`
-	Notes        []string  `json:"notes,omitempty"`
+	AtUTC time.Time `json:"at_utc"`
+	AtSec int       `json:"at_sec"`
+	Observation
+	WireGuard []WireGuardDevice `json:"wireguard,omitempty"`
+	Online    bool              `json:"online"`
+	Notes     []string          `json:"notes,omitempty"`
 }
 
 type ExitDelta struct {
@@ -51,29 +114,61 @@ type DNSDelta struct {
 	From  []string `json:"from"`
 	To    []string `json:"to"`
 	AtSec int      `json:"at_sec"`
+	// Lookups are the per-lookup changes; an IPv6-only change can leave
+	// the union above unchanged.
+	Lookups []RecursorDelta `json:"lookups,omitempty"`
+}
+
+// TunnelBypass records connectivity that continued while a WireGuard peer
+// carrying the default route stopped handshaking or moving traffic.
+type TunnelBypass struct {
+	Device string `json:"device"`
+	Peer   string `json:"peer"`
+	Reason string `json:"reason"`
+	AtSec  int    `json:"at_sec"`
+}
+
+// ConnectionEvent is a VPN/network state change observed outside the probes
+// (e.g. NetworkManager signals), placed on the same T+ timeline.
+type ConnectionEvent struct {
+	AtUTC      time.Time `json:"at_utc"`
+	AtSec      int       `json:"at_sec"`
+	Source     string    `json:"source"` // networkmanager
+	Kind       string    `json:"kind"`   // vpn|active|device|action
+	Connection string    `json:"connection,omitempty"`
+	State      string    `json:"state"`
+	Error      string    `json:"error,omitempty"`
 }
 
 type Verdict struct {
 	Overall    string `json:"overall"`               // PASS|FAIL|INCONCLUSIVE|OK
 	KillSwitch string `json:"kill_switch,omitempty"` // PASS|FAIL|NOT TESTED|INCONCLUSIVE
+	DNS        string `json:"dns,omitempty"`         // PASS|LEAK|UNKNOWN
 	Reason     string `json:"reason,omitempty"`
 }
 
 type RunReport struct {
-	RunID       string        `json:"run_id"`
-	StartedUTC  time.Time     `json:"started_utc"`
-	Mode        RunMode       `json:"mode"`
-	Duration    time.Duration `json:"duration"`
-	Interval    time.Duration `json:"interval"`
-	BaselineWin time.Duration `json:"baseline_window"`
+	SchemaVersion int       `json:"schema_version"`
+	RunID         string    `json:"run_id"`
+	StartedUTC    time.Time `json:"started_utc"`
+	Mode          RunMode   `json:"mode"`
+	Duration      Seconds   `json:"duration_seconds"`
+	Interval      Seconds   `json:"interval_seconds"`
+	BaselineWin   Seconds   `json:"baseline_window_seconds"`
 
 	Baseline ProbeSet `json:"baseline"`
 	End      ProbeSet `json:"end"`
 
-	ExitDeltas   []ExitDelta `json:"exit_deltas,omitempty"`
-	DNSDelta     *DNSDelta   `json:"dns_delta,omitempty"`
-	OfflineAtSec *int        `json:"offline_at_sec,omitempty"`
-	Notes        []string    `json:"notes,omitempty"`
+	ExitDeltas   []ExitDelta    `json:"exit_deltas,omitempty"`
+	DNSDelta     *DNSDelta      `json:"dns_delta,omitempty"`
+	DNS          *DNSAssessment `json:"dns,omitempty"`
+	IPv6         *IPv6Analysis  `json:"ipv6,omitempty"`
+	Host         *HostConfig    `json:"host,omitempty"`
+	TunnelBypass *TunnelBypass  `json:"tunnel_bypass,omitempty"`
+	OfflineAtSec *int           `json:"offline_at_sec,omitempty"`
+	Notes        []string       `json:"notes,omitempty"`
+
+	ConnectionEvents []ConnectionEvent `json:"connection_events,omitempty"`
 
 	Probes  []ProbeSet `json:"probes,omitempty"`
 	Verdict Verdict    `json:"verdict"`
@@ -81,12 +176,13 @@ type RunReport struct {
 
 func NewRunReport(mode RunMode, duration, interval, baselineWin time.Duration) RunReport {
 	return RunReport{
-		RunID:       time.Now().UTC().Format("20060102_150405"),
-		StartedUTC:  time.Now().UTC(),
-		Mode:        mode,
-		Duration:    duration,
-		Interval:    interval,
-		BaselineWin: baselineWin,
+		SchemaVersion: SchemaVersion,
+		RunID:         time.Now().UTC().Format("20060102_150405"),
+		StartedUTC:    time.Now().UTC(),
+		Mode:          mode,
+		Duration:      Seconds(duration),
+		Interval:      Seconds(interval),
+		BaselineWin:   Seconds(baselineWin),
 	}
 }
 
@@ -117,7 +213,7 @@ func (ps *ProbeSet) DeriveOnline() {
 func (r *RunReport) MaybeRecordExitDelta(baseline, current ProbeSet) {
 	// Record the first time the exit IP changes from the baseline.
 	// This is enough to "prove" a leak for the user, without spamming output.
-	if baseline.ExitV4.IP != "" && current.ExitV4.IP != "" && baseline.ExitV4.IP != current.ExitV4.IP {
+	if exitChanged(baseline.ExitV4, current.ExitV4) {
 		if !r.hasExitDelta("ipv4") {
 			r.ExitDeltas = append(r.ExitDeltas, ExitDelta{
 				Family: "ipv4",
@@ -127,7 +223,7 @@ func (r *RunReport) MaybeRecordExitDelta(baseline, current ProbeSet) {
 			})
 		}
 	}
-	if baseline.ExitV6.IP != "" && current.ExitV6.IP != "" && baseline.ExitV6.IP != current.ExitV6.IP {
+	if exitChanged(baseline.ExitV6, current.ExitV6) {
 		if !r.hasExitDelta("ipv6") {
 			r.ExitDeltas = append(r.ExitDeltas, ExitDelta{
 				Family: "ipv6",
@@ -139,17 +235,133 @@ func (r *RunReport) MaybeRecordExitDelta(baseline, current ProbeSet) {
 	}
 }
 
+// exitChanged reports a change between two exits the providers agreed on. A
+// disputed or insufficient exit may be a stale answer that won a tie, so it
+// is not taken as proof; Finish reports it as inconclusive instead.
+func exitChanged(from, to ExitInfo) bool {
+	if from.IP == "" || to.IP == "" || from.IP == to.IP {
+		return false
+	}
+	return from.Geo.Trusted() && to.Geo.Trusted()
+}
+
 func (r *RunReport) MaybeRecordDNSDelta(baseline, current ProbeSet) {
 	if len(baseline.DNSRecursors) == 0 || len(current.DNSRecursors) == 0 {
 		return
 	}
-	if !equalStringSets(baseline.DNSRecursors, current.DNSRecursors) && r.DNSDelta == nil {
+	if r.DNSDelta != nil {
+		return
+	}
+	lookups := DiffRecursors(baseline.Observation, current.Observation)
+	if !equalStringSets(baseline.DNSRecursors, current.DNSRecursors) || len(lookups) > 0 {
 		r.DNSDelta = &DNSDelta{
-			From:  baseline.DNSRecursors,
-			To:    current.DNSRecursors,
-			AtSec: current.AtSec,
+			From:    baseline.DNSRecursors,
+			To:      current.DNSRecursors,
+			AtSec:   current.AtSec,
+			Lookups: lookups,
+		}
+	}
+}
+
+// WireGuard rejects a session 180s after its last handshake (REJECT_AFTER_TIME);
+// a peer older than that is no longer able to carry traffic.
+const wireGuardRejectAfter = 180 * time.Second
+
+func (r *RunReport) MaybeRecordTunnelBypass(baseline, prev, current ProbeSet) {
+	// Only the first occurrence is recorded, like the exit/DNS deltas.
+	if r.TunnelBypass != nil || !current.Online {
+		return
+	}
+
+	for _, d := range current.WireGuard {
+		for _, p := range d.Peers {
+			// Only peers that carried the default route with a live session at baseline matter.
+			base, ok := findWireGuardPeer(baseline.WireGuard, d.Name, p.PublicKey)
+			if !ok || !carriesDefaultRoute(base) || handshakeAge(base, baseline.AtUTC) > wireGuardRejectAfter {
+				continue
+			}
+
+			reason := ""
+			if age := handshakeAge(p, current.AtUTC); age > wireGuardRejectAfter {
+				reason = "latest WireGuard handshake is stale (" + age.Round(time.Second).String() + " old) while connectivity continued"
+			} else if old, ok := findWireGuardPeer(prev.WireGuard, d.Name, p.PublicKey); ok && prev.Online && old.RxBytes == p.RxBytes && old.TxBytes == p.TxBytes {
+				reason = "WireGuard rx/tx counters did not move between online probes"
+			}
+			if reason == "" {
+				continue
+			}
+
+			r.TunnelBypass = &TunnelBypass{
+				Device: d.Name,
+				Peer:   p.PublicKey,
+				Reason: reason,
+				AtSec:  current.AtSec,
+			}
+			return
+		}
+	}
+}
+
+func findWireGuardPeer(devs []WireGuardDevice, device, key string) (WireGuardPeer, bool) {
+	for _, d := range devs {
+		if d.Name != device {
+			continue
+		}
+		for _, p := range d.Peers {
+			if p.PublicKey == key {
+				return p, true
+			}
 		}
 	}
+	return WireGuardPeer{}, false
+}
+
+func carriesDefaultRoute(p WireGuardPeer) bool {
+	for _, a := range p.AllowedIPs {
+		if a == "0.0.0.0/0" || a == "::/0" {
+			return true
+		}
+	}
+	return false
+}
+
+func handshakeAge(p WireGuardPeer, at time.Time) time.Duration {
+	if p.LastHandshakeUTC == nil {
+		// Never handshaken: treat as infinitely old.
+		return 1<<63 - 1
+	}
+	return at.Sub(*p.LastHandshakeUTC)
+}
+
+// untrustedExit returns the first exit whose providers did not reach a
+// quorum, with the probe time.
+func (r *RunReport) untrustedExit() (ExitInfo, int, bool) {
+	probes := r.Probes
+	if len(probes) == 0 {
+		probes = []ProbeSet{r.Baseline, r.End}
+	}
+	for _, p := range probes {
+		for _, e := range []ExitInfo{p.ExitV4, p.ExitV6} {
+			if e.IP != "" && !e.Geo.Trusted() {
+				return e, p.AtSec, true
+			}
+		}
+	}
+	return ExitInfo{}, 0, false
+}
+
+// appearedExit returns the first exit of a family that was unavailable at
+// baseline but showed up later, with the probe time.
+func (r *RunReport) appearedExit(family string) (ExitInfo, int, bool) {
+	if r.Baseline.Exit(family).IP != "" {
+		return ExitInfo{}, 0, false
+	}
+	for _, p := range r.Probes {
+		if e := p.Exit(family); e.IP != "" && e.Error == "" {
+			return e, p.AtSec, true
+		}
+	}
+	return ExitInfo{}, 0, false
 }
 
 func (r *RunReport) hasExitDelta(family string) bool {
@@ -183,17 +395,104 @@ func equalStringSets(a, b []string) bool {
 	return true
 }
 
+// ClassifyDNS classifies every recursor the run saw while online against
+// the baseline exits, which are the VPN's.
+func (r *RunReport) ClassifyDNS(p DNSProfile) {
+	probes := r.Probes
+	if len(probes) == 0 {
+		probes = []ProbeSet{r.Baseline, r.End}
+	}
+	seen := Observation{DNSRecursors: r.Baseline.DNSRecursors, IPGeo: map[string]GeoInfo{}}
+	for _, ps := range probes {
+		if !ps.Online {
+			continue
+		}
+		for _, ip := range ps.DNSRecursors {
+			if !containsFold(seen.DNSRecursors, ip) {
+				seen.DNSRecursors = append(seen.DNSRecursors, ip)
+			}
+		}
+		for ip, g := range ps.IPGeo {
+			seen.IPGeo[ip] = g
+		}
+	}
+	ref := r.Baseline.Observation
+	if len(ref.IPGeo) == 0 {
+		ref.IPGeo = seen.IPGeo
+	}
+	r.DNS = ClassifyDNS(seen, ref, p)
+}
+
 func (r *RunReport) Finish() {
+	r.finish()
+	// A family that was unavailable at baseline but shows up later bypasses
+	// a VPN that only covers the other family.
+	for _, family := range []string{"ipv4", "ipv6"} {
+		if e, at, ok := r.appearedExit(family); ok && r.Baseline.Online {
+			r.fail(fmt.Sprintf("%s exit %s appeared at T+%ds outside the VPN.", family, e.IP, at))
+		}
+	}
+	if r.DNS == nil {
+		return
+	}
+	r.Verdict.DNS = r.DNS.Verdict
+	// Queries answered outside the VPN are a leak whatever the exit did;
+	// the kill-switch verdict stays about connectivity.
+	if r.DNS.Verdict == DNSLeak {
+		r.fail("DNS leak: " + r.DNS.Reason + ".")
+	}
+}
+
+// fail marks the run FAIL, keeping the reasons of an earlier failure.
+func (r *RunReport) fail(reason string) {
+	if r.Verdict.Overall == "FAIL" {
+		r.Verdict.Reason += " " + reason
+	} else {
+		r.Verdict.Overall, r.Verdict.Reason = "FAIL", reason
+	}
+}
+
+func (r *RunReport) finish() {
 	// If the baseline never established connectivity, no reliable validation can be done.
-	if r.Mode == RunModeKillSwitch && !r.Baseline.Online {
+	if !r.Baseline.Online {
+		r.Verdict = Verdict{
+			Overall: "INCONCLUSIVE",
+			Reason:  "Baseline connectivity could not be established during the baseline window.",
+		}
+		if r.Mode == RunModeKillSwitch {
+			r.Verdict.KillSwitch = "INCONCLUSIVE"
+		}
+		return
+	}
+
+	// A bypassed tunnel is a leak regardless of mode.
+	if r.TunnelBypass != nil {
 		r.Verdict = Verdict{
-			Overall:    "INCONCLUSIVE",
-			KillSwitch: "INCONCLUSIVE",
-			Reason:     "Baseline connectivity could not be established during the baseline window.",
+			Overall: "FAIL",
+			Reason:  "Traffic continued while the WireGuard tunnel was not carrying it (" + r.TunnelBypass.Reason + ").",
+		}
+		if r.Mode == RunModeKillSwitch {
+			r.Verdict.KillSwitch = "FAIL"
 		}
 		return
 	}
 
+	// Without an agreed exit a stale or cached provider answer could hide a
+	// change, so a clean result cannot be trusted.
+	if len(r.ExitDeltas) == 0 {
+		if e, at, ok := r.untrustedExit(); ok {
+			r.Verdict = Verdict{
+				Overall: "INCONCLUSIVE",
+				Reason: fmt.Sprintf("Geo providers did not agree on the %s exit at T+%ds (%s); the exit IP cannot be verified.",
+					e.Family, at, e.Geo.Consensus.Status),
+			}
+			if r.Mode == RunModeKillSwitch {
+				r.Verdict.KillSwitch = "INCONCLUSIVE"
+			}
+			return
+		}
+	}
+
 	switch r.Mode {
 	case RunModeVPNOnly:
 		r.Verdict = Verdict{
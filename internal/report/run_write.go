@@ -12,6 +12,9 @@ import (
 )
 
 func WriteRunJSON(path string, r RunReport) error {
+	if r.SchemaVersion == 0 {
+		r.SchemaVersion = SchemaVersion
+	}
 	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
 		return err
 	}
@@ -44,6 +47,11 @@ func RenderRunText(r RunReport) string {
 	writeExitLine(&b, "Exit IPv4", r.Baseline.ExitV4, findExitDelta(r, "ipv4"))
 	writeExitLine(&b, "Exit IPv6", r.Baseline.ExitV6, findExitDelta(r, "ipv6"))
 	writeDNSLine(&b, r)
+	writeDNSRouteLine(&b, r)
+	writeDNSAssessment(&b, r.DNS)
+	writeEgressLine(&b, r.Baseline.Observation)
+	writeIPv6Analysis(&b, r.IPv6)
+	writeWireGuardLine(&b, r)
 	b.WriteString("\n")
 
 	// Result.
@@ -53,12 +61,15 @@ func RenderRunText(r RunReport) string {
 			b.WriteString(fmt.Sprintf("Offline at: T+%ds\n", *r.OfflineAtSec))
 		}
 	} else {
-		b.WriteString("VPN test: OK\n")
+		b.WriteString(fmt.Sprintf("VPN test: %s\n", r.Verdict.Overall))
 	}
 	if strings.TrimSpace(r.Verdict.Reason) != "" {
 		b.WriteString("Reason: " + strings.TrimSpace(r.Verdict.Reason) + "\n")
 	}
 
+	writeConnectionEvents(&b, r)
+	writeHostConfig(&b, r.Host, false)
+
 	// Notes (kept short and de-duplicated).
 	notes := []string{}
 	notes = append(notes, r.Notes...)
@@ -83,10 +94,10 @@ func writeBanner(b *strings.Builder) {
 func writeRunLine(b *strings.Builder, r RunReport) {
 	started := r.StartedUTC.Format("2006-01-02T15:04:05Z")
 	if r.Mode == RunModeKillSwitch {
-		b.WriteString(fmt.Sprintf("Run: %s  |  Duration: %s  |  Interval: %s\n", started, durShort(r.Duration), durShort(r.Interval)))
+		b.WriteString(fmt.Sprintf("Run: %s  |  Duration: %s  |  Interval: %s\n", started, durShort(r.Duration.Duration()), durShort(r.Interval.Duration())))
 		return
 	}
-	b.WriteString(fmt.Sprintf("Run: %s  |  Duration: %s\n", started, durShort(r.Duration)))
+	b.WriteString(fmt.Sprintf("Run: %s  |  Duration: %s\n", started, durShort(r.Duration.Duration())))
 }
 
 func durShort(d time.Duration) string {
@@ -113,7 +124,7 @@ func writeExitLine(b *strings.Builder, label string, base ExitInfo, d *ExitDelta
 	}
 
 	if d == nil || strings.TrimSpace(d.To.IP) == "" {
-		b.WriteString(fmt.Sprintf("%s: %s%s\n", label, base.IP, formatGeoSuffix(base.Geo)))
+		b.WriteString(fmt.Sprintf("%s: %s%s%s\n", label, base.IP, formatGeoSuffix(base.Geo), disputeSuffix(base.Geo)))
 		return
 	}
 
@@ -128,7 +139,7 @@ func writeExitLine(b *strings.Builder, label string, base ExitInfo, d *ExitDelta
 }
 
 func writeDNSLine(b *strings.Builder, r RunReport) {
-	base := formatDNSShort(r.Baseline.DNSRecursors)
+	base := formatDNSList(r.Baseline.DNSRecursors)
 	if base == "" {
 		return
 	}
@@ -136,44 +147,88 @@ func writeDNSLine(b *strings.Builder, r RunReport) {
 		b.WriteString(fmt.Sprintf("DNS: %s\n", base))
 		return
 	}
-	from := formatDNSShort(r.DNSDelta.From)
-	to := formatDNSShort(r.DNSDelta.To)
+	from := formatDNSList(r.DNSDelta.From)
+	to := formatDNSList(r.DNSDelta.To)
 	if from == "" {
 		from = base
 	}
 	b.WriteString(fmt.Sprintf("DNS: %s  ->  %s  [T+%ds]\n", from, to, r.DNSDelta.AtSec))
+	for _, d := range r.DNSDelta.Lookups {
+		b.WriteString(fmt.Sprintf("  %s: %s  ->  %s\n", d.Label(), formatDNSList(d.From), formatDNSList(d.To)))
+	}
 }
 
-func formatDNSShort(list []string) string {
-	if len(list) == 0 {
-		return ""
+// writeDNSRouteLine prints systemd-resolved's route of the baseline lookups.
+func writeDNSRouteLine(b *strings.Builder, r RunReport) {
+	if rt := r.Baseline.DNSRoute; rt != nil && len(r.Baseline.DNSRecursors) > 0 {
+		b.WriteString("DNS route (systemd-resolved): " + rt.Text() + "\n")
 	}
-	var v4 string
-	hasV6 := false
-	for _, s := range list {
-		ip := strings.TrimSpace(s)
-		if ip == "" {
-			continue
+}
+
+func writeWireGuardLine(b *strings.Builder, r RunReport) {
+	for _, d := range r.Baseline.WireGuard {
+		for _, p := range d.Peers {
+			if !carriesDefaultRoute(p) {
+				continue
+			}
+			hs := "no handshake"
+			if p.LastHandshakeUTC != nil {
+				hs = "handshake " + durShort(r.Baseline.AtUTC.Sub(*p.LastHandshakeUTC).Round(time.Second)) + " ago"
+			}
+			b.WriteString(fmt.Sprintf("WireGuard: %s -> %s (%s)\n", d.Name, printableEndpoint(p.Endpoint), hs))
 		}
-		if strings.Contains(ip, ":") {
-			hasV6 = true
-			continue
+	}
+	if t := r.TunnelBypass; t != nil {
+		b.WriteString(fmt.Sprintf("Tunnel bypass: %s: %s  [T+%ds]\n", t.Device, t.Reason, t.AtSec))
+	}
+}
+
+func writeConnectionEvents(b *strings.Builder, r RunReport) {
+	if len(r.ConnectionEvents) == 0 {
+		return
+	}
+	b.WriteString("\nConnection events:\n")
+	for _, e := range r.ConnectionEvents {
+		line := fmt.Sprintf("  T+%ds  %s %s", e.AtSec, e.Kind, e.State)
+		if e.Connection != "" {
+			line += fmt.Sprintf(" (%s)", e.Connection)
 		}
-		if v4 == "" {
-			v4 = ip
+		if e.Error != "" {
+			line += ": " + e.Error
 		}
+		b.WriteString(line + "\n")
 	}
+}
 
-	if v4 == "" {
-		if hasV6 {
-			return "ipv6"
+func printableEndpoint(ep string) string {
+	if strings.TrimSpace(ep) == "" {
+		return "(no endpoint)"
+	}
+	return ep
+}
+
+// formatDNSList prints every recursor, IPv4 before IPv6.
+func formatDNSList(list []string) string {
+	var v4, v6 []string
+	for _, s := range list {
+		ip := strings.TrimSpace(s)
+		switch {
+		case ip == "":
+		case strings.Contains(ip, ":"):
+			v6 = append(v6, ip)
+		default:
+			v4 = append(v4, ip)
 		}
-		return ""
 	}
-	if hasV6 {
-		return v4 + ", ipv6"
+	return strings.Join(append(v4, v6...), ", ")
+}
+
+// disputeSuffix flags an exit the geo providers did not agree on.
+func disputeSuffix(g GeoInfo) string {
+	if g.Trusted() {
+		return ""
 	}
-	return v4
+	return " " + consensusText(g.Consensus)
 }
 
 func formatGeoSuffix(g GeoInfo) string {
@@ -0,0 +1,163 @@
+// File: internal/report/schema.go (complete file)
+
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SchemaVersion is written to schema_version of every run report and
+// snapshot. Version 1 is the unversioned layout with durations in
+// nanoseconds; version 2 stores durations as seconds (duration_seconds, ...);
+// version 3 gives snapshots exit_v4/exit_v6, filled from public_ips when an
+// older snapshot is read.
+const SchemaVersion = 3
+
+// Seconds is a duration that serializes as a number of seconds.
+type Seconds time.Duration
+
+func (s Seconds) Duration() time.Duration { return time.Duration(s) }
+
+func (s Seconds) String() string { return time.Duration(s).String() }
+
+func (s Seconds) MarshalJSON() ([]byte, error) {
+	return strconv.AppendFloat(nil, time.Duration(s).Seconds(), 'f', -1, 64), nil
+}
+
+func (s *Seconds) UnmarshalJSON(b []byte) error {
+	var f float64
+	if err := json.Unmarshal(b, &f); err != nil {
+		return fmt.Errorf("duration must be a number of seconds: %w", err)
+	}
+	*s = Seconds(math.Round(f * float64(time.Second)))
+	return nil
+}
+
+// JSONSchema returns the JSON Schema (draft 2020-12) of a report kind,
+// generated from the Go types so it cannot drift from what is written.
+func JSONSchema(kind DocumentKind) ([]byte, error) {
+	var root reflect.Type
+	var title string
+	switch kind {
+	case KindRun:
+		root, title = reflect.TypeOf(RunReport{}), "vpnleakidentifier run report"
+	case KindSnapshot:
+		root, title = reflect.TypeOf(Snapshot{}), "vpnleakidentifier snapshot"
+	default:
+		return nil, fmt.Errorf("unknown report kind %q (want run|snapshot)", kind)
+	}
+
+	g := &schemaGen{defs: map[string]any{}}
+	doc := g.object(root)
+	doc["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	doc["$id"] = fmt.Sprintf("https://github.com/baptistax/vpn-leak-identifier/schema/%s/v%d.json", kind, SchemaVersion)
+	doc["title"] = title
+	if len(g.defs) > 0 {
+		doc["$defs"] = g.defs
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// schemaEnums restricts string fields to known values, keyed by
+// "<Go type>.<json name>".
+var schemaEnums = map[string][]string{
+	"RunReport.mode":           {string(RunModeKillSwitch), string(RunModeVPNOnly)},
+	"Verdict.overall":          validOverall,
+	"Verdict.kill_switch":      validKillSwitch[1:],
+	"PublicIPResult.family":    validFamilies,
+	"ExitDelta.family":         {"ipv4", "ipv6"},
+	"ConnectionEvent.source":   {"networkmanager"},
+	"GeoConsensus.status":      {"agreed", "disputed", "insufficient", "failed"},
+	"Verdict.dns":              validDNS[1:],
+	"DNSAssessment.verdict":    validDNS[1:],
+	"RecursorAssessment.class": {RecursorVPN, RecursorPublic, RecursorISP, RecursorThirdParty, RecursorUnknown},
+	"IPv6Finding.severity":     {"info", "warning", "critical"},
+	"IPv6Finding.kind":         {"eui64", "privacy", "teredo", "6to4", "nat64", "native"},
+	"HostFinding.severity":     {"info", "warning", "critical"},
+	"HostFinding.kind":         {"ipv6", "accept_ra", "rp_filter", "src_valid_mark", "resolv_conf", "resolved", "nsswitch"},
+}
+
+var (
+	timeType    = reflect.TypeOf(time.Time{})
+	secondsType = reflect.TypeOf(Seconds(0))
+)
+
+type schemaGen struct {
+	defs map[string]any
+}
+
+func (g *schemaGen) object(t reflect.Type) map[string]any {
+	props := map[string]any{}
+	var required []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		s := g.schema(f.Type)
+		if name == "schema_version" {
+			s = map[string]any{"type": "integer", "const": SchemaVersion}
+		}
+		if enum, ok := schemaEnums[t.Name()+"."+name]; ok {
+			s["enum"] = enum
+		}
+		props[name] = s
+		if !strings.Contains(opts, "omitempty") {
+			required = append(required, name)
+		}
+	}
+	out := map[string]any{"type": "object", "properties": props}
+	if len(required) > 0 {
+		out["required"] = required
+	}
+	return out
+}
+
+func (g *schemaGen) schema(t reflect.Type) map[string]any {
+	switch t {
+	case timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case secondsType:
+		return map[string]any{"type": "number", "description": "duration in seconds"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return g.schema(t.Elem())
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]any{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		// A nil slice without omitempty is written as null.
+		return map[string]any{"type": []string{"array", "null"}, "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": []string{"object", "null"}, "additionalProperties": g.schema(t.Elem())}
+	case reflect.Struct:
+		if _, ok := g.defs[t.Name()]; !ok {
+			g.defs[t.Name()] = nil // reserve against recursion
+			g.defs[t.Name()] = g.object(t)
+		}
+		return map[string]any{"$ref": "#/$defs/" + t.Name()}
+	}
+	return map[string]any{}
+}
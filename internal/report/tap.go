@@ -0,0 +1,43 @@
+// File: internal/report/tap.go (complete file)
+
+package report
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// RenderTAP renders checks as a TAP version 13 stream. Failures carry a YAML
+// diagnostic block with the message and evidence.
+func RenderTAP(checks []Check) string {
+	var b strings.Builder
+	b.WriteString("TAP version 13\n")
+	b.WriteString(fmt.Sprintf("1..%d\n", len(checks)))
+	for i, c := range checks {
+		switch c.Status {
+		case CheckFail:
+			b.WriteString(fmt.Sprintf("not ok %d - %s\n", i+1, c.Name))
+			b.WriteString("  ---\n")
+			b.WriteString("  message: " + strconv.Quote(c.Message) + "\n")
+			if len(c.Evidence) > 0 {
+				b.WriteString("  evidence:\n")
+				for _, e := range c.Evidence {
+					b.WriteString("    - " + strconv.Quote(e) + "\n")
+				}
+			}
+			b.WriteString("  ...\n")
+		case CheckSkip:
+			b.WriteString(fmt.Sprintf("ok %d - %s # SKIP %s\n", i+1, c.Name, tapLine(c.Message)))
+		default:
+			b.WriteString(fmt.Sprintf("ok %d - %s\n", i+1, c.Name))
+		}
+	}
+	return b.String()
+}
+
+// tapLine keeps a directive on one line; '#' would start a new directive.
+func tapLine(s string) string {
+	s = strings.ReplaceAll(s, "\n", " ")
+	return strings.ReplaceAll(s, "#", `\#`)
+}
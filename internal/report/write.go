@@ -11,6 +11,9 @@ import (
 )
 
 func WriteJSON(path string, s Snapshot) error {
+	if s.SchemaVersion == 0 {
+		s.SchemaVersion = SchemaVersion
+	}
 	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
 		return err
 	}
@@ -29,10 +32,17 @@ func WriteText(path string, s Snapshot) error {
 	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
 		return err
 	}
+	return os.WriteFile(path, []byte(RenderSnapshotText(s)), 0o644)
+}
 
+func RenderSnapshotText(s Snapshot) string {
 	var b strings.Builder
 	b.WriteString("Timestamp (UTC): " + s.TimestampUTC.Format("2006-01-02T15:04:05Z") + "\n")
 
+	if s.ExitV4.Family != "" || s.ExitV6.Family != "" {
+		writeExitLine(&b, "Exit IPv4", s.ExitV4, nil)
+		writeExitLine(&b, "Exit IPv6", s.ExitV6, nil)
+	}
 	for _, r := range s.PublicIPs {
 		if r.Error != "" {
 			b.WriteString(fmt.Sprintf("Public IP [%s/%s]: error: %s\n", r.Source, r.Family, r.Error))
@@ -41,24 +51,88 @@ func WriteText(path string, s Snapshot) error {
 		b.WriteString(fmt.Sprintf("Public IP [%s/%s]: %s\n", r.Source, r.Family, r.IP))
 	}
 
-	if len(s.DnsRecursors) > 0 {
-		b.WriteString("DNS recursors (via ns.ident.me): " + strings.Join(s.DnsRecursors, ", ") + "\n")
-	}
+	writeObservationDetail(&b, s.Observation)
+	writeDNSAssessment(&b, s.DNS)
+	writeIPv6Analysis(&b, s.IPv6)
 
 	if len(s.DnsLeak) > 0 {
 		b.WriteString("dnsleaktest.com observed recursors:\n")
 		for _, d := range s.DnsLeak {
-			b.WriteString(fmt.Sprintf("  %s (%s) - %s - %s, %s\n", d.IPAddress, d.Hostname, d.ISP, d.City, d.Country))
+			line := fmt.Sprintf("  %s (%s) - %s - %s, %s", d.IPAddress, d.Hostname, d.ISP, d.City, d.Country)
+			if g := s.IPGeo[d.IPAddress]; g.ASN != "" {
+				line += " - " + g.ASN
+			}
+			b.WriteString(line + "\n")
 		}
 	}
 
-	if len(s.StunObserved) > 0 {
-		b.WriteString("STUN observed public IPs: " + strings.Join(s.StunObserved, ", ") + "\n")
-	}
+	writeHostConfig(&b, s.Host, false)
 
 	for _, n := range s.Notes {
 		b.WriteString("Note: " + n + "\n")
 	}
 
-	return os.WriteFile(path, []byte(b.String()), 0o644)
+	return b.String()
+}
+
+// writeObservationDetail prints recursors per family, STUN per server and the
+// egress interfaces. Reports written before these were split fall back to
+// the flat lists.
+func writeObservationDetail(b *strings.Builder, o Observation) {
+	if len(o.Recursors) > 0 {
+		for _, r := range o.Recursors {
+			query := r.Query
+			if query == "" {
+				query = "ns.ident.me"
+			}
+			switch {
+			case r.Error != "":
+				b.WriteString(fmt.Sprintf("DNS recursors [%s] (via %s): error: %s\n", r.Label(), query, r.Error))
+			case len(r.IPs) > 0:
+				b.WriteString(fmt.Sprintf("DNS recursors [%s] (via %s): %s\n", r.Label(), query, strings.Join(o.Annotate(r.IPs), ", ")))
+			}
+		}
+	} else if len(o.DNSRecursors) > 0 {
+		b.WriteString("DNS recursors (via ns.ident.me): " + strings.Join(o.Annotate(o.DNSRecursors), ", ") + "\n")
+	}
+	if o.DNSRoute != nil {
+		b.WriteString("DNS route (systemd-resolved): " + o.DNSRoute.Text() + "\n")
+	}
+
+	if len(o.Stun) > 0 {
+		for _, r := range o.Stun {
+			if r.Error != "" {
+				b.WriteString(fmt.Sprintf("STUN [%s]: error: %s\n", r.Server, r.Error))
+				continue
+			}
+			b.WriteString(fmt.Sprintf("STUN [%s]: %s\n", r.Server, strings.Join(o.Annotate(r.Observed), ", ")))
+		}
+	} else if len(o.StunObserved) > 0 {
+		b.WriteString("STUN observed public IPs: " + strings.Join(o.Annotate(o.StunObserved), ", ") + "\n")
+	}
+
+	writeEgressLine(b, o)
+}
+
+// writeDNSAssessment prints the DNS verdict and the class of each recursor.
+func writeDNSAssessment(b *strings.Builder, a *DNSAssessment) {
+	if a == nil {
+		return
+	}
+	b.WriteString(fmt.Sprintf("DNS leak: %s (%s)\n", a.Verdict, a.Reason))
+	for _, r := range a.Recursors {
+		b.WriteString("  " + recursorText(r) + "\n")
+	}
+}
+
+func writeEgressLine(b *strings.Builder, o Observation) {
+	var parts []string
+	for _, family := range []string{"ipv4", "ipv6"} {
+		if name := o.EgressInterface(family); name != "" {
+			parts = append(parts, family+" via "+name)
+		}
+	}
+	if len(parts) > 0 {
+		b.WriteString("Egress: " + strings.Join(parts, ", ") + "\n")
+	}
 }
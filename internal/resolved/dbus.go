@@ -0,0 +1,197 @@
+// File: internal/resolved/dbus.go (complete file)
+
+package resolved
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+
+	"github.com/baptistax/vpn-leak-identifier/internal/dbus"
+)
+
+// systemd-resolved D-Bus API (see org.freedesktop.resolve1(5)).
+
+const (
+	BusName = "org.freedesktop.resolve1"
+
+	ManagerPath = dbus.ObjectPath("/org/freedesktop/resolve1")
+
+	IfaceManager = "org.freedesktop.resolve1.Manager"
+	IfaceLink    = "org.freedesktop.resolve1.Link"
+)
+
+type Client struct {
+	conn *dbus.Conn
+}
+
+// Connect opens the system bus, where systemd-resolved lives.
+func Connect() (*Client, error) {
+	conn, err := dbus.SystemBus()
+	if err != nil {
+		return nil, err
+	}
+	return New(conn), nil
+}
+
+// New wraps an existing bus connection (e.g. a session bus in tests).
+func New(conn *dbus.Conn) *Client {
+	return &Client{conn: conn}
+}
+
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// Links returns the global scope (index 0) and every link with DNS servers
+// or domains, sorted by index. Names come from the local interfaces, "#N"
+// when the index is unknown. When no servers are configured anywhere the
+// global scope carries resolved's FallbackDNS, as resolved itself does.
+func (c *Client) Links(ctx context.Context) ([]Link, error) {
+	props, err := c.conn.GetAllProperties(ctx, BusName, ManagerPath, IfaceManager)
+	if err != nil {
+		return nil, err
+	}
+
+	global := Link{Name: "global"}
+	byIndex := map[int]*Link{}
+	link := func(idx int) *Link {
+		if idx == 0 {
+			return &global
+		}
+		if l, ok := byIndex[idx]; ok {
+			return l
+		}
+		l := &Link{Index: idx, Name: interfaceName(idx)}
+		byIndex[idx] = l
+		return l
+	}
+	for _, s := range structs(props["DNS"]) {
+		if len(s) == 3 {
+			l := link(intValue(s[0]))
+			l.Servers = append(l.Servers, addrString(s[1], s[2]))
+		}
+	}
+	for _, s := range structs(props["Domains"]) {
+		if len(s) == 3 {
+			l := link(intValue(s[0]))
+			l.Domains = append(l.Domains, domainString(s[1], s[2]))
+		}
+	}
+
+	out := []Link{}
+	for idx, l := range byIndex {
+		c.readLink(ctx, idx, l)
+		out = append(out, *l)
+	}
+	if !hasServers(global) && !anyServers(out) {
+		for _, s := range structs(props["FallbackDNS"]) {
+			if len(s) == 3 && intValue(s[0]) == 0 {
+				global.Servers = append(global.Servers, addrString(s[1], s[2]))
+			}
+		}
+		if hasServers(global) {
+			global.Name = "global (fallback)"
+		}
+	}
+	if hasServers(global) || len(global.Domains) > 0 {
+		global.DefaultRoute = EffectiveDefaultRoute(global)
+		out = append(out, global)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Index < out[j].Index })
+	return out, nil
+}
+
+// readLink replaces the manager's view of a link with the link object's
+// own DNS, Domains and DefaultRoute. Older resolved versions lack the
+// DefaultRoute property; the setting is then derived from the domains.
+func (c *Client) readLink(ctx context.Context, idx int, l *Link) {
+	l.DefaultRoute = EffectiveDefaultRoute(*l)
+	out, err := c.conn.Call(ctx, BusName, ManagerPath, IfaceManager, "GetLink", "i", int32(idx))
+	if err != nil || len(out) == 0 {
+		return
+	}
+	path, ok := out[0].(dbus.ObjectPath)
+	if !ok {
+		return
+	}
+	props, err := c.conn.GetAllProperties(ctx, BusName, path, IfaceLink)
+	if err != nil {
+		return
+	}
+	if v, ok := props["DNS"]; ok {
+		l.Servers = nil
+		for _, s := range structs(v) {
+			if len(s) == 2 {
+				l.Servers = append(l.Servers, addrString(s[0], s[1]))
+			}
+		}
+	}
+	if v, ok := props["Domains"]; ok {
+		l.Domains = nil
+		for _, s := range structs(v) {
+			if len(s) == 2 {
+				l.Domains = append(l.Domains, domainString(s[0], s[1]))
+			}
+		}
+	}
+	if b, ok := props["DefaultRoute"].(bool); ok {
+		l.DefaultRoute, l.DefaultRouteSet = b, true
+	} else {
+		l.DefaultRoute = EffectiveDefaultRoute(*l)
+	}
+}
+
+func hasServers(l Link) bool { return len(l.Servers) > 0 }
+
+func anyServers(links []Link) bool {
+	for _, l := range links {
+		if hasServers(l) {
+			return true
+		}
+	}
+	return false
+}
+
+func interfaceName(idx int) string {
+	if i, err := net.InterfaceByIndex(idx); err == nil {
+		return i.Name
+	}
+	return fmt.Sprintf("#%d", idx)
+}
+
+// structs returns the fields of each struct in a decoded array of structs.
+func structs(v any) [][]any {
+	list, _ := v.([]any)
+	var out [][]any
+	for _, e := range list {
+		if s, ok := e.([]any); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func intValue(v any) int {
+	n, _ := v.(int32)
+	return int(n)
+}
+
+// addrString renders an (address family, address bytes) pair.
+func addrString(family, addr any) string {
+	b, _ := addr.([]byte)
+	if intValue(family) == 2 && len(b) == net.IPv4len || len(b) == net.IPv6len {
+		return net.IP(b).String()
+	}
+	return fmt.Sprintf("%x", b)
+}
+
+// domainString restores the "~" of routing-only domains.
+func domainString(name, routeOnly any) string {
+	s, _ := name.(string)
+	if b, _ := routeOnly.(bool); b {
+		return "~" + s
+	}
+	return s
+}
@@ -0,0 +1,129 @@
+// File: internal/resolved/resolved.go (complete file)
+
+// Package resolved reads systemd-resolved's per-link DNS configuration and
+// predicts which links a name is sent to.
+package resolved
+
+import "strings"
+
+// Link is systemd-resolved's DNS configuration of one link. Index 0 is the
+// global scope (resolved.conf).
+type Link struct {
+	Index   int
+	Name    string
+	Servers []string
+	// Domains are search domains; routing-only domains keep their "~"
+	// ("~." routes every name to this link).
+	Domains []string
+	// DefaultRoute is the effective setting; DefaultRouteSet is false when
+	// it was derived from the domains rather than configured.
+	DefaultRoute    bool
+	DefaultRouteSet bool
+}
+
+// RoutingOnly reports whether any of the link's domains is routing-only.
+func (l Link) RoutingOnly() bool {
+	for _, d := range l.Domains {
+		if strings.HasPrefix(d, "~") {
+			return true
+		}
+	}
+	return false
+}
+
+// RoutesAll reports whether the link has the "~." routing domain.
+func (l Link) RoutesAll() bool {
+	for _, d := range l.Domains {
+		if d == "~." {
+			return true
+		}
+	}
+	return false
+}
+
+// EffectiveDefaultRoute mirrors resolved's link_get_default_route: an
+// explicit DefaultRoute= wins, otherwise a link is a default route unless
+// it has routing-only domains.
+func EffectiveDefaultRoute(l Link) bool {
+	if l.DefaultRouteSet {
+		return l.DefaultRoute
+	}
+	return !l.RoutingOnly()
+}
+
+// Route is the predicted path of one name.
+type Route struct {
+	Name string
+	// Links are the links the query is sent to; resolved sends it to all of
+	// them in parallel and takes the first answer.
+	Links []Link
+	// Domain is the routing or search domain that claimed the name; empty
+	// when the name fell through to the default-route links.
+	Domain string
+	Reason string
+}
+
+// Predict returns the links resolved would send name to. It follows
+// resolved's scope selection: links whose domains match the name win, the
+// longest match first ("." matching everything with the lowest rank), and
+// only when no link claims the name does it go to every default-route
+// link. Links without DNS servers have no DNS scope and never take part.
+func Predict(links []Link, name string) Route {
+	name = strings.TrimSuffix(strings.ToLower(name), ".")
+	r := Route{Name: name}
+
+	best, bestDomain := -1, ""
+	var matched []Link
+	for _, l := range links {
+		if len(l.Servers) == 0 {
+			continue
+		}
+		n, domain := matchLabels(l.Domains, name)
+		switch {
+		case n < 0 || n < best:
+		case n > best:
+			best, bestDomain, matched = n, domain, []Link{l}
+		default:
+			matched = append(matched, l)
+		}
+	}
+
+	if len(matched) > 0 {
+		r.Domain = bestDomain
+		r.Reason = "domain " + bestDomain + " matches"
+	} else {
+		for _, l := range links {
+			if len(l.Servers) > 0 && l.DefaultRoute {
+				matched = append(matched, l)
+			}
+		}
+		r.Reason = "no domain matches; sent to every default-route link"
+		if len(matched) == 0 {
+			r.Reason = "no link can resolve the name"
+		}
+	}
+	r.Links = matched
+	return r
+}
+
+// matchLabels returns the label count of the longest domain in domains
+// that name is equal to or below, with the domain as configured, or -1.
+func matchLabels(domains []string, name string) (int, string) {
+	best, bestDomain := -1, ""
+	for _, raw := range domains {
+		d := strings.TrimSuffix(strings.ToLower(strings.TrimPrefix(raw, "~")), ".")
+		n := 0
+		switch {
+		case d == "":
+			// "~." matches every name, ranked below any real domain.
+		case name == d || strings.HasSuffix(name, "."+d):
+			n = strings.Count(d, ".") + 1
+		default:
+			continue
+		}
+		if n > best {
+			best, bestDomain = n, raw
+		}
+	}
+	return best, bestDomain
+}
@@ -0,0 +1,149 @@
+// File: internal/resolved/resolved_test.go (complete file)
+
+package resolved
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/baptistax/vpn-leak-identifier/internal/dbus"
+	"github.com/baptistax/vpn-leak-identifier/internal/dbus/dbustest"
+)
+
+func names(r Route) []string {
+	var out []string
+	for _, l := range r.Links {
+		out = append(out, l.Name)
+	}
+	return out
+}
+
+func TestPredict(t *testing.T) {
+	eth := Link{Index: 2, Name: "eth0", Servers: []string{"192.168.1.1"}, Domains: []string{"lan"}, DefaultRoute: true}
+	wg := Link{Index: 5, Name: "wg0", Servers: []string{"10.64.0.1"}, Domains: []string{"~corp.example.com"}}
+	wgAll := Link{Index: 5, Name: "wg0", Servers: []string{"10.64.0.1"}, Domains: []string{"~."}}
+	noServers := Link{Index: 7, Name: "tun1", Domains: []string{"~."}, DefaultRoute: true}
+
+	cases := []struct {
+		name  string
+		links []Link
+		query string
+		want  []string
+	}{
+		// The VPN does not claim "~.": unclaimed names go to every default
+		// route, here only the physical link.
+		{"fall through", []Link{eth, wg}, "ns.ident.me", []string{"eth0"}},
+		{"routing domain", []Link{eth, wg}, "git.corp.example.com.", []string{"wg0"}},
+		{"search domain", []Link{eth, wgAll}, "printer.lan", []string{"eth0"}},
+		{"route all", []Link{eth, wgAll}, "ns.ident.me", []string{"wg0"}},
+		{"no servers", []Link{noServers}, "ns.ident.me", nil},
+		{"partial label", []Link{eth, wg}, "notcorp.example.com", []string{"eth0"}},
+	}
+	for _, c := range cases {
+		r := Predict(c.links, c.query)
+		if got := names(r); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: links = %v, want %v (%s)", c.name, got, c.want, r.Reason)
+		}
+	}
+
+	if r := Predict([]Link{eth, wg}, "git.corp.example.com"); r.Domain != "~corp.example.com" {
+		t.Errorf("domain = %q", r.Domain)
+	}
+}
+
+// properties answers Get and GetAll from a fixed property map.
+func properties(props map[string]dbus.Variant) dbus.Handler {
+	return func(member string, args []any) (dbus.Signature, []any, error) {
+		if member == "Get" {
+			return "v", []any{props[args[1].(string)]}, nil
+		}
+		return "a{sv}", []any{props}, nil
+	}
+}
+
+// newMockResolved exports a resolved with a physical link (9001) that is a
+// default route and a VPN link (9002) with only a routing domain, so names
+// outside it leak to the physical link.
+func newMockResolved(t *testing.T, addr string) {
+	t.Helper()
+	conn, err := dbus.Dial(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	conn.Export(ManagerPath, "org.freedesktop.DBus.Properties", properties(map[string]dbus.Variant{
+		"DNS": {Sig: "a(iiay)", Value: []any{
+			[]any{int32(9001), int32(2), []byte{192, 168, 1, 1}},
+			[]any{int32(9002), int32(2), []byte{10, 64, 0, 1}},
+		}},
+		"Domains": {Sig: "a(isb)", Value: []any{
+			[]any{int32(9002), "corp.example.com", true},
+		}},
+		"FallbackDNS": {Sig: "a(iiay)", Value: []any{
+			[]any{int32(0), int32(2), []byte{1, 1, 1, 1}},
+		}},
+	}))
+	conn.Export(ManagerPath, IfaceManager, func(member string, args []any) (dbus.Signature, []any, error) {
+		if member != "GetLink" {
+			return "", nil, fmt.Errorf("unexpected %s", member)
+		}
+		return "o", []any{dbus.ObjectPath(fmt.Sprintf("/org/freedesktop/resolve1/link/_%d", args[0].(int32)))}, nil
+	})
+	conn.Export("/org/freedesktop/resolve1/link/_9001", "org.freedesktop.DBus.Properties", properties(map[string]dbus.Variant{
+		"DNS":          {Sig: "a(iay)", Value: []any{[]any{int32(2), []byte{192, 168, 1, 1}}}},
+		"Domains":      {Sig: "a(sb)", Value: []any{[]any{"lan", false}}},
+		"DefaultRoute": dbus.MakeVariant(true),
+	}))
+	conn.Export("/org/freedesktop/resolve1/link/_9002", "org.freedesktop.DBus.Properties", properties(map[string]dbus.Variant{
+		"DNS": {Sig: "a(iay)", Value: []any{
+			[]any{int32(2), []byte{10, 64, 0, 1}},
+			[]any{int32(10), []byte{0xfd, 0x00, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1}},
+		}},
+		"Domains":      {Sig: "a(sb)", Value: []any{[]any{"corp.example.com", true}}},
+		"DefaultRoute": dbus.MakeVariant(false),
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := conn.RequestName(ctx, BusName); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLinks_ReadsManagerAndLinks(t *testing.T) {
+	addr := dbustest.StartSessionBus(t)
+	newMockResolved(t, addr)
+
+	conn, err := dbus.Dial(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := New(conn)
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	links, err := c.Links(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Link{
+		{Index: 9001, Name: "#9001", Servers: []string{"192.168.1.1"}, Domains: []string{"lan"}, DefaultRoute: true, DefaultRouteSet: true},
+		{Index: 9002, Name: "#9002", Servers: []string{"10.64.0.1", "fd00::1"}, Domains: []string{"~corp.example.com"}, DefaultRouteSet: true},
+	}
+	if !reflect.DeepEqual(links, want) {
+		t.Fatalf("links = %+v", links)
+	}
+
+	if r := Predict(links, "ns.ident.me"); !reflect.DeepEqual(names(r), []string{"#9001"}) {
+		t.Fatalf("ns.ident.me routed to %v (%s)", names(r), r.Reason)
+	}
+	if r := Predict(links, "wiki.corp.example.com"); !reflect.DeepEqual(names(r), []string{"#9002"}) {
+		t.Fatalf("wiki.corp.example.com routed to %v (%s)", names(r), r.Reason)
+	}
+}
@@ -0,0 +1,64 @@
+// File: internal/sdnotify/sdnotify.go (complete file)
+
+package sdnotify
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Common states, see sd_notify(3).
+const (
+	Ready     = "READY=1"
+	Reloading = "RELOADING=1"
+	Stopping  = "STOPPING=1"
+	Watchdog  = "WATCHDOG=1"
+)
+
+// Enabled reports whether the process was started with a notify socket.
+func Enabled() bool {
+	return os.Getenv("NOTIFY_SOCKET") != ""
+}
+
+// Notify sends newline-separated state assignments to $NOTIFY_SOCKET. It is a
+// no-op returning nil when the variable is unset (not run by systemd).
+func Notify(states ...string) error {
+	path := os.Getenv("NOTIFY_SOCKET")
+	if path == "" {
+		return nil
+	}
+	// "@name" is an abstract socket, written with a leading NUL.
+	if strings.HasPrefix(path, "@") {
+		path = "\x00" + path[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.Write([]byte(strings.Join(states, "\n")))
+	return err
+}
+
+// Status formats a STATUS= assignment; newlines are not allowed in it.
+func Status(s string) string {
+	return "STATUS=" + strings.ReplaceAll(s, "\n", " ")
+}
+
+// WatchdogInterval returns $WATCHDOG_USEC when the watchdog is enabled for
+// this process, or zero.
+func WatchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond
+}
@@ -0,0 +1,283 @@
+// File: internal/wireguard/wireguard.go (complete file)
+
+package wireguard
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"net"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/baptistax/vpn-leak-identifier/internal/netlink"
+)
+
+// Kernel WireGuard state is read through the "wireguard" generic netlink family
+// (see include/uapi/linux/wireguard.h). Reading requires CAP_NET_ADMIN.
+
+const (
+	genlName    = "wireguard"
+	genlVersion = 1
+
+	cmdGetDevice uint8 = 0
+
+	deviceAttrIfindex    uint16 = 1
+	deviceAttrIfname     uint16 = 2
+	deviceAttrPublicKey  uint16 = 4
+	deviceAttrListenPort uint16 = 6
+	deviceAttrFwmark     uint16 = 7
+	deviceAttrPeers      uint16 = 8
+
+	peerAttrPublicKey         uint16 = 1
+	peerAttrEndpoint          uint16 = 4
+	peerAttrKeepaliveInterval uint16 = 5
+	peerAttrLastHandshake     uint16 = 6
+	peerAttrRxBytes           uint16 = 7
+	peerAttrTxBytes           uint16 = 8
+	peerAttrAllowedIPs        uint16 = 9
+
+	allowedIPAttrFamily   uint16 = 1
+	allowedIPAttrIPAddr   uint16 = 2
+	allowedIPAttrCidrMask uint16 = 3
+)
+
+var ErrNotSupported = netlink.ErrNotSupported
+
+type Device struct {
+	Name       string
+	Index      int
+	PublicKey  string
+	ListenPort int
+	Fwmark     uint32
+	Peers      []Peer
+}
+
+type Peer struct {
+	PublicKey         string
+	Endpoint          string
+	KeepaliveInterval time.Duration
+	LastHandshake     time.Time // zero if no handshake has completed
+	RxBytes           uint64
+	TxBytes           uint64
+	AllowedIPs        []string
+}
+
+// Devices returns the state of every kernel WireGuard interface on the host.
+// An empty result with a nil error means no WireGuard interface exists. The
+// ctx deadline bounds every netlink read; cancellation stops between
+// interfaces.
+func Devices(ctx context.Context) ([]Device, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := netlink.Dial(netlink.FamilyGeneric, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	if dl, ok := ctx.Deadline(); ok && time.Until(dl) > 0 {
+		if err := c.SetReadTimeout(time.Until(dl)); err != nil {
+			return nil, err
+		}
+	}
+
+	family, err := c.ResolveFamily(genlName)
+	if err != nil {
+		// The module is not loaded, so there cannot be any WireGuard device.
+		if errors.Is(err, syscall.ENOENT) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var out []Device
+	for _, iface := range ifaces {
+		if err := ctx.Err(); err != nil {
+			return out, err
+		}
+		if iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		d, err := getDevice(c, family, iface.Name)
+		if err != nil {
+			// Non-WireGuard links are rejected by the kernel; skip them.
+			if errors.Is(err, syscall.EOPNOTSUPP) || errors.Is(err, syscall.ENODEV) {
+				continue
+			}
+			return out, err
+		}
+		out = append(out, d)
+	}
+	return out, nil
+}
+
+func getDevice(c *netlink.Conn, family uint16, name string) (Device, error) {
+	replies, err := c.ExecuteGeneric(family, netlink.FlagDump, netlink.GenericMessage{
+		Command: cmdGetDevice,
+		Version: genlVersion,
+		Attrs:   []netlink.Attribute{netlink.StringAttribute(deviceAttrIfname, name)},
+	})
+	if err != nil {
+		return Device{}, err
+	}
+
+	// Large peer lists are split across several messages; merge them.
+	var d Device
+	for _, r := range replies {
+		if err := parseDevice(&d, r.Attrs); err != nil {
+			return Device{}, err
+		}
+	}
+	if d.Name == "" {
+		d.Name = name
+	}
+	return d, nil
+}
+
+func parseDevice(d *Device, attrs []netlink.Attribute) error {
+	for _, a := range attrs {
+		switch a.Type {
+		case deviceAttrIfindex:
+			d.Index = int(a.Uint32())
+		case deviceAttrIfname:
+			d.Name = a.String()
+		case deviceAttrPublicKey:
+			d.PublicKey = encodeKey(a.Data)
+		case deviceAttrListenPort:
+			d.ListenPort = int(a.Uint16())
+		case deviceAttrFwmark:
+			d.Fwmark = a.Uint32()
+		case deviceAttrPeers:
+			peers, err := netlink.ParseAttributes(a.Data)
+			if err != nil {
+				return err
+			}
+			for _, pa := range peers {
+				p, err := parsePeer(pa.Data)
+				if err != nil {
+					return err
+				}
+				d.Peers = mergePeer(d.Peers, p)
+			}
+		}
+	}
+	return nil
+}
+
+// mergePeer appends p, or extends the allowed IPs of a peer continued from a
+// previous dump message.
+func mergePeer(peers []Peer, p Peer) []Peer {
+	if n := len(peers); n > 0 && peers[n-1].PublicKey == p.PublicKey {
+		peers[n-1].AllowedIPs = append(peers[n-1].AllowedIPs, p.AllowedIPs...)
+		return peers
+	}
+	return append(peers, p)
+}
+
+func parsePeer(b []byte) (Peer, error) {
+	attrs, err := netlink.ParseAttributes(b)
+	if err != nil {
+		return Peer{}, err
+	}
+
+	var p Peer
+	for _, a := range attrs {
+		switch a.Type {
+		case peerAttrPublicKey:
+			p.PublicKey = encodeKey(a.Data)
+		case peerAttrEndpoint:
+			p.Endpoint = parseSockaddr(a.Data)
+		case peerAttrKeepaliveInterval:
+			p.KeepaliveInterval = time.Duration(a.Uint16()) * time.Second
+		case peerAttrLastHandshake:
+			// struct __kernel_timespec { s64 tv_sec; s64 tv_nsec; }
+			if len(a.Data) >= 16 {
+				sec := int64(binary.NativeEndian.Uint64(a.Data[0:8]))
+				nsec := int64(binary.NativeEndian.Uint64(a.Data[8:16]))
+				if sec != 0 || nsec != 0 {
+					p.LastHandshake = time.Unix(sec, nsec).UTC()
+				}
+			}
+		case peerAttrRxBytes:
+			p.RxBytes = a.Uint64()
+		case peerAttrTxBytes:
+			p.TxBytes = a.Uint64()
+		case peerAttrAllowedIPs:
+			list, err := netlink.ParseAttributes(a.Data)
+			if err != nil {
+				return Peer{}, err
+			}
+			for _, ipa := range list {
+				if s := parseAllowedIP(ipa.Data); s != "" {
+					p.AllowedIPs = append(p.AllowedIPs, s)
+				}
+			}
+		}
+	}
+	return p, nil
+}
+
+func parseAllowedIP(b []byte) string {
+	attrs, err := netlink.ParseAttributes(b)
+	if err != nil {
+		return ""
+	}
+
+	var ip net.IP
+	mask := -1
+	for _, a := range attrs {
+		switch a.Type {
+		case allowedIPAttrIPAddr:
+			ip = net.IP(append([]byte(nil), a.Data...))
+		case allowedIPAttrCidrMask:
+			mask = int(a.Uint8())
+		case allowedIPAttrFamily:
+			// Implied by the address length.
+		}
+	}
+	if ip == nil || mask < 0 {
+		return ""
+	}
+	return ip.String() + "/" + strconv.Itoa(mask)
+}
+
+// parseSockaddr decodes a struct sockaddr_in / sockaddr_in6 as stored by the
+// kernel. The family is host order, the port and address network order.
+func parseSockaddr(b []byte) string {
+	if len(b) < 2 {
+		return ""
+	}
+	port := 0
+	if len(b) >= 4 {
+		port = int(binary.BigEndian.Uint16(b[2:4]))
+	}
+	switch binary.NativeEndian.Uint16(b[0:2]) {
+	case syscall.AF_INET:
+		if len(b) < 8 {
+			return ""
+		}
+		return net.JoinHostPort(net.IP(b[4:8]).String(), strconv.Itoa(port))
+	case syscall.AF_INET6:
+		if len(b) < 24 {
+			return ""
+		}
+		return net.JoinHostPort(net.IP(b[8:24]).String(), strconv.Itoa(port))
+	default:
+		return ""
+	}
+}
+
+func encodeKey(b []byte) string {
+	if len(b) == 0 {
+		return ""
+	}
+	return base64.StdEncoding.EncodeToString(b)
+}
@@ -0,0 +1,121 @@
+// File: internal/wireguard/wireguard_test.go (complete file)
+
+package wireguard
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/baptistax/vpn-leak-identifier/internal/netlink"
+)
+
+// deviceDump is the attribute payload of a WG_CMD_GET_DEVICE reply (after
+// the genetlink header) on a little-endian host: wg0, ifindex 5, port 51820,
+// fwmark 0xca6c and one peer at 203.0.113.7:51820 with keepalive 25s, a
+// handshake at 1700000000s+500ns, rx 1024, tx 2048 and 0.0.0.0/0, ::/0.
+const deviceDump = "08000100050000000800020077673000060006006cca0000080007006cca0000" +
+	"bc000880b8000080240001000102030405060708090a0b0c0d0e0f1011121314" +
+	"15161718191a1b1c1d1e1f20140004000200ca6ccb0071070000000000000000" +
+	"06000500190000001400060000f1536500000000f4010000000000000c000700" +
+	"00040000000000000c0008000008000000000000480009801c00008006000100" +
+	"020000000800020000000000050003000000000028000080060001000a000000" +
+	"14000200000000000000000000000000000000000500030000000000"
+
+// peerContinuation is a follow-up dump message continuing the same peer
+// with 10.0.0.0/8, as the kernel sends for long allowed-IP lists.
+const peerContinuation = "4c00088048000080240001000102030405060708090a0b0c0d0e0f1011121314" +
+	"15161718191a1b1c1d1e1f20200009801c000080060001000200000008000200" +
+	"0a0000000500030008000000"
+
+const peerKey = "AQIDBAUGBwgJCgsMDQ4PEBESExQVFhcYGRobHB0eHyA="
+
+func attrs(t *testing.T, s string) []netlink.Attribute {
+	t.Helper()
+	if binary.NativeEndian.Uint16([]byte{1, 0}) != 1 {
+		t.Skip("captured messages are little-endian")
+	}
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := netlink.ParseAttributes(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func TestParseDevice(t *testing.T) {
+	var d Device
+	if err := parseDevice(&d, attrs(t, deviceDump)); err != nil {
+		t.Fatal(err)
+	}
+	want := Device{
+		Name:       "wg0",
+		Index:      5,
+		ListenPort: 51820,
+		Fwmark:     0xca6c,
+		Peers: []Peer{{
+			PublicKey:         peerKey,
+			Endpoint:          "203.0.113.7:51820",
+			KeepaliveInterval: 25 * time.Second,
+			LastHandshake:     time.Unix(1700000000, 500).UTC(),
+			RxBytes:           1024,
+			TxBytes:           2048,
+			AllowedIPs:        []string{"0.0.0.0/0", "::/0"},
+		}},
+	}
+	if !reflect.DeepEqual(d, want) {
+		t.Fatalf("device =\n%+v\nwant\n%+v", d, want)
+	}
+
+	// A continued peer extends the allowed IPs instead of adding a peer.
+	if err := parseDevice(&d, attrs(t, peerContinuation)); err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Peers) != 1 || !reflect.DeepEqual(d.Peers[0].AllowedIPs, []string{"0.0.0.0/0", "::/0", "10.0.0.0/8"}) {
+		t.Fatalf("merged peers = %+v", d.Peers)
+	}
+}
+
+func TestParseSockaddr(t *testing.T) {
+	if binary.NativeEndian.Uint16([]byte{1, 0}) != 1 {
+		t.Skip("captured sockaddrs are little-endian")
+	}
+	cases := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"ipv4", "0200ca6ccb0071070000000000000000", "203.0.113.7:51820"},
+		{"ipv6", "0a0001bb0000000020010db800000000000000000000000100000000", "[2001:db8::1]:443"},
+		{"short ipv4", "0200ca6ccb00", ""},
+		{"short ipv6", "0a0001bb0000000020010db8", ""},
+		{"unknown family", "0100ca6ccb0071070000000000000000", ""},
+		{"empty", "", ""},
+	}
+	for _, c := range cases {
+		b, _ := hex.DecodeString(c.in)
+		if got := parseSockaddr(b); got != c.want {
+			t.Errorf("%s: got %q, want %q", c.name, got, c.want)
+		}
+	}
+}
+
+func TestParsePeer_NoHandshake(t *testing.T) {
+	// A zero timespec means no handshake has completed yet.
+	b := netlink.EncodeAttributes([]netlink.Attribute{
+		{Type: peerAttrPublicKey, Data: make([]byte, 32)},
+		{Type: peerAttrLastHandshake, Data: make([]byte, 16)},
+	})
+	p, err := parsePeer(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.LastHandshake.IsZero() {
+		t.Fatalf("handshake = %v", p.LastHandshake)
+	}
+}